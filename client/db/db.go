@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"sync"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/teejays/gokutil/errutil"
@@ -33,6 +34,11 @@ type ServerOptions struct {
 type Options struct {
 	Database string
 	ServerOptions
+	// Replicas are optional read replicas of the primary server. Reads made outside of a transaction are spread
+	// across the healthy replicas in a round-robin fashion. Writes and transactions always use the primary.
+	Replicas []ServerOptions
+	// ReplicaRetryInterval is how long an unhealthy replica is kept out of rotation. Defaults to DEFAULT_REPLICA_RETRY_INTERVAL.
+	ReplicaRetryInterval time.Duration
 }
 
 // QueryableConnection groups together a sql.Connection, sq.DB (with pool handling) and a Transaction
@@ -54,6 +60,8 @@ type ConnectionProvider struct {
 	// NumTxs  int
 	// UseTransaction bool    // Do we need this extra bool?
 	InitOptions Options // Options used to initialize the connection
+
+	replicas *replicaPool // optional: read replicas, shared by all the connections of the provider
 }
 
 func (c *ConnectionProvider) GetConnection(ctx context.Context) (*Connection, error) {
@@ -64,12 +72,18 @@ func (c *ConnectionProvider) GetConnection(ctx context.Context) (*Connection, er
 	}
 
 	return &Connection{
-		Dialect: c.Dialect,
-		DB:      sqlDB,
-		DbName:  c.DbName,
+		Dialect:  c.Dialect,
+		DB:       sqlDB,
+		DbName:   c.DbName,
+		replicas: c.replicas,
 	}, nil
 }
 
+// Close closes the read replicas of the provider. Connections that are still open fall back to the primary for reads.
+func (c *ConnectionProvider) Close(ctx context.Context) error {
+	return c.replicas.close(ctx)
+}
+
 // NewConnectionProvider initializes a totally new database connection provider. It does not check if the connection is already initialized.
 // This should ideally not be used directly, but through NewOrExistingConnectionProvider (which reuses existing providers).
 func NewConnectionProvider(ctx context.Context, opt Options) (ConnectionProvider, error) {
@@ -79,9 +93,16 @@ func NewConnectionProvider(ctx context.Context, opt Options) (ConnectionProvider
 		InitOptions: opt,
 	}
 
+	// Connect to the read replicas once, so that the round-robin and the health of the replicas is shared by all the
+	// connections
+	prov.replicas = newReplicaPool(ctx, opt)
+
 	// Ensure that we are able to connect to the database
 	conn, err := prov.GetConnection(ctx)
 	if err != nil {
+		if closeErr := prov.Close(ctx); closeErr != nil {
+			log.Warn(ctx, "Could not close the read replicas", "error", closeErr)
+		}
 		return ConnectionProvider{}, errutil.Wrap(err, "Could not succesfully test the connection to the database")
 	}
 	err = conn.Close(ctx)
@@ -139,6 +160,10 @@ type Connection struct {
 	Tx      *sql.Tx
 	NumTxs  int
 	DbName  string
+
+	replicas *replicaPool // optional: read replicas, shared with the ConnectionProvider and its other connections
+
+	afterCommit []func(ctx context.Context) // funcs to run once the current transaction commits
 }
 
 // GetSQLConnection returns a direct sql.DB or sql.Tx object that can be used to run queries
@@ -149,6 +174,23 @@ func (c Connection) GetQueryableConnection(ctx context.Context) (QueryableConnec
 	return c.DB, nil
 }
 
+// getReadQueryableConnection returns the connection that a read-only query should use. Outside of a transaction, this
+// is the next healthy read replica (if any). Inside a transaction, or when the context forces primary reads, it is the
+// same as GetQueryableConnection. The replica is returned as well (nil when the primary is used) so that callers can
+// report it as unhealthy.
+func (c Connection) getReadQueryableConnection(ctx context.Context) (QueryableConnection, *replica, error) {
+	if c.IsInTransaction(ctx) || IsPrimaryReadForced(ctx) {
+		conn, err := c.GetQueryableConnection(ctx)
+		return conn, nil, err
+	}
+	if r := c.replicas.pick(); r != nil {
+		log.Trace(ctx, "Using read replica for query", "host", r.host)
+		return r.db, r, nil
+	}
+	conn, err := c.GetQueryableConnection(ctx)
+	return conn, nil, err
+}
+
 func (c *Connection) Close(ctx context.Context) error {
 	// if connection has transactions, rollthem back.
 	if c.IsInTransaction(ctx) {
@@ -158,6 +200,9 @@ func (c *Connection) Close(ctx context.Context) error {
 		}
 	}
 
+	// The read replicas belong to the ConnectionProvider, so they are not closed here
+	c.replicas = nil
+
 	// Close the connection (if any)
 	if c.DB != nil {
 		if err := c.DB.Close(); err != nil {
@@ -185,6 +230,12 @@ func (c *Connection) IsInTransaction(ctx context.Context) bool {
 }
 
 func (c *Connection) Begin(ctx context.Context) error {
+	return c.BeginTx(ctx, &sql.TxOptions{})
+}
+
+// BeginTx is like Begin but allows setting the options (e.g. isolation level) of the transaction. The options are
+// ignored for nested transactions.
+func (c *Connection) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
 
 	c.NumTxs++
 
@@ -196,8 +247,9 @@ func (c *Connection) Begin(ctx context.Context) error {
 
 	// Otherwise start a transaction
 	log.Debug(ctx, "Begin transaction", "number", c.NumTxs)
-	txn, err := c.DB.BeginTx(ctx, &sql.TxOptions{})
+	txn, err := c.DB.BeginTx(ctx, opts)
 	if err != nil {
+		c.NumTxs--
 		return err
 	}
 	c.Tx = txn
@@ -219,14 +271,42 @@ func (c *Connection) Commit(ctx context.Context) error {
 	}
 	log.Debug(ctx, "Commiting transaction", "number", c.NumTxs+1)
 	err := c.Tx.Commit()
+	// The transaction is over even if the commit failed, so it cannot be used anymore
+	c.Tx = nil
+	afterCommit := c.afterCommit
+	c.afterCommit = nil
 	if err != nil {
 		return err
 	}
-	c.Tx = nil
+
+	for _, fn := range afterCommit {
+		runAfterCommit(ctx, fn)
+	}
 
 	return nil
 }
 
+// AfterCommit runs fn once the current transaction commits. It is dropped if the transaction is rolled back. Outside
+// of a transaction, fn runs right away.
+func (c *Connection) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if !c.IsInTransaction(ctx) {
+		runAfterCommit(ctx, fn)
+		return
+	}
+	c.afterCommit = append(c.afterCommit, fn)
+}
+
+// runAfterCommit runs fn, making sure that a panic in fn does not affect the caller since the transaction is already
+// committed.
+func runAfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(ctx, "Panic in after commit func", "panic", r)
+		}
+	}()
+	fn(ctx)
+}
+
 func (c *Connection) MustCommit(ctx context.Context) {
 	panics.IfError(c.Commit(ctx), "Error committing DB.Transaction")
 }
@@ -242,6 +322,7 @@ func (c *Connection) Rollback(ctx context.Context) error {
 	}
 	log.Info(ctx, "Rollback transaction")
 	c.NumTxs = 0
+	c.afterCommit = nil
 
 	err := c.Tx.Rollback()
 	if err != nil {
@@ -257,9 +338,12 @@ func (c *Connection) MustRollback(ctx context.Context) {
 }
 
 // ExecuteQuery executes an insert or update query, returning the number of rows affected.
-func (c Connection) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (int64, error) {
+func (c Connection) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (rowsAffected int64, err error) {
+
+	log.Debug(ctx, "Executing SQL query", "query", query, "args", RedactQueryArgs(args))
 
-	log.Debug(ctx, "Executing SQL query", "query", query, "args", args)
+	ctx, done := c.observeQuery(ctx, QueryKind_Exec, query, args)
+	defer func() { done(rowsAffected, err) }()
 
 	// Execute the Query
 	conn, err := c.GetQueryableConnection(ctx)
@@ -269,11 +353,11 @@ func (c Connection) ExecuteQuery(ctx context.Context, query string, args ...inte
 
 	result, err := conn.ExecContext(ctx, query, args...)
 	if err != nil {
-		return -1, errutil.Wrap(err, "failed to execute query")
+		return -1, errutil.Wrap(TranslateError(err), "failed to execute query")
 	}
 
 	// Validate the result
-	rowsAffected, err := result.RowsAffected()
+	rowsAffected, err = result.RowsAffected()
 	if err != nil {
 		return -1, fmt.Errorf("cannot fetch number of rows affected for executed query: %w", err)
 	}
@@ -287,45 +371,69 @@ func (c Connection) ExecuteQuery(ctx context.Context, query string, args ...inte
 }
 
 // QueryRows executes a query that returns rows e.g. Select Query
-func (c Connection) QueryRows(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+// Observers are notified once the query has returned, which is before the rows are read.
+func (c Connection) QueryRows(ctx context.Context, query string, args ...interface{}) (_ *sql.Rows, err error) {
 
-	log.Debug(ctx, "Running SQL Query", "query", query, "args", args)
+	log.Debug(ctx, "Running SQL Query", "query", query, "args", RedactQueryArgs(args))
 
-	// Get the connection that we'll use to execute the query. This `conn` can be a direct DB connection or a transaction
-	conn, err := c.GetQueryableConnection(ctx)
+	ctx, done := c.observeQuery(ctx, QueryKind_QueryRows, query, args)
+	defer func() { done(-1, err) }()
+
+	// Get the connection that we'll use to execute the query. This `conn` can be a direct DB connection, a read replica or a transaction
+	conn, rep, err := c.getReadQueryableConnection(ctx)
 	if err != nil {
 		return nil, errutil.Wrap(err, "failed to get a connection to the database")
 	}
 
 	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil && rep != nil && isConnectionError(err) {
+		// The replica could not be reached, so fall back to the primary
+		c.replicas.markUnhealthy(ctx, rep, err)
+		rows, err = c.DB.QueryContext(ctx, query, args...)
+	}
 	if err != nil {
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	return rows, nil
 }
 
-func (c Connection) QueryRow(ctx context.Context, v interface{}, query string, args ...interface{}) error {
+func (c Connection) QueryRow(ctx context.Context, v interface{}, query string, args ...interface{}) (err error) {
 
-	log.Debug(ctx, "Running SQL Query (single row)", "query", query, "args", args)
+	log.Debug(ctx, "Running SQL Query (single row)", "query", query, "args", RedactQueryArgs(args))
 
-	conn, err := c.GetQueryableConnection(ctx)
+	ctx, done := c.observeQuery(ctx, QueryKind_QueryRow, query, args)
+	defer func() {
+		var n int64 = 1
+		if err != nil {
+			n = 0
+		}
+		done(n, err)
+	}()
+
+	conn, rep, err := c.getReadQueryableConnection(ctx)
 	if err != nil {
 		return errutil.Wrap(err, "failed to get a connection to the database")
 	}
 
 	row := conn.QueryRowContext(ctx, query, args...)
 	err = row.Err()
+	if err != nil && rep != nil && isConnectionError(err) {
+		// The replica could not be reached, so fall back to the primary
+		c.replicas.markUnhealthy(ctx, rep, err)
+		row = c.DB.QueryRowContext(ctx, query, args...)
+		err = row.Err()
+	}
 	if err != nil {
-		log.Debug(ctx, "Error quering row", "query", query, "args", args, "error", err)
-		return row.Err()
+		log.Debug(ctx, "Error quering row", "query", query, "args", RedactQueryArgs(args), "error", err)
+		return TranslateError(err)
 
 	}
 
 	err = row.Scan(v)
 	if err != nil {
-		log.Debug(ctx, "Error scanning row", "query", query, "args", args, "error", err)
-		return err
+		log.Debug(ctx, "Error scanning row", "query", query, "args", RedactQueryArgs(args), "error", err)
+		return TranslateError(err)
 	}
 
 	return nil
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/teejays/gokutil/errutil"
+)
+
+// Postgres error codes that are translated into GokuErrors.
+// See: https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	PQ_ERR_CODE_NOT_NULL_VIOLATION    pq.ErrorCode = "23502"
+	PQ_ERR_CODE_FOREIGN_KEY_VIOLATION pq.ErrorCode = "23503"
+	PQ_ERR_CODE_UNIQUE_VIOLATION      pq.ErrorCode = "23505"
+	PQ_ERR_CODE_CHECK_VIOLATION       pq.ErrorCode = "23514"
+	PQ_ERR_CODE_QUERY_CANCELED        pq.ErrorCode = "57014"
+)
+
+// TranslateError converts Postgres errors that are caused by the request (e.g. a duplicate value) into GokuErrors
+// with an appropriate HTTP status and an external message that is safe to show to clients. The internal message
+// includes the table, column and constraint names. Other errors are returned as is.
+func TranslateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case PQ_ERR_CODE_UNIQUE_VIOLATION:
+		return errutil.WrapGerrorf(err, "Unique constraint violated: %s", describePQError(pqErr)).
+			SetHTTPStatus(http.StatusConflict).
+			SetExternalMsg("A record with the same value already exists.")
+	case PQ_ERR_CODE_FOREIGN_KEY_VIOLATION:
+		return errutil.WrapGerrorf(err, "Foreign key constraint violated: %s", describePQError(pqErr)).
+			SetHTTPStatus(http.StatusUnprocessableEntity).
+			SetExternalMsg("The record refers to another record that does not exist, or is still referred to by other records.")
+	case PQ_ERR_CODE_NOT_NULL_VIOLATION:
+		return errutil.WrapGerrorf(err, "Not-null constraint violated: %s", describePQError(pqErr)).
+			SetHTTPStatus(http.StatusBadRequest).
+			SetExternalMsg("A required value is missing.")
+	case PQ_ERR_CODE_CHECK_VIOLATION:
+		return errutil.WrapGerrorf(err, "Check constraint violated: %s", describePQError(pqErr)).
+			SetHTTPStatus(http.StatusBadRequest).
+			SetExternalMsg("A provided value is not allowed.")
+	case PQ_ERR_CODE_QUERY_CANCELED:
+		// Query canceled is also used when the client cancels the request, which we don't want to translate
+		if !strings.Contains(pqErr.Message, "statement timeout") {
+			return err
+		}
+		return errutil.WrapGerrorf(err, "Statement timed out: %s", describePQError(pqErr)).
+			SetHTTPStatus(http.StatusGatewayTimeout).
+			SetExternalMsg("The request took too long to process. Please try again later.")
+	}
+
+	return err
+}
+
+// describePQError returns a short description of where the error happened e.g. "table [users] column [email] constraint [users_email_key]"
+func describePQError(pqErr *pq.Error) string {
+	var parts []string
+	if pqErr.Table != "" {
+		parts = append(parts, "table ["+pqErr.Table+"]")
+	}
+	if pqErr.Column != "" {
+		parts = append(parts, "column ["+pqErr.Column+"]")
+	}
+	if pqErr.Constraint != "" {
+		parts = append(parts, "constraint ["+pqErr.Constraint+"]")
+	}
+	if pqErr.Detail != "" {
+		parts = append(parts, "detail ["+pqErr.Detail+"]")
+	}
+	if len(parts) < 1 {
+		return pqErr.Message
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/errutil"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int // 0 if the error should not be translated
+	}{
+		{"unique violation", &pq.Error{Code: PQ_ERR_CODE_UNIQUE_VIOLATION, Table: "users", Constraint: "users_email_key"}, http.StatusConflict},
+		{"foreign key violation", &pq.Error{Code: PQ_ERR_CODE_FOREIGN_KEY_VIOLATION}, http.StatusUnprocessableEntity},
+		{"not null violation", &pq.Error{Code: PQ_ERR_CODE_NOT_NULL_VIOLATION, Column: "email"}, http.StatusBadRequest},
+		{"check violation", &pq.Error{Code: PQ_ERR_CODE_CHECK_VIOLATION}, http.StatusBadRequest},
+		{"wrapped", fmt.Errorf("inserting: %w", &pq.Error{Code: PQ_ERR_CODE_UNIQUE_VIOLATION}), http.StatusConflict},
+		{"statement timeout", &pq.Error{Code: PQ_ERR_CODE_QUERY_CANCELED, Message: "canceling statement due to statement timeout"}, http.StatusGatewayTimeout},
+		{"canceled by the client", &pq.Error{Code: PQ_ERR_CODE_QUERY_CANCELED, Message: "canceling statement due to user request"}, 0},
+		{"other pq error", &pq.Error{Code: PQ_ERR_CODE_SERIALIZATION_FAILURE}, 0},
+		{"not a pq error", fmt.Errorf("some error"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TranslateError(tt.err)
+			gerr, ok := errutil.AsGokuError(got)
+			if tt.wantStatus == 0 {
+				assert.False(t, ok)
+				assert.Equal(t, tt.err, got)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantStatus, gerr.GetHTTPStatus())
+			assert.NotEmpty(t, gerr.GetExternalMsg())
+			// The original error can still be checked
+			var pqErr *pq.Error
+			assert.ErrorAs(t, got, &pqErr)
+		})
+	}
+
+	// The internal message says where the error happened, but the external one does not
+	got := TranslateError(&pq.Error{Code: PQ_ERR_CODE_UNIQUE_VIOLATION, Table: "users", Constraint: "users_email_key"})
+	assert.Contains(t, got.Error(), "table [users] constraint [users_email_key]")
+	gerr, _ := errutil.AsGokuError(got)
+	assert.NotContains(t, gerr.GetExternalMsg(), "users")
+}
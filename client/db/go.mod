@@ -5,6 +5,7 @@ go 1.23.4
 require (
 	github.com/doug-martin/goqu/v9 v9.19.0
 	github.com/lib/pq v1.10.9
+	github.com/stretchr/testify v1.10.0
 	github.com/teejays/gokutil/errutil v0.0.0-20240730034000-a4d834987b3d
 	github.com/teejays/gokutil/log v0.0.0-20240805201441-7ba176910d62
 	github.com/teejays/gokutil/panics v0.0.0-20240730034000-a4d834987b3d
@@ -16,8 +17,8 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/graph-gophers/graphql-go v1.5.0 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
 	github.com/teejays/clog v0.0.0-20240330223723-2114569c05a4 // indirect
 	github.com/teejays/gokutil/clog v0.0.0-20240805201441-7ba176910d62 // indirect
 	github.com/teejays/gokutil/env v0.0.0-20240801191936-9caf6e23633a // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/teejays/gokutil/errutil"
 	"github.com/teejays/gokutil/log"
 	"github.com/teejays/gokutil/panics"
@@ -187,7 +188,11 @@ type SelectByIDBuilderRequest struct {
 	// For where condition, so we only update the required row
 	IDColumn string
 	IDs      []scalars.ID
-	OrderBy  []SelectOrderBy
+	// Where conditions are added to the ID condition
+	Where   []exp.Expression
+	OrderBy []SelectOrderBy
+	// ForUpdate locks the selected rows until the end of the transaction
+	ForUpdate bool
 }
 
 type SelectOrderBy struct {
@@ -218,6 +223,9 @@ func ConstructSelectByIDQuery(ctx context.Context, dialectStr string, req Select
 	ds = ds.Where(
 		goqu.C(req.IDColumn).In(UUIDsToInterfaces(req.IDs)...),
 	)
+	if len(req.Where) > 0 {
+		ds = ds.Where(req.Where...)
+	}
 
 	// Order by
 	if len(req.OrderBy) > 0 {
@@ -237,6 +245,10 @@ func ConstructSelectByIDQuery(ctx context.Context, dialectStr string, req Select
 	ds = ds.OrderAppend(goqu.C("created_at").Asc())
 	ds = ds.OrderAppend(goqu.C(req.IDColumn).Asc())
 
+	if req.ForUpdate {
+		ds = ds.ForUpdate(exp.Wait)
+	}
+
 	query, args, err := ds.ToSQL()
 	if err != nil {
 		return "", nil, err
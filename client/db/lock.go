@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/teejays/gokutil/errutil"
+	"github.com/teejays/gokutil/log"
+	"github.com/teejays/gokutil/scalars"
+)
+
+/* * * * * * *
+ * Advisory Locks
+ * * * * * * */
+
+// ADVISORY_UNLOCK_TIMEOUT limits how long releasing a session lock can take, since it may happen after the caller's context is done.
+const ADVISORY_UNLOCK_TIMEOUT = 5 * time.Second
+
+// GetAdvisoryLockKey converts a string key into the bigint key used by Postgres advisory locks. Like
+// scalars.NewStaticID, the same string always gives the same key, in every process.
+func GetAdvisoryLockKey(key string) int64 {
+	id := scalars.NewStaticID(key)
+	return int64(binary.BigEndian.Uint64(id.UUID[:8]))
+}
+
+// AdvisoryLock is a held Postgres advisory lock.
+//
+// Outside a transaction, a session-level lock is taken on a connection that is set aside from the pool for as long as
+// the lock is held. It is released by Release, or automatically when the context used to take it is done.
+//
+// Inside a transaction, a transaction-scoped lock is taken. It is released by Postgres when the transaction commits or
+// rolls back, so Release is a no-op.
+type AdvisoryLock struct {
+	Key  string
+	ID   int64
+	inTx bool
+	conn *sql.Conn // only for session-level locks
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// IsTransactionScoped returns true if the lock is released at the end of the transaction rather than by Release.
+func (l *AdvisoryLock) IsTransactionScoped() bool {
+	return l.inTx
+}
+
+// Release releases a session-level lock. It is safe to call multiple times.
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	if l == nil || l.inTx {
+		return nil
+	}
+	l.once.Do(func() {
+		close(l.done)
+		l.err = l.release(ctx)
+	})
+	return l.err
+}
+
+func (l *AdvisoryLock) release(ctx context.Context) error {
+	// The lock must be released even if the caller's context is already canceled
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ADVISORY_UNLOCK_TIMEOUT)
+	defer cancel()
+
+	var unlocked bool
+	err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.ID).Scan(&unlocked)
+	if err == nil && !unlocked {
+		err = fmt.Errorf("advisory lock was not held by the session")
+	}
+	if err != nil {
+		log.Warn(ctx, "Releasing advisory lock failed, discarding the connection", "key", l.Key, "error", err)
+		// If the lock could still be held by the session, the connection must not go back to the pool. Returning
+		// ErrBadConn here makes database/sql close it, which ends the session and so releases the lock.
+		_ = l.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	} else {
+		log.Debug(ctx, "Released advisory lock", "key", l.Key)
+	}
+	if closeErr := l.conn.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errutil.Wrap(err, "Releasing advisory lock [%s]", l.Key)
+	}
+	return nil
+}
+
+// TryAdvisoryLock tries to take the advisory lock for the key without waiting. It returns false if the lock is held by
+// someone else.
+func (c *Connection) TryAdvisoryLock(ctx context.Context, key string) (*AdvisoryLock, bool, error) {
+	return c.advisoryLock(ctx, key, true)
+}
+
+// AdvisoryLock takes the advisory lock for the key, waiting until it's available or the context is done.
+func (c *Connection) AdvisoryLock(ctx context.Context, key string) (*AdvisoryLock, error) {
+	l, _, err := c.advisoryLock(ctx, key, false)
+	return l, err
+}
+
+// WithAdvisoryLock runs fn while holding the advisory lock for the key, waiting for the lock if needed. A session-level
+// lock is released when fn returns or panics, or when the context is done.
+func (c *Connection) WithAdvisoryLock(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	l, err := c.AdvisoryLock(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := l.Release(ctx); err != nil {
+			log.Error(ctx, "Releasing advisory lock", "key", key, "error", err)
+		}
+	}()
+	return fn(ctx)
+}
+
+// TryWithAdvisoryLock is like WithAdvisoryLock but doesn't wait: if the lock is held by someone else, fn is not run
+// and false is returned. This is useful for jobs that should only run on one instance at a time.
+func (c *Connection) TryWithAdvisoryLock(ctx context.Context, key string, fn func(ctx context.Context) error) (bool, error) {
+	l, acquired, err := c.TryAdvisoryLock(ctx, key)
+	if err != nil || !acquired {
+		return false, err
+	}
+	defer func() {
+		if err := l.Release(ctx); err != nil {
+			log.Error(ctx, "Releasing advisory lock", "key", key, "error", err)
+		}
+	}()
+	return true, fn(ctx)
+}
+
+func (c *Connection) advisoryLock(ctx context.Context, key string, try bool) (*AdvisoryLock, bool, error) {
+	l := &AdvisoryLock{
+		Key:  key,
+		ID:   GetAdvisoryLockKey(key),
+		done: make(chan struct{}),
+	}
+
+	fn := "pg_advisory_lock"
+	if c.IsInTransaction(ctx) {
+		fn = "pg_advisory_xact_lock"
+		l.inTx = true
+	}
+	if try {
+		fn = "pg_try_" + fn[len("pg_"):]
+	}
+
+	var conn QueryableConnection = c.Tx
+	if !l.inTx {
+		sqlConn, err := c.DB.Conn(ctx)
+		if err != nil {
+			return nil, false, errutil.Wrap(err, "Getting a dedicated connection for advisory lock [%s]", key)
+		}
+		l.conn = sqlConn
+		conn = sqlConn
+	}
+
+	log.Debug(ctx, "Taking advisory lock", "key", key, "id", l.ID, "func", fn)
+	acquired := true
+	row := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT %s($1)", fn), l.ID)
+	var err error
+	if try {
+		err = row.Scan(&acquired)
+	} else {
+		err = row.Scan(new(interface{}))
+	}
+	if err != nil {
+		if l.conn != nil {
+			// We can't be sure whether the lock was taken (e.g. if the context was canceled just as it was), so
+			// the connection is discarded instead of going back to the pool
+			_ = l.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			l.conn.Close()
+		}
+		return nil, false, errutil.Wrap(TranslateError(err), "Taking advisory lock [%s]", key)
+	}
+	if !acquired {
+		if l.conn != nil {
+			l.conn.Close()
+		}
+		log.Debug(ctx, "Advisory lock is held by someone else", "key", key)
+		return nil, false, nil
+	}
+
+	// Release session-level locks when the context is done, so that a lock is never kept by a forgotten caller
+	if !l.inTx {
+		go func() {
+			select {
+			case <-ctx.Done():
+				if err := l.Release(ctx); err != nil {
+					log.Error(ctx, "Releasing advisory lock after context was done", "key", key, "error", err)
+				}
+			case <-l.done:
+			}
+		}()
+	}
+
+	return l, true, nil
+}
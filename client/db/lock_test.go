@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAdvisoryLockKey(t *testing.T) {
+	// The same key always gives the same ID, so that all processes agree on it
+	assert.Equal(t, GetAdvisoryLockKey("migrations"), GetAdvisoryLockKey("migrations"))
+	assert.Equal(t, int64(-8192422080662993252), GetAdvisoryLockKey("migrations"))
+
+	seen := map[int64]string{}
+	for _, key := range []string{"", "migrations", "Migrations", "migrations ", "jobs:cleanup", "jobs:cleanup:1"} {
+		id := GetAdvisoryLockKey(key)
+		if other, ok := seen[id]; ok {
+			t.Errorf("keys [%s] and [%s] have the same ID [%d]", key, other, id)
+		}
+		seen[id] = key
+	}
+}
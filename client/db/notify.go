@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/teejays/gokutil/errutil"
+	"github.com/teejays/gokutil/log"
+)
+
+/* * * * * * *
+ * LISTEN/NOTIFY
+ * * * * * * */
+
+const (
+	// Backoff limits used by a subscription when reconnecting to the database. The wait doubles after every failed attempt.
+	LISTENER_MIN_RECONNECT_INTERVAL = 1 * time.Second
+	LISTENER_MAX_RECONNECT_INTERVAL = 1 * time.Minute
+	// LISTENER_PING_INTERVAL is how often an idle subscription checks that its connection is still alive.
+	LISTENER_PING_INTERVAL = 90 * time.Second
+	// NOTIFICATION_BUFFER_SIZE is the size of the channel returned by Subscribe.
+	NOTIFICATION_BUFFER_SIZE = 64
+)
+
+// Notification is a message received on a Postgres channel.
+type Notification struct {
+	Channel    string
+	Payload    string
+	BackendPID int // PID of the backend that sent the notification
+	// Reconnected is set (with an empty Payload) when the subscription had lost its connection and has just
+	// re-established it. Notifications sent while disconnected are lost, so listeners should treat this as "anything
+	// may have changed" e.g. by invalidating their whole cache.
+	Reconnected bool
+}
+
+// Subscribe listens to the Postgres channel on a dedicated connection, and sends the notifications received to the
+// returned Go channel. If the connection is lost, it reconnects with exponential backoff and listens to the channel
+// again. The subscription ends, and the Go channel is closed, when the context is canceled.
+// Listeners should keep up with the notifications: if the buffer is full, the subscription blocks and notifications
+// queue up in the database.
+func (c *ConnectionProvider) Subscribe(ctx context.Context, channel string) (<-chan Notification, error) {
+	if channel == "" {
+		return nil, fmt.Errorf("channel name cannot be empty")
+	}
+
+	connStr, err := getConnectionString(ctx, c.InitOptions)
+	if err != nil {
+		return nil, errutil.Wrap(err, "geting connection string")
+	}
+
+	listener := pq.NewListener(connStr, LISTENER_MIN_RECONNECT_INTERVAL, LISTENER_MAX_RECONNECT_INTERVAL, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			log.Warn(ctx, "Subscription lost its database connection, reconnecting", "channel", channel, "error", err)
+		case pq.ListenerEventReconnected:
+			log.Info(ctx, "Subscription reconnected to the database", "channel", channel)
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Warn(ctx, "Subscription failed to connect to the database, retrying", "channel", channel, "error", err)
+		}
+	})
+
+	// The listener re-runs LISTEN for all its channels by itself after a reconnect
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, errutil.Wrap(err, "Listening to channel [%s]", channel)
+	}
+	log.Debug(ctx, "Subscribed to database channel", "channel", channel)
+
+	ch := make(chan Notification, NOTIFICATION_BUFFER_SIZE)
+	go func() {
+		defer close(ch)
+		defer func() {
+			if err := listener.Close(); err != nil {
+				log.Warn(ctx, "Closing subscription listener", "channel", channel, "error", err)
+			}
+		}()
+
+		send := func(n Notification) bool {
+			select {
+			case ch <- n:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debug(ctx, "Unsubscribing from database channel", "channel", channel)
+				return
+			case n := <-listener.Notify:
+				// A nil notification is sent after the connection was re-established
+				if n == nil {
+					if !send(Notification{Channel: channel, Reconnected: true}) {
+						return
+					}
+					continue
+				}
+				if !send(Notification{Channel: n.Channel, Payload: n.Extra, BackendPID: n.BePid}) {
+					return
+				}
+			case <-time.After(LISTENER_PING_INTERVAL):
+				// Ping so that a connection that silently died is noticed and re-established
+				go func() {
+					if err := listener.Ping(); err != nil {
+						log.Warn(ctx, "Pinging subscription connection", "channel", channel, "error", err)
+					}
+				}()
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Notify sends a notification with the payload to the Postgres channel. When called inside a transaction, the
+// notification is only delivered if (and when) the transaction commits.
+func (c Connection) Notify(ctx context.Context, channel string, payload string) error {
+	conn, err := c.GetQueryableConnection(ctx)
+	if err != nil {
+		return errutil.Wrap(err, "failed to get a connection to the database")
+	}
+	log.Debug(ctx, "Sending notification", "channel", channel, "inTransaction", c.IsInTransaction(ctx))
+	_, err = conn.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	if err != nil {
+		return errutil.Wrap(TranslateError(err), "Notifying channel [%s]", channel)
+	}
+	return nil
+}
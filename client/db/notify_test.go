@@ -0,0 +1,20 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionProvider_Subscribe_InvalidArgs(t *testing.T) {
+	ctx := context.Background()
+
+	prov := &ConnectionProvider{InitOptions: Options{ServerOptions: ServerOptions{Host: "localhost", Port: DEFAULT_POSTGRES_PORT}}}
+	_, err := prov.Subscribe(ctx, "")
+	assert.Error(t, err)
+
+	// The connection string is checked before connecting
+	_, err = (&ConnectionProvider{}).Subscribe(ctx, "events")
+	assert.Error(t, err)
+}
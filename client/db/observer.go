@@ -0,0 +1,341 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teejays/gokutil/log"
+	"github.com/teejays/gokutil/scalars"
+)
+
+/* * * * * * *
+ * Query Observers
+ * * * * * * */
+
+type QueryKind string
+
+const (
+	QueryKind_Exec      QueryKind = "exec"
+	QueryKind_QueryRows QueryKind = "query_rows"
+	QueryKind_QueryRow  QueryKind = "query_row"
+)
+
+// QueryEvent describes a single query that was run through a Connection.
+type QueryEvent struct {
+	Kind          QueryKind
+	Query         string
+	Args          []interface{} // Args are redacted using the registered QueryArgRedactor
+	Table         string        // Best effort: the first table referenced by the query
+	InTransaction bool
+	StartedAt     time.Time
+
+	// Only populated in AfterQuery
+	Duration     time.Duration
+	RowsAffected int64 // -1 when unknown e.g. for QueryRows, where rows are read after the query returns
+	Err          error
+}
+
+// QueryObserver is notified before and after every query run by Connection.ExecuteQuery, QueryRows and QueryRow.
+// BeforeQuery can return a new context (e.g. with a tracing span) which is passed down to the query and to AfterQuery.
+// Observers should be fast and must not panic, since they are called inline with the query.
+type QueryObserver interface {
+	BeforeQuery(ctx context.Context, e QueryEvent) context.Context
+	AfterQuery(ctx context.Context, e QueryEvent)
+}
+
+// QueryArgRedactor returns the value of a query arg that is safe to be shared with observers.
+type QueryArgRedactor func(arg interface{}) interface{}
+
+const REDACTED_ARG = "[REDACTED]"
+
+// DefaultQueryArgRedactor redacts secret values.
+func DefaultQueryArgRedactor(arg interface{}) interface{} {
+	switch arg.(type) {
+	case scalars.Secret, *scalars.Secret:
+		return REDACTED_ARG
+	}
+	return arg
+}
+
+var _queryObservers []QueryObserver
+var _queryArgRedactor QueryArgRedactor = DefaultQueryArgRedactor
+var _queryObserversLock = &sync.RWMutex{}
+
+// AddQueryObserver registers an observer for all the queries run by this package.
+func AddQueryObserver(o QueryObserver) {
+	_queryObserversLock.Lock()
+	defer _queryObserversLock.Unlock()
+	_queryObservers = append(_queryObservers, o)
+}
+
+// SetQueryArgRedactor replaces the function used to redact query args before they are logged or passed to observers.
+func SetQueryArgRedactor(fn QueryArgRedactor) {
+	_queryObserversLock.Lock()
+	defer _queryObserversLock.Unlock()
+	if fn == nil {
+		fn = DefaultQueryArgRedactor
+	}
+	_queryArgRedactor = fn
+}
+
+func getQueryObservers() ([]QueryObserver, QueryArgRedactor) {
+	_queryObserversLock.RLock()
+	defer _queryObserversLock.RUnlock()
+	return _queryObservers, _queryArgRedactor
+}
+
+// RedactQueryArgs returns a copy of the args with the registered QueryArgRedactor applied.
+func RedactQueryArgs(args []interface{}) []interface{} {
+	_, redact := getQueryObservers()
+	return redactQueryArgs(redact, args)
+}
+
+func redactQueryArgs(redact QueryArgRedactor, args []interface{}) []interface{} {
+	r := make([]interface{}, len(args))
+	for i := range args {
+		r[i] = redact(args[i])
+	}
+	return r
+}
+
+var tableNameRegex = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|JOIN)\s+"?([a-zA-Z0-9_\.]+)"?`)
+
+// GetQueryTableName returns the first table referenced in a query, or an empty string if it can't be found.
+func GetQueryTableName(query string) string {
+	m := tableNameRegex.FindStringSubmatch(query)
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
+
+// observeQuery notifies all the observers that a query is starting. The returned function should be called once the
+// query has finished.
+func (c *Connection) observeQuery(ctx context.Context, kind QueryKind, query string, args []interface{}) (context.Context, func(rowsAffected int64, err error)) {
+	observers, redact := getQueryObservers()
+	if len(observers) < 1 {
+		return ctx, func(int64, error) {}
+	}
+
+	e := QueryEvent{
+		Kind:          kind,
+		Query:         query,
+		Args:          redactQueryArgs(redact, args),
+		Table:         GetQueryTableName(query),
+		InTransaction: c.IsInTransaction(ctx),
+		StartedAt:     time.Now(),
+	}
+
+	for _, o := range observers {
+		ctx = o.BeforeQuery(ctx, e)
+	}
+
+	return ctx, func(rowsAffected int64, err error) {
+		e.Duration = time.Since(e.StartedAt)
+		e.RowsAffected = rowsAffected
+		e.Err = err
+		for _, o := range observers {
+			o.AfterQuery(ctx, e)
+		}
+	}
+}
+
+/* * * * * * *
+ * Slow Query Observer
+ * * * * * * */
+
+// SlowQueryObserver logs a warning for every query that takes longer than the Threshold.
+type SlowQueryObserver struct {
+	Threshold time.Duration
+}
+
+func NewSlowQueryObserver(threshold time.Duration) SlowQueryObserver {
+	return SlowQueryObserver{Threshold: threshold}
+}
+
+func (o SlowQueryObserver) BeforeQuery(ctx context.Context, e QueryEvent) context.Context {
+	return ctx
+}
+
+func (o SlowQueryObserver) AfterQuery(ctx context.Context, e QueryEvent) {
+	if e.Duration < o.Threshold {
+		return
+	}
+	log.Warn(ctx, "Slow SQL query", "duration", e.Duration, "threshold", o.Threshold, "table", e.Table, "kind", e.Kind, "query", e.Query, "args", e.Args)
+}
+
+/* * * * * * *
+ * Table Metrics Observer
+ * * * * * * */
+
+// DefaultQueryDurationBuckets are the upper bounds of the duration histogram buckets used by TableMetricsObserver.
+var DefaultQueryDurationBuckets = []time.Duration{
+	1 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	5 * time.Second,
+}
+
+// TableQueryMetrics are the metrics collected for a single table and query kind.
+type TableQueryMetrics struct {
+	Table         string
+	Kind          QueryKind
+	Count         int64
+	ErrorCount    int64
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+	// BucketCounts[i] is the number of queries that took <= Buckets[i]. The last element counts the queries that took
+	// longer than the largest bucket.
+	Buckets      []time.Duration
+	BucketCounts []int64
+}
+
+type tableQueryKey struct {
+	table string
+	kind  QueryKind
+}
+
+// TableMetricsObserver keeps in-memory counters and duration histograms per table and query kind. A high count for
+// a table relative to the number of requests usually points to an N+1 query pattern.
+type TableMetricsObserver struct {
+	buckets []time.Duration
+	lock    sync.Mutex
+	metrics map[tableQueryKey]*TableQueryMetrics
+}
+
+// NewTableMetricsObserver creates a new TableMetricsObserver. If no buckets are provided, DefaultQueryDurationBuckets are used.
+func NewTableMetricsObserver(buckets ...time.Duration) *TableMetricsObserver {
+	if len(buckets) < 1 {
+		buckets = DefaultQueryDurationBuckets
+	}
+	buckets = append([]time.Duration{}, buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &TableMetricsObserver{
+		buckets: buckets,
+		metrics: map[tableQueryKey]*TableQueryMetrics{},
+	}
+}
+
+func (o *TableMetricsObserver) BeforeQuery(ctx context.Context, e QueryEvent) context.Context {
+	return ctx
+}
+
+func (o *TableMetricsObserver) AfterQuery(ctx context.Context, e QueryEvent) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	key := tableQueryKey{table: e.Table, kind: e.Kind}
+	m, exists := o.metrics[key]
+	if !exists {
+		m = &TableQueryMetrics{
+			Table:        e.Table,
+			Kind:         e.Kind,
+			Buckets:      o.buckets,
+			BucketCounts: make([]int64, len(o.buckets)+1),
+		}
+		o.metrics[key] = m
+	}
+
+	m.Count++
+	if e.Err != nil {
+		m.ErrorCount++
+	}
+	m.TotalDuration += e.Duration
+	if e.Duration > m.MaxDuration {
+		m.MaxDuration = e.Duration
+	}
+	i := sort.Search(len(o.buckets), func(i int) bool { return e.Duration <= o.buckets[i] })
+	m.BucketCounts[i]++
+}
+
+// Snapshot returns a copy of the metrics collected so far, sorted by table and kind.
+func (o *TableMetricsObserver) Snapshot() []TableQueryMetrics {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	var r []TableQueryMetrics
+	for _, m := range o.metrics {
+		c := *m
+		c.BucketCounts = append([]int64{}, m.BucketCounts...)
+		r = append(r, c)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Table != r[j].Table {
+			return r[i].Table < r[j].Table
+		}
+		return r[i].Kind < r[j].Kind
+	})
+	return r
+}
+
+// Reset clears all the metrics collected so far.
+func (o *TableMetricsObserver) Reset() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.metrics = map[tableQueryKey]*TableQueryMetrics{}
+}
+
+/* * * * * * *
+ * Tracing Observer
+ * * * * * * */
+
+// Span is the minimal interface of a tracing span. It can be implemented by a thin adapter over any tracing library
+// (e.g. OpenTelemetry) so that this package doesn't have to depend on one.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts new spans.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+type spanContextKey struct{}
+
+// TracingObserver creates a span for each query.
+type TracingObserver struct {
+	Tracer Tracer
+}
+
+func NewTracingObserver(t Tracer) TracingObserver {
+	return TracingObserver{Tracer: t}
+}
+
+func (o TracingObserver) BeforeQuery(ctx context.Context, e QueryEvent) context.Context {
+	name := fmt.Sprintf("db.%s", e.Kind)
+	if e.Table != "" {
+		name = fmt.Sprintf("%s %s", name, e.Table)
+	}
+	ctx, span := o.Tracer.StartSpan(ctx, name)
+	span.SetAttribute("db.system", SQL_DIALECT)
+	span.SetAttribute("db.statement", e.Query)
+	span.SetAttribute("db.sql.table", e.Table)
+	span.SetAttribute("db.operation", strings.ToUpper(strings.SplitN(strings.TrimSpace(e.Query), " ", 2)[0]))
+	span.SetAttribute("db.in_transaction", e.InTransaction)
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+func (o TracingObserver) AfterQuery(ctx context.Context, e QueryEvent) {
+	span, ok := ctx.Value(spanContextKey{}).(Span)
+	if !ok {
+		return
+	}
+	if e.RowsAffected >= 0 {
+		span.SetAttribute("db.rows_affected", e.RowsAffected)
+	}
+	if e.Err != nil {
+		span.RecordError(e.Err)
+	}
+	span.End()
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/scalars"
+)
+
+func TestGetQueryTableName(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT * FROM users WHERE id = $1`, "users"},
+		{`SELECT "u".* FROM "users" AS "u"`, "users"},
+		{`select * from public.users`, "public.users"},
+		{`INSERT INTO orders (id) VALUES ($1)`, "orders"},
+		{`UPDATE "orders" SET "status" = $1`, "orders"},
+		{`DELETE FROM order_items WHERE order_id = $1`, "order_items"},
+		{`SELECT 1`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, GetQueryTableName(tt.query))
+		})
+	}
+}
+
+func TestTableMetricsObserver(t *testing.T) {
+	ctx := context.Background()
+	o := NewTableMetricsObserver(100*time.Millisecond, 10*time.Millisecond)
+
+	for _, e := range []QueryEvent{
+		{Table: "users", Kind: QueryKind_QueryRows, Duration: 5 * time.Millisecond},
+		{Table: "users", Kind: QueryKind_QueryRows, Duration: 10 * time.Millisecond},
+		{Table: "users", Kind: QueryKind_QueryRows, Duration: 50 * time.Millisecond, Err: fmt.Errorf("some error")},
+		{Table: "users", Kind: QueryKind_QueryRows, Duration: time.Second},
+		{Table: "users", Kind: QueryKind_Exec, Duration: time.Millisecond},
+		{Table: "orders", Kind: QueryKind_QueryRow, Duration: time.Millisecond},
+	} {
+		o.AfterQuery(ctx, e)
+	}
+
+	got := o.Snapshot()
+	assert.Len(t, got, 3)
+	// Sorted by table and kind
+	assert.Equal(t, "orders", got[0].Table)
+	assert.Equal(t, QueryKind_Exec, got[1].Kind)
+
+	m := got[2]
+	assert.Equal(t, "users", m.Table)
+	assert.Equal(t, QueryKind_QueryRows, m.Kind)
+	assert.Equal(t, int64(4), m.Count)
+	assert.Equal(t, int64(1), m.ErrorCount)
+	assert.Equal(t, time.Second, m.MaxDuration)
+	assert.Equal(t, 1065*time.Millisecond, m.TotalDuration)
+	// Buckets are sorted, and the bounds are inclusive. The last count is for queries longer than the largest bucket.
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 100 * time.Millisecond}, m.Buckets)
+	assert.Equal(t, []int64{2, 1, 1}, m.BucketCounts)
+
+	// The snapshot is a copy
+	m.BucketCounts[0] = 100
+	assert.Equal(t, int64(2), o.Snapshot()[2].BucketCounts[0])
+
+	o.Reset()
+	assert.Empty(t, o.Snapshot())
+
+	// Default buckets
+	assert.Equal(t, DefaultQueryDurationBuckets, NewTableMetricsObserver().buckets)
+}
+
+func TestDefaultQueryArgRedactor(t *testing.T) {
+	secret := scalars.NewSecret("hunter2")
+	args := []interface{}{"alice", 42, secret, &secret, nil}
+
+	got := redactQueryArgs(DefaultQueryArgRedactor, args)
+	assert.Equal(t, []interface{}{"alice", 42, REDACTED_ARG, REDACTED_ARG, nil}, got)
+	// The args themselves are not changed
+	assert.Equal(t, secret, args[2])
+}
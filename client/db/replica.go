@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/teejays/gokutil/log"
+)
+
+// DEFAULT_REPLICA_RETRY_INTERVAL is how long a replica that failed is kept out of the rotation before we try it again.
+const DEFAULT_REPLICA_RETRY_INTERVAL = 30 * time.Second
+
+type contextKey int
+
+const (
+	_ contextKey = iota
+	forcePrimaryReadKey
+)
+
+// WithPrimaryRead returns a context that forces all reads made with it to go to the primary database.
+// This is useful for read-after-write consistency, where a replica might not have caught up with the primary yet.
+func WithPrimaryRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryReadKey, true)
+}
+
+// IsPrimaryReadForced returns true if the context has been marked using WithPrimaryRead.
+func IsPrimaryReadForced(ctx context.Context) bool {
+	v, ok := ctx.Value(forcePrimaryReadKey).(bool)
+	return ok && v
+}
+
+// replica is a single read replica connection along with its health state.
+type replica struct {
+	host           string
+	db             *sql.DB
+	unhealthySince time.Time // zero value means the replica is healthy
+}
+
+// replicaPool holds the read replicas for a ConnectionProvider and hands them out in a round-robin fashion.
+// It is shared (via a pointer) between all the connections of the provider.
+type replicaPool struct {
+	lock          sync.Mutex
+	replicas      []*replica
+	next          int
+	retryInterval time.Duration
+}
+
+// newReplicaPool opens a connection to each of the replicas. Replicas that cannot be connected to are skipped, since
+// reads can always fall back to the primary.
+func newReplicaPool(ctx context.Context, o Options) *replicaPool {
+	if len(o.Replicas) < 1 {
+		return nil
+	}
+
+	pool := &replicaPool{
+		retryInterval: o.ReplicaRetryInterval,
+	}
+	if pool.retryInterval <= 0 {
+		pool.retryInterval = DEFAULT_REPLICA_RETRY_INTERVAL
+	}
+
+	for _, so := range o.Replicas {
+		sqlDB, err := NewSqlConnection(ctx, Options{Database: o.Database, ServerOptions: so})
+		if err != nil {
+			log.Warn(ctx, "Could not connect to the read replica, skipping it", "host", so.Host, "port", so.Port, "error", err)
+			continue
+		}
+		pool.replicas = append(pool.replicas, &replica{host: so.Host, db: sqlDB})
+	}
+
+	if len(pool.replicas) < 1 {
+		log.Warn(ctx, "No read replicas could be connected to. All reads will go to the primary.")
+		return nil
+	}
+
+	return pool
+}
+
+// pick returns the next healthy replica, or nil if there is none.
+func (p *replicaPool) pick() *replica {
+	if p == nil {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(p.replicas); i++ {
+		r := p.replicas[p.next]
+		p.next = (p.next + 1) % len(p.replicas)
+
+		// Give unhealthy replicas another chance once the retry interval has passed
+		if !r.unhealthySince.IsZero() && now.Sub(r.unhealthySince) < p.retryInterval {
+			continue
+		}
+		return r
+	}
+	return nil
+}
+
+// markUnhealthy takes the replica out of the rotation for the retry interval.
+func (p *replicaPool) markUnhealthy(ctx context.Context, r *replica, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	log.Warn(ctx, "Read replica is unhealthy, removing it from rotation", "host", r.host, "retryAfter", p.retryInterval, "error", err)
+	r.unhealthySince = time.Now()
+}
+
+func (p *replicaPool) close(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var errs []error
+	for _, r := range p.replicas {
+		if err := r.db.Close(); err != nil {
+			log.Error(ctx, "Error closing read replica DB.Connection", "host", r.host, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	p.replicas = nil
+	return errors.Join(errs...)
+}
+
+// isConnectionError reports whether the error means that the database itself could not be reached (as opposed to
+// an error with the query).
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	// The caller gave up on the query, which says nothing about the replica
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
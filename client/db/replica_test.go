@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestReplicaPool(hosts ...string) *replicaPool {
+	p := &replicaPool{retryInterval: time.Minute}
+	for _, h := range hosts {
+		p.replicas = append(p.replicas, &replica{host: h})
+	}
+	return p
+}
+
+func TestReplicaPool_Pick(t *testing.T) {
+	var nilPool *replicaPool
+	assert.Nil(t, nilPool.pick())
+
+	// Round-robin over the replicas
+	p := newTestReplicaPool("a", "b", "c")
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, p.pick().host)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, got)
+}
+
+func TestReplicaPool_MarkUnhealthy(t *testing.T) {
+	ctx := context.Background()
+	p := newTestReplicaPool("a", "b")
+
+	// Unhealthy replicas are skipped
+	p.markUnhealthy(ctx, p.replicas[0], driver.ErrBadConn)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b", p.pick().host)
+	}
+	p.markUnhealthy(ctx, p.replicas[1], driver.ErrBadConn)
+	assert.Nil(t, p.pick())
+
+	// ... until the retry interval has passed
+	p.replicas[0].unhealthySince = time.Now().Add(-2 * time.Minute)
+	assert.Equal(t, "a", p.pick().host)
+}
+
+type testNetError struct {
+	timeout bool
+}
+
+func (e testNetError) Error() string   { return "net error" }
+func (e testNetError) Timeout() bool   { return e.timeout }
+func (e testNetError) Temporary() bool { return false }
+
+var _ net.Error = testNetError{}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"bad connection", driver.ErrBadConn, true},
+		{"connection done", fmt.Errorf("querying: %w", sql.ErrConnDone), true},
+		{"network timeout", &net.OpError{Op: "read", Err: testNetError{timeout: true}}, true},
+		{"network error that is not a timeout", testNetError{timeout: false}, false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"canceled", fmt.Errorf("querying: %w", context.Canceled), false},
+		{"query error", fmt.Errorf("syntax error"), false},
+		{"no rows", sql.ErrNoRows, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isConnectionError(tt.err))
+		})
+	}
+}
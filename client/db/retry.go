@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/teejays/gokutil/errutil"
+	"github.com/teejays/gokutil/log"
+)
+
+// Postgres error codes for failures where rerunning the whole transaction is expected to succeed.
+// See: https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	PQ_ERR_CODE_SERIALIZATION_FAILURE pq.ErrorCode = "40001"
+	PQ_ERR_CODE_DEADLOCK_DETECTED     pq.ErrorCode = "40P01"
+)
+
+var retryableErrorCodes = []pq.ErrorCode{
+	PQ_ERR_CODE_SERIALIZATION_FAILURE,
+	PQ_ERR_CODE_DEADLOCK_DETECTED,
+}
+
+// IsRetryableError returns true if the error (or any error it wraps) is a Postgres error that can be fixed by rerunning
+// the transaction e.g. serialization failures and deadlocks.
+func IsRetryableError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	for _, code := range retryableErrorCodes {
+		if pqErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryOptions configure how RunInTxWithRetry reruns a transaction.
+type RetryOptions struct {
+	MaxAttempts    int           // Total number of attempts, including the first one. Defaults to 3.
+	InitialBackoff time.Duration // Wait before the second attempt. Defaults to 50ms.
+	MaxBackoff     time.Duration // Upper limit for the wait between attempts. Defaults to 2s.
+	TxOptions      *sql.TxOptions
+}
+
+var DefaultRetryOptions = RetryOptions{
+	MaxAttempts:    3,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+func (o RetryOptions) withDefaults() RetryOptions {
+	if o.MaxAttempts < 1 {
+		o.MaxAttempts = DefaultRetryOptions.MaxAttempts
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = DefaultRetryOptions.InitialBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultRetryOptions.MaxBackoff
+	}
+	return o
+}
+
+// getBackoff returns the wait before the given attempt (starting at 2), using exponential backoff with full jitter.
+func (o RetryOptions) getBackoff(attempt int) time.Duration {
+	backoff := o.InitialBackoff << (attempt - 2)
+	if backoff > o.MaxBackoff || backoff <= 0 {
+		backoff = o.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+// RunInTxWithRetry runs fn inside a new transaction and commits it. If fn or the commit fails with a retryable
+// error (see IsRetryableError), the transaction is rolled back and the whole of fn is run again, after a jittered
+// backoff, until opts.MaxAttempts is reached. fn may be called multiple times, so it should not have side effects
+// outside the transaction.
+// It cannot be called while the connection is already in a transaction, since the outer transaction can't be rerun.
+func (c *Connection) RunInTxWithRetry(ctx context.Context, opts RetryOptions, fn func(ctx context.Context, conn *Connection) error) error {
+	if c.IsInTransaction(ctx) {
+		return fmt.Errorf("RunInTxWithRetry cannot be called inside an existing transaction")
+	}
+	opts = opts.withDefaults()
+
+	var err error
+	for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			backoff := opts.getBackoff(attempt)
+			log.Warn(ctx, "Retrying SQL transaction", "attempt", attempt, "maxAttempts", opts.MaxAttempts, "backoff", backoff, "error", err)
+			select {
+			case <-ctx.Done():
+				return errutil.Wrap(ctx.Err(), "Waiting to retry transaction (attempt %d)", attempt)
+			case <-time.After(backoff):
+			}
+		}
+
+		err = c.runInTx(ctx, opts.TxOptions, fn)
+		if err == nil {
+			return nil
+		}
+		if !IsRetryableError(err) {
+			return err
+		}
+	}
+
+	return errutil.Wrap(err, "Transaction failed after [%d] attempts", opts.MaxAttempts)
+}
+
+// runInTx runs fn in a transaction, committing it if fn succeeds and rolling it back otherwise (including on panics).
+func (c *Connection) runInTx(ctx context.Context, txOpts *sql.TxOptions, fn func(ctx context.Context, conn *Connection) error) (err error) {
+	err = c.BeginTx(ctx, txOpts)
+	if err != nil {
+		return errutil.Wrap(err, "Beginning transaction")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if c.IsInTransaction(ctx) {
+				if rbErr := c.Rollback(ctx); rbErr != nil {
+					log.Error(ctx, "Rolling back transaction after panic", "error", rbErr)
+				}
+			}
+			panic(r)
+		}
+	}()
+
+	err = fn(ctx, c)
+	if err != nil {
+		if c.IsInTransaction(ctx) {
+			if rbErr := c.Rollback(ctx); rbErr != nil {
+				log.Error(ctx, "Rolling back transaction", "error", rbErr)
+			}
+		}
+		return err
+	}
+
+	err = c.Commit(ctx)
+	if err != nil {
+		return errutil.Wrap(err, "Committing transaction")
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/errutil"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pq.Error{Code: PQ_ERR_CODE_SERIALIZATION_FAILURE}, true},
+		{"deadlock", &pq.Error{Code: PQ_ERR_CODE_DEADLOCK_DETECTED}, true},
+		{"wrapped", errutil.Wrap(fmt.Errorf("committing: %w", &pq.Error{Code: PQ_ERR_CODE_SERIALIZATION_FAILURE}), "running tx"), true},
+		{"unique violation", &pq.Error{Code: PQ_ERR_CODE_UNIQUE_VIOLATION}, false},
+		{"not a pq error", fmt.Errorf("40001"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryableError(tt.err))
+		})
+	}
+}
+
+func TestRetryOptions_GetBackoff(t *testing.T) {
+	opts := RetryOptions{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}.withDefaults()
+	assert.Equal(t, DefaultRetryOptions.MaxAttempts, opts.MaxAttempts)
+
+	// Full jitter: (0, InitialBackoff * 2^(attempt-2)], capped at MaxBackoff
+	for attempt, upper := range map[int]time.Duration{
+		2:  10 * time.Millisecond,
+		3:  20 * time.Millisecond,
+		4:  40 * time.Millisecond,
+		5:  50 * time.Millisecond,
+		70: 50 * time.Millisecond, // the shift overflows
+	} {
+		for i := 0; i < 100; i++ {
+			got := opts.getBackoff(attempt)
+			assert.Greater(t, got, time.Duration(0), "attempt %d", attempt)
+			assert.LessOrEqual(t, got, upper, "attempt %d", attempt)
+		}
+	}
+
+	// Defaults
+	assert.Equal(t, DefaultRetryOptions, RetryOptions{}.withDefaults())
+}
@@ -59,7 +59,12 @@ type ITypeDALMeta[T types.BasicType, F types.Field] interface {
 
 	InternalHookSavePre(ctx context.Context, elem T, now scalars.Timestamp) (T, error)
 	InternalHookCreatePre(ctx context.Context, elem T, now scalars.Timestamp) (T, error)
-	// InternalHookReadPre(ctx context.Context, elem T) (T, error)
+	// InternalHookReadPre is optional, see IInternalHookReadPre.
+}
+
+// IInternalHookReadPre can be implemented by a ITypeDALMeta to change the read request before the custom ReadPre hooks.
+type IInternalHookReadPre interface {
+	InternalHookReadPre(ctx context.Context, req types.ReadRequest) (types.ReadRequest, error)
 }
 
 // TypeCommonDALMeta
@@ -72,6 +77,30 @@ type TypeCommonDALMeta[T types.BasicType, F types.Field] struct {
 	UpdatedAtField                F
 }
 
+// GetDALInfo implements types.IDALMeta, so the DAL meta can be added to the types.Registry.
+func (m *TypeCommonDALMeta[T, F]) GetDALInfo() types.DALInfo {
+	return types.DALInfo{
+		DatabaseColumnFields: fieldsToNames(m.DatabaseColumnFields),
+		SubTableFields:       fieldsToNames(m.DatabaseSubTableFields),
+		ImmutableFields:      fieldsToNames(m.ImmutableFields),
+		InternalFields:       fieldsToNames(m.SetInternallyByDALFields),
+	}
+}
+
+// RegisterTypeDALMeta registers the type meta, along with its DAL meta, with the default types.Registry and runs its
+// Init hooks.
+func RegisterTypeDALMeta[T types.BasicType, F types.Field](ctx context.Context, meta ITypeDALMeta[T, F]) error {
+	return types.Register(ctx, types.GetRegistry(), meta, meta.GetCommonDALMeta())
+}
+
+func fieldsToNames[F types.Field](fields []F) []naam.Name {
+	var names []naam.Name
+	for _, f := range fields {
+		names = append(names, f.Name())
+	}
+	return names
+}
+
 //	func NewBasicTypeDALMetaBase[T types.BasicType, F types.Field]() ITypeDALMeta[T, F] {
 //		return &TypeCommonDALMeta[T, F]{}
 //	}
@@ -159,6 +188,64 @@ func FieldsToStrings[T types.Field](fields []T) []string {
 	return colNames
 }
 
+// runHooks runs the hook chain for the hook point on each of the elems. It stops at the first error.
+func runHooks[T types.BasicType, F types.Field](ctx context.Context, meta ITypeDALMeta[T, F], point types.HookType, elems []T) ([]T, error) {
+	hooks := meta.GetHooks(point)
+	if len(hooks) == 0 {
+		log.None(ctx, "No hooks found", "type", meta.GetTypeCommonMeta().Name, "hookPoint", point)
+		return elems, nil
+	}
+	log.Info(ctx, "Running hooks", "type", meta.GetTypeCommonMeta().Name, "hookPoint", point, "count", len(hooks))
+	for i := range elems {
+		var err error
+		elems[i], err = types.RunHooks(ctx, hooks, elems[i])
+		if err != nil {
+			if len(elems) == 1 {
+				return nil, fmt.Errorf("Running hooks [%s]: %w", point, err)
+			}
+			return nil, fmt.Errorf("Running hooks [%s] for item index [%d]: %w", point, i, err)
+		}
+	}
+	return elems, nil
+}
+
+// runHook is like runHooks, but for a single elem.
+func runHook[T types.BasicType, F types.Field](ctx context.Context, meta ITypeDALMeta[T, F], point types.HookType, elem T) (T, error) {
+	elems, err := runHooks(ctx, meta, point, []T{elem})
+	if err != nil {
+		return elem, err
+	}
+	return elems[0], nil
+}
+
+// publishEvent publishes the event to the event bus once the transaction on the connection (if any) commits.
+func publishEvent[T types.BasicType](ctx context.Context, conn *db.Connection, event types.Event[T]) {
+	conn.AfterCommit(ctx, func(ctx context.Context) {
+		types.Publish(ctx, types.GetEventBus(), event)
+	})
+}
+
+// runReadPreHooks runs the internal ReadPre hook, if the meta has one, and then the ReadPre hook chain.
+func runReadPreHooks[T types.BasicType, F types.Field](ctx context.Context, meta ITypeDALMeta[T, F], req types.ReadRequest) (types.ReadRequest, error) {
+	var err error
+	if m, ok := meta.(IInternalHookReadPre); ok {
+		req, err = m.InternalHookReadPre(ctx, req)
+		if err != nil {
+			return req, fmt.Errorf("Running InternalHookReadPre: %w", err)
+		}
+	}
+	hooks := meta.GetReadPreHooks()
+	if len(hooks) == 0 {
+		return req, nil
+	}
+	log.Info(ctx, "Running hooks", "type", meta.GetTypeCommonMeta().Name, "hookPoint", types.HookPoint_ReadPre, "count", len(hooks))
+	req, err = types.RunReadPreHooks(ctx, hooks, req)
+	if err != nil {
+		return req, fmt.Errorf("Running hooks [%s]: %w", types.HookPoint_ReadPre, err)
+	}
+	return req, nil
+}
+
 func AddType[T types.BasicType, F types.Field](ctx context.Context, conn *db.Connection, params db.InsertTypeParams, meta ITypeDALMeta[T, F], elem T) (T, error) {
 	log.Info(ctx, "Adding type", "type", meta.GetTypeCommonMeta().Name, "data", elem)
 	var emptyT T
@@ -198,39 +285,15 @@ func BatchAddType[T types.BasicType, F types.Field](ctx context.Context, conn *d
 	}
 
 	// Run any before create hooks
-	if fn := meta.GetHookCreatePre(); fn != nil {
-		log.Info(ctx, "Running HookCreatePre", "type", meta.GetTypeCommonMeta().Name)
-		for i := range elems {
-			var err error
-			elems[i], err = fn(ctx, elems[i])
-			if err != nil {
-				if len(elems) == 1 {
-					return nil, fmt.Errorf("HookCreatePre failed: %w", err)
-				} else {
-					return nil, fmt.Errorf("HookCreatePre failed for item index [%d]: %w", i, err)
-				}
-			}
-		}
-	} else {
-		log.Debug(ctx, "No HookCreatePre found", "type", meta.GetTypeCommonMeta().Name, "meta", meta)
+	elems, err = runHooks(ctx, meta, types.HookPoint_CreatePre, elems)
+	if err != nil {
+		return nil, err
 	}
 
 	// Run any before save hooks
-	if fn := meta.GetHookSavePre(); fn != nil {
-		log.Info(ctx, "Running HookSavePre", "type", meta.GetTypeCommonMeta().Name)
-		for i := range elems {
-			var err error
-			elems[i], err = fn(ctx, elems[i])
-			if err != nil {
-				if len(elems) == 1 {
-					return nil, fmt.Errorf("HookSavePre failed: %w", err)
-				} else {
-					return nil, fmt.Errorf("HookSavePre failed for item index [%d]: %w", i, err)
-				}
-			}
-		}
-	} else {
-		log.Debug(ctx, "No HookSavePre found", "type", meta.GetTypeCommonMeta().Name, "meta", meta)
+	elems, err = runHooks(ctx, meta, types.HookPoint_SavePre, elems)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate the types before they are added
@@ -290,46 +353,28 @@ func BatchAddType[T types.BasicType, F types.Field](ctx context.Context, conn *d
 	}
 
 	// Run any after save hooks
-	if fn := meta.GetHookSavePost(); fn != nil {
-		log.Info(ctx, "Running HookSavePost", "type", meta.GetTypeCommonMeta().Name)
-		for i := range elems {
-			var err error
-			elems[i], err = fn(ctx, elems[i])
-			if err != nil {
-				if len(elems) == 1 {
-					return nil, fmt.Errorf("HookSavePost failed: %w", err)
-				} else {
-					return nil, fmt.Errorf("HookSavePost failed for item index [%d]: %w", i, err)
-				}
-			}
-		}
-	} else {
-		log.Debug(ctx, "No HookSavePost found", "type", meta.GetTypeCommonMeta().Name, "meta", meta)
+	elems, err = runHooks(ctx, meta, types.HookPoint_SavePost, elems)
+	if err != nil {
+		return nil, err
 	}
 
 	// Run any after create hooks
-	if fn := meta.GetHookCreatePost(); fn != nil {
-		log.Info(ctx, "Running HookCreatePost", "type", meta.GetTypeCommonMeta().Name)
-		for i := range elems {
-			var err error
-			elems[i], err = fn(ctx, elems[i])
-			if err != nil {
-				if len(elems) == 1 {
-					return nil, fmt.Errorf("HookCreatePost failed: %w", err)
-				} else {
-					return nil, fmt.Errorf("HookCreatePost failed for item index [%d]: %w", i, err)
-				}
-			}
-		}
-	} else {
-		log.Debug(ctx, "No HookCreatePost found", "type", meta.GetTypeCommonMeta().Name, "meta", meta)
+	elems, err = runHooks(ctx, meta, types.HookPoint_CreatePost, elems)
+	if err != nil {
+		return nil, err
+	}
+
+	// Publish the created events once the transaction (if any) commits
+	for i := range elems {
+		publishEvent(ctx, conn, types.NewCreatedEvent(meta.GetTypeCommonMeta().Name, elems[i]))
 	}
 
 	return elems, nil
 
 }
 
-// ListTypeByIDs fetches a list of type T based on the IDs provided.
+// ListTypeByIDs fetches a list of type T based on the IDs provided. The ReadPre hooks run before the query, and can
+// change or deny it. This includes the reads that UpdateType and DeleteType make for the existing object.
 func ListTypeByIDs[T types.BasicType, F types.Field](ctx context.Context, conn *db.Connection, params db.ListTypeByIDsParams, meta ITypeDALMeta[T, F]) (ListTypeResponse[T], error) {
 	var resp ListTypeResponse[T]
 
@@ -338,11 +383,26 @@ func ListTypeByIDs[T types.BasicType, F types.Field](ctx context.Context, conn *
 		return resp, nil
 	}
 
+	// Read Pre Hooks: these can change the request (e.g. add conditions) or deny it
+	readReq, err := runReadPreHooks(ctx, meta, types.ReadRequest{
+		TableName: params.TableName,
+		IDColumn:  params.IDColumn,
+		IDs:       params.IDs,
+	})
+	if err != nil {
+		return resp, err
+	}
+	params.TableName, params.IDColumn, params.IDs = readReq.TableName, readReq.IDColumn, readReq.IDs
+	if len(params.IDs) < 1 {
+		return resp, nil
+	}
+
 	subReq := db.SelectByIDBuilderRequest{
 		TableName: params.TableName,
 		Columns:   meta.GetDatabaseColumns(),
 		IDColumn:  params.IDColumn,
 		IDs:       params.IDs,
+		Where:     readReq.Where,
 	}
 
 	query, args, err := db.ConstructSelectByIDQuery(ctx, conn.Dialect, subReq)
@@ -375,22 +435,10 @@ func ListTypeByIDs[T types.BasicType, F types.Field](ctx context.Context, conn *
 		return resp, err
 	}
 
-	// Run any before save hooks
-	if fn := meta.GetHookReadPost(); fn != nil {
-		log.Info(ctx, "Running HookReadPost", "type", meta.GetTypeCommonMeta().Name)
-		for i := range elems {
-			var err error
-			elems[i], err = fn(ctx, elems[i])
-			if err != nil {
-				if len(elems) == 1 {
-					return resp, fmt.Errorf("HookReadPost failed: %w", err)
-				} else {
-					return resp, fmt.Errorf("HookReadPost failed for item index [%d]: %w", i, err)
-				}
-			}
-		}
-	} else {
-		log.None(ctx, "No HookReadPost found", "type", meta.GetTypeCommonMeta().Name, "meta", meta)
+	// Run any after read hooks
+	elems, err = runHooks(ctx, meta, types.HookPoint_ReadPost, elems)
+	if err != nil {
+		return resp, err
 	}
 
 	resp.Items = elems
@@ -508,29 +556,15 @@ func UpdateType[T types.BasicType, F types.Field](ctx context.Context, req Updat
 	}
 
 	// Update Pre Hooks
-	if fn := meta.GetHookUpdatePre(); fn != nil {
-		log.Info(ctx, "Running HookUpdatePre", "type", meta.GetTypeCommonMeta().Name)
-		var err error
-		elem, err = fn(ctx, elem)
-		if err != nil {
-			return resp, fmt.Errorf("HookUpdatePre failed: %w", err)
-		}
-
-	} else {
-		log.None(ctx, "No HookUpdatePre found", "type", meta.GetTypeCommonMeta().Name, "meta", meta)
+	elem, err = runHook(ctx, meta, types.HookPoint_UpdatePre, elem)
+	if err != nil {
+		return resp, err
 	}
 
 	// Save Pre Hooks
-	if fn := meta.GetHookSavePre(); fn != nil {
-		log.Info(ctx, "Running HookSavePre", "type", meta.GetTypeCommonMeta().Name)
-		var err error
-		elem, err = fn(ctx, elem)
-		if err != nil {
-			return resp, fmt.Errorf("HookSavePre failed: %w", err)
-		}
-
-	} else {
-		log.None(ctx, "No HookSavePre found", "type", meta.GetTypeCommonMeta().Name, "meta", meta)
+	elem, err = runHook(ctx, meta, types.HookPoint_SavePre, elem)
+	if err != nil {
+		return resp, err
 	}
 
 	// Direct table
@@ -587,30 +621,19 @@ func UpdateType[T types.BasicType, F types.Field](ctx context.Context, req Updat
 
 	// Run any after save hooks
 	// Save Post Hooks
-	if fn := meta.GetHookSavePost(); fn != nil {
-		log.Info(ctx, "Running HookSavePost", "type", meta.GetTypeCommonMeta().Name)
-		var err error
-		elem, err = fn(ctx, elem)
-		if err != nil {
-			return resp, fmt.Errorf("HookSavePost failed: %w", err)
-		}
-
-	} else {
-		log.None(ctx, "No HookSavePost found", "type", meta.GetTypeCommonMeta().Name, "meta", meta)
+	elem, err = runHook(ctx, meta, types.HookPoint_SavePost, elem)
+	if err != nil {
+		return resp, err
 	}
 
 	// Update Post Hooks
-	if fn := meta.GetHookUpdatePost(); fn != nil {
-		log.Info(ctx, "Running HookUpdatePost", "type", meta.GetTypeCommonMeta().Name)
-		var err error
-		elem, err = fn(ctx, elem)
-		if err != nil {
-			return resp, fmt.Errorf("HookUpdatePost failed: %w", err)
-		}
-	} else {
-		log.None(ctx, "No HookUpdatePost found", "type", meta.GetTypeCommonMeta().Name)
+	elem, err = runHook(ctx, meta, types.HookPoint_UpdatePost, elem)
+	if err != nil {
+		return resp, err
 	}
 
+	publishEvent(ctx, conn, types.NewUpdatedEvent(meta.GetTypeCommonMeta().Name, oldElem, elem))
+
 	resp.Object = elem
 
 	return resp, nil
@@ -721,6 +744,8 @@ func DeleteType[T types.BasicType, F types.Field](ctx context.Context, req Delet
 		return resp, fmt.Errorf("HookDeletePost not implemented")
 	}
 
+	publishEvent(ctx, req.Connection, types.NewDeletedEvent(typName, oldElem))
+
 	return resp, nil
 
 }
@@ -3,6 +3,7 @@ module github.com/teejays/gokutil/dalutil
 go 1.23.4
 
 require (
+	github.com/stretchr/testify v1.10.0
 	github.com/teejays/gokutil/aiutil v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/client/db v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/errutil v0.0.0-20250426215142-5dc7bd3f1fd0
@@ -18,12 +19,16 @@ require (
 	github.com/Rican7/conjson v0.1.0 // indirect
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/doug-martin/goqu/v9 v9.19.0 // indirect
+	github.com/felixge/httpsnoop v1.0.4 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.9 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/go-playground/validator/v10 v10.26.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
+	github.com/gorilla/handlers v1.5.2 // indirect
+	github.com/gorilla/mux v1.8.1 // indirect
 	github.com/graph-gophers/graphql-go v1.6.0 // indirect
 	github.com/iancoleman/strcase v0.3.0 // indirect
 	github.com/invopop/jsonschema v0.13.0 // indirect
@@ -31,11 +36,13 @@ require (
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/lib/pq v1.10.9 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/teejays/gokutil/clog v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/ctxutil v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/env v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/gopi v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
+	github.com/teejays/gokutil/httputil v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/sclog v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/strcase v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
@@ -0,0 +1,411 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/teejays/gokutil/client/db"
+	"github.com/teejays/gokutil/errutil"
+	"github.com/teejays/gokutil/log"
+	"github.com/teejays/gokutil/scalars"
+)
+
+var llog = log.GetLogger().WithHeading("Migrate")
+
+// MIGRATIONS_TABLE keeps a record of all the migrations that have been applied to the database.
+const MIGRATIONS_TABLE = "schema_migrations"
+
+/* * * * * * *
+ * Plan
+ * * * * * * */
+
+// Statement is a single DDL statement of a migration.
+type Statement struct {
+	SQL string
+	// Destructive statements can lose or change data (e.g. dropping a column, or changing its type), or can fail on a
+	// table that has rows (e.g. making a column NOT NULL), and are only applied when forced.
+	Destructive bool
+	// Reason says why the statement is destructive.
+	Reason string
+}
+
+// Plan is the list of statements needed to bring the database in line with the Schema.
+type Plan struct {
+	Statements []Statement
+}
+
+func (p Plan) IsEmpty() bool {
+	return len(p.Statements) < 1
+}
+
+func (p Plan) HasDestructive() bool {
+	for _, s := range p.Statements {
+		if s.Destructive {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Plan) GetDestructive() []Statement {
+	var r []Statement
+	for _, s := range p.Statements {
+		if s.Destructive {
+			r = append(r, s)
+		}
+	}
+	return r
+}
+
+// SQL returns all the statements of the plan as a single SQL script.
+func (p Plan) SQL() string {
+	var stmts []string
+	for _, s := range p.Statements {
+		stmts = append(stmts, s.SQL)
+	}
+	return strings.Join(stmts, "\n")
+}
+
+// Diff compares the Schema with the tables that currently exist in the database (keyed by table name) and returns
+// the statements needed to migrate them. Tables in the database that are not part of the schema are left alone.
+func Diff(schema Schema, current map[string]Table) Plan {
+	var plan Plan
+	add := func(format string, args ...interface{}) {
+		plan.Statements = append(plan.Statements, Statement{SQL: fmt.Sprintf(format, args...)})
+	}
+	addDestructive := func(reason string, format string, args ...interface{}) {
+		plan.Statements = append(plan.Statements, Statement{SQL: fmt.Sprintf(format, args...), Destructive: true, Reason: reason})
+	}
+
+	for _, want := range schema.sortedTables() {
+		have, exists := current[want.Name]
+		if !exists {
+			add("%s", want.CreateTableSQL())
+			continue
+		}
+		table := quoteIdent(want.Name)
+
+		for _, wantCol := range want.Columns {
+			haveCol, exists := have.GetColumn(wantCol.Name)
+			col := quoteIdent(wantCol.Name)
+			if !exists {
+				if wantCol.Nullable {
+					add("ALTER TABLE %s ADD COLUMN %s;", table, wantCol.definitionSQL())
+					continue
+				}
+				// The existing rows get the zero value, which is what they would have had if the column was there
+				// from the start. The default is only needed to add the column.
+				def, ok := getZeroValueSQL(wantCol.Type)
+				if !ok {
+					addDestructive(fmt.Sprintf("column [%s] is NOT NULL and has no default, so it cannot be added if the table has rows", wantCol.Name),
+						"ALTER TABLE %s ADD COLUMN %s;", table, wantCol.definitionSQL())
+					continue
+				}
+				add("ALTER TABLE %s ADD COLUMN %s DEFAULT %s;", table, wantCol.definitionSQL(), def)
+				add("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", table, col)
+				continue
+			}
+			if normalizeType(wantCol.Type) != normalizeType(haveCol.Type) {
+				addDestructive(fmt.Sprintf("column [%s] changes type from [%s] to [%s]", wantCol.Name, haveCol.Type, wantCol.Type),
+					"ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;", table, col, wantCol.Type, col, wantCol.Type)
+			}
+			if wantCol.Nullable && !haveCol.Nullable {
+				add("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;", table, col)
+			}
+			if !wantCol.Nullable && haveCol.Nullable {
+				def, ok := getZeroValueSQL(wantCol.Type)
+				if !ok {
+					addDestructive(fmt.Sprintf("column [%s] becomes NOT NULL and has no default, so it cannot be changed if it has NULL values", wantCol.Name),
+						"ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", table, col)
+					continue
+				}
+				addDestructive(fmt.Sprintf("column [%s] becomes NOT NULL, so its NULL values are replaced with %s", wantCol.Name, def),
+					"UPDATE %s SET %s = %s WHERE %s IS NULL;", table, col, def, col)
+				add("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", table, col)
+			}
+		}
+
+		for _, haveCol := range have.Columns {
+			if _, exists := want.GetColumn(haveCol.Name); !exists {
+				addDestructive(fmt.Sprintf("column [%s] is dropped", haveCol.Name),
+					"ALTER TABLE %s DROP COLUMN %s;", table, quoteIdent(haveCol.Name))
+			}
+		}
+	}
+
+	return plan
+}
+
+// getZeroValueSQL returns the SQL literal for the Go zero value stored in a column of the type, if there is a clear one.
+func getZeroValueSQL(typ string) (string, bool) {
+	t := normalizeType(typ)
+	if strings.HasSuffix(t, "[]") {
+		return "'{}'", true
+	}
+	switch t {
+	case "text", "character varying", "character":
+		return "''", true
+	case "boolean":
+		return "false", true
+	case "smallint", "integer", "bigint", "real", "double precision", "numeric":
+		return "0", true
+	case "bytea":
+		return "''::bytea", true
+	}
+	return "", false
+}
+
+// Generate returns the plan to migrate the database that the connection points to, without applying it.
+func Generate(ctx context.Context, conn *db.Connection, schema Schema) (Plan, error) {
+	current, err := GetCurrentTables(ctx, conn)
+	if err != nil {
+		return Plan{}, err
+	}
+	return Diff(schema, current), nil
+}
+
+/* * * * * * *
+ * Introspection
+ * * * * * * */
+
+// GetCurrentTables reads the tables and columns of the current schema of the database from information_schema.
+func GetCurrentTables(ctx context.Context, conn *db.Connection) (map[string]Table, error) {
+	query := `SELECT c.table_name, c.column_name, c.data_type, c.udt_name, c.is_nullable
+		FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE'
+		ORDER BY c.table_name, c.ordinal_position`
+
+	rows, err := conn.QueryRows(db.WithPrimaryRead(ctx), query)
+	if err != nil {
+		return nil, errutil.Wrap(err, "Querying information_schema columns")
+	}
+	defer rows.Close()
+
+	tables := map[string]Table{}
+	for rows.Next() {
+		var tableName, columnName, dataType, udtName, isNullable string
+		if err := rows.Scan(&tableName, &columnName, &dataType, &udtName, &isNullable); err != nil {
+			return nil, errutil.Wrap(err, "Scanning information_schema columns")
+		}
+		t := tables[tableName]
+		t.Name = tableName
+		t.Columns = append(t.Columns, Column{
+			Name:     columnName,
+			Type:     getColumnType(dataType, udtName),
+			Nullable: isNullable == "YES",
+		})
+		tables[tableName] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errutil.Wrap(err, "Reading information_schema columns")
+	}
+
+	return tables, nil
+}
+
+// udtTypes maps the Postgres internal type names (as used in information_schema udt_name) to their SQL names.
+var udtTypes = map[string]string{
+	"bool":        "boolean",
+	"int2":        "smallint",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"varchar":     "character varying",
+	"bpchar":      "character",
+}
+
+// getColumnType converts the type reported by information_schema into the type name we use. Array columns are only
+// reported as "ARRAY" in data_type, so their element type is taken from udt_name (e.g. "_text").
+func getColumnType(dataType, udtName string) string {
+	if dataType != "ARRAY" {
+		return dataType
+	}
+	elem := strings.TrimPrefix(udtName, "_")
+	if t, ok := udtTypes[elem]; ok {
+		elem = t
+	}
+	return elem + "[]"
+}
+
+var typeAliases = map[string]string{
+	"timestamptz": "timestamp with time zone",
+	"int":         "integer",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"int2":        "smallint",
+	"bool":        "boolean",
+	"float8":      "double precision",
+	"float4":      "real",
+	"varchar":     "character varying",
+}
+
+func normalizeType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	suffix := ""
+	for strings.HasSuffix(t, "[]") {
+		t = strings.TrimSuffix(t, "[]")
+		suffix += "[]"
+	}
+	// Precision e.g. numeric(10,2) is not part of the type for comparison purposes
+	if i := strings.Index(t, "("); i > 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+	if alias, ok := typeAliases[t]; ok {
+		t = alias
+	}
+	return t + suffix
+}
+
+/* * * * * * *
+ * Apply
+ * * * * * * */
+
+type ApplyOptions struct {
+	// Force allows destructive statements (e.g. dropping columns) to be applied.
+	Force bool
+	// Description is saved with the migration record.
+	Description string
+}
+
+// Migration is a record of an applied migration.
+type Migration struct {
+	Version     int64
+	Description string
+	Statements  string
+	AppliedAt   scalars.Timestamp
+}
+
+// MIGRATION_LOCK_KEY is the key of the advisory lock that makes sure only one process migrates the database at a time.
+const MIGRATION_LOCK_KEY = "gokutil.dalutil.migrate"
+
+// Apply migrates the database to the Schema in a single transaction, and records the migration as a new version in
+// the migrations table. An advisory lock is held for the duration of the transaction so that concurrent processes
+// (e.g. multiple instances of a service starting together) don't race. If the plan has destructive statements, it is
+// refused unless opts.Force is set. The returned Migration is nil if the database was already up to date.
+func Apply(ctx context.Context, conn *db.Connection, schema Schema, opts ApplyOptions) (*Migration, error) {
+	if conn.IsInTransaction(ctx) {
+		return nil, fmt.Errorf("migrations cannot be applied inside an existing transaction")
+	}
+
+	if err := conn.Begin(ctx); err != nil {
+		return nil, errutil.Wrap(err, "Beginning migration transaction")
+	}
+	migration, err := apply(ctx, conn, schema, opts)
+	if err != nil {
+		if rbErr := conn.Rollback(ctx); rbErr != nil {
+			llog.Error(ctx, "Rolling back migration transaction", "error", rbErr)
+		}
+		return nil, err
+	}
+	if err := conn.Commit(ctx); err != nil {
+		return nil, errutil.Wrap(err, "Committing migration transaction")
+	}
+
+	if migration != nil {
+		llog.Info(ctx, "Applied migration", "version", migration.Version, "statements", migration.Statements)
+	}
+	return migration, nil
+}
+
+func apply(ctx context.Context, conn *db.Connection, schema Schema, opts ApplyOptions) (*Migration, error) {
+	sqlConn, err := conn.GetQueryableConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transaction-scoped, so it's released automatically when the transaction ends
+	if _, err := conn.AdvisoryLock(ctx, MIGRATION_LOCK_KEY); err != nil {
+		return nil, errutil.Wrap(err, "Acquiring migration lock")
+	}
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	// Diff only after the lock is held, since another process might have just migrated the database
+	plan, err := Generate(ctx, conn, schema)
+	if err != nil {
+		return nil, errutil.Wrap(err, "Generating migration plan")
+	}
+	if plan.IsEmpty() {
+		llog.Debug(ctx, "Database schema is up to date, no migration needed")
+		return nil, nil
+	}
+	if plan.HasDestructive() && !opts.Force {
+		var stmts []string
+		for _, s := range plan.GetDestructive() {
+			stmts = append(stmts, fmt.Sprintf("%s (%s)", s.SQL, s.Reason))
+		}
+		return nil, fmt.Errorf("migration has destructive statements and is not forced: %s", strings.Join(stmts, " "))
+	}
+
+	for _, s := range plan.Statements {
+		llog.Debug(ctx, "Running migration statement", "sql", s.SQL, "destructive", s.Destructive, "reason", s.Reason)
+		if _, err := sqlConn.ExecContext(ctx, s.SQL); err != nil {
+			return nil, errutil.Wrap(db.TranslateError(err), "Running migration statement [%s]", s.SQL)
+		}
+	}
+
+	migration := Migration{
+		Description: opts.Description,
+		Statements:  plan.SQL(),
+		AppliedAt:   scalars.NewTimestampNow(),
+	}
+	err = conn.QueryRow(ctx, &migration.Version, fmt.Sprintf("SELECT COALESCE(MAX(version), 0) + 1 FROM %s", quoteIdent(MIGRATIONS_TABLE)))
+	if err != nil {
+		return nil, errutil.Wrap(err, "Getting next migration version")
+	}
+	_, err = conn.ExecuteQuery(ctx,
+		fmt.Sprintf("INSERT INTO %s (version, description, statements, applied_at) VALUES ($1, $2, $3, $4)", quoteIdent(MIGRATIONS_TABLE)),
+		migration.Version, migration.Description, migration.Statements, migration.AppliedAt,
+	)
+	if err != nil {
+		return nil, errutil.Wrap(err, "Recording migration version [%d]", migration.Version)
+	}
+
+	return &migration, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *db.Connection) error {
+	sqlConn, err := conn.GetQueryableConnection(ctx)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version bigint NOT NULL PRIMARY KEY,
+		description text NOT NULL DEFAULT '',
+		statements text NOT NULL,
+		applied_at timestamp with time zone NOT NULL
+	)`, quoteIdent(MIGRATIONS_TABLE))
+	if _, err := sqlConn.ExecContext(ctx, query); err != nil {
+		return errutil.Wrap(err, "Creating migrations table")
+	}
+	return nil
+}
+
+// GetAppliedMigrations returns all the migrations that have been applied, ordered by version.
+func GetAppliedMigrations(ctx context.Context, conn *db.Connection) ([]Migration, error) {
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryRows(db.WithPrimaryRead(ctx), fmt.Sprintf("SELECT version, description, statements, applied_at FROM %s ORDER BY version", quoteIdent(MIGRATIONS_TABLE)))
+	if err != nil {
+		return nil, errutil.Wrap(err, "Querying migrations")
+	}
+	defer rows.Close()
+
+	var migrations []Migration
+	for rows.Next() {
+		var m Migration
+		if err := rows.Scan(&m.Version, &m.Description, &m.Statements, &m.AppliedAt); err != nil {
+			return nil, errutil.Wrap(err, "Scanning migration")
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, rows.Err()
+}
@@ -0,0 +1,137 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	users := Table{Name: "users", Columns: []Column{
+		{Name: "id", Type: "uuid", PrimaryKey: true},
+		{Name: "name", Type: "text"},
+	}}
+
+	tests := []struct {
+		name            string
+		want            Table
+		have            *Table
+		wantSQL         []string
+		wantDestructive []string
+	}{
+		{
+			name:    "new table",
+			want:    users,
+			wantSQL: []string{users.CreateTableSQL()},
+		},
+		{
+			name: "up to date, with type aliases",
+			want: users,
+			have: &Table{Name: "users", Columns: []Column{{Name: "id", Type: "UUID"}, {Name: "name", Type: "text"}}},
+		},
+		{
+			name: "nullable column added",
+			want: Table{Name: "users", Columns: append(users.Columns, Column{Name: "bio", Type: "text", Nullable: true})},
+			have: &users,
+			wantSQL: []string{
+				`ALTER TABLE "users" ADD COLUMN "bio" text;`,
+			},
+		},
+		{
+			name: "NOT NULL column added with the zero value as default",
+			want: Table{Name: "users", Columns: append(users.Columns, Column{Name: "age", Type: "bigint"}, Column{Name: "tags", Type: "text[]"})},
+			have: &users,
+			wantSQL: []string{
+				`ALTER TABLE "users" ADD COLUMN "age" bigint NOT NULL DEFAULT 0;`,
+				`ALTER TABLE "users" ALTER COLUMN "age" DROP DEFAULT;`,
+				`ALTER TABLE "users" ADD COLUMN "tags" text[] NOT NULL DEFAULT '{}';`,
+				`ALTER TABLE "users" ALTER COLUMN "tags" DROP DEFAULT;`,
+			},
+		},
+		{
+			name:            "NOT NULL column added without a zero value",
+			want:            Table{Name: "users", Columns: append(users.Columns, Column{Name: "born_at", Type: "timestamp with time zone"})},
+			have:            &users,
+			wantSQL:         []string{`ALTER TABLE "users" ADD COLUMN "born_at" timestamp with time zone NOT NULL;`},
+			wantDestructive: []string{`ALTER TABLE "users" ADD COLUMN "born_at" timestamp with time zone NOT NULL;`},
+		},
+		{
+			name: "column made NOT NULL is backfilled",
+			want: users,
+			have: &Table{Name: "users", Columns: []Column{{Name: "id", Type: "uuid"}, {Name: "name", Type: "text", Nullable: true}}},
+			wantSQL: []string{
+				`UPDATE "users" SET "name" = '' WHERE "name" IS NULL;`,
+				`ALTER TABLE "users" ALTER COLUMN "name" SET NOT NULL;`,
+			},
+			wantDestructive: []string{`UPDATE "users" SET "name" = '' WHERE "name" IS NULL;`},
+		},
+		{
+			name:            "column without a zero value made NOT NULL",
+			want:            Table{Name: "users", Columns: []Column{{Name: "born_on", Type: "date"}}},
+			have:            &Table{Name: "users", Columns: []Column{{Name: "born_on", Type: "date", Nullable: true}}},
+			wantSQL:         []string{`ALTER TABLE "users" ALTER COLUMN "born_on" SET NOT NULL;`},
+			wantDestructive: []string{`ALTER TABLE "users" ALTER COLUMN "born_on" SET NOT NULL;`},
+		},
+		{
+			name:    "column made nullable",
+			want:    Table{Name: "users", Columns: []Column{{Name: "name", Type: "text", Nullable: true}}},
+			have:    &Table{Name: "users", Columns: []Column{{Name: "name", Type: "text"}}},
+			wantSQL: []string{`ALTER TABLE "users" ALTER COLUMN "name" DROP NOT NULL;`},
+		},
+		{
+			name:            "type changed and column dropped",
+			want:            Table{Name: "users", Columns: []Column{{Name: "age", Type: "integer"}}},
+			have:            &Table{Name: "users", Columns: []Column{{Name: "age", Type: "text"}, {Name: "old", Type: "text"}}},
+			wantSQL:         []string{`ALTER TABLE "users" ALTER COLUMN "age" TYPE integer USING "age"::integer;`, `ALTER TABLE "users" DROP COLUMN "old";`},
+			wantDestructive: []string{`ALTER TABLE "users" ALTER COLUMN "age" TYPE integer USING "age"::integer;`, `ALTER TABLE "users" DROP COLUMN "old";`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := map[string]Table{
+				// Tables outside of the schema are left alone
+				"other": {Name: "other", Columns: []Column{{Name: "id", Type: "uuid"}}},
+			}
+			if tt.have != nil {
+				current[tt.have.Name] = *tt.have
+			}
+			plan := Diff(Schema{Tables: []Table{tt.want}}, current)
+
+			var gotSQL, gotDestructive []string
+			for _, s := range plan.Statements {
+				gotSQL = append(gotSQL, s.SQL)
+				if s.Destructive {
+					gotDestructive = append(gotDestructive, s.SQL)
+					assert.NotEmpty(t, s.Reason, s.SQL)
+				}
+			}
+			assert.Equal(t, tt.wantSQL, gotSQL)
+			assert.Equal(t, tt.wantDestructive, gotDestructive)
+			assert.Equal(t, len(tt.wantSQL) == 0, plan.IsEmpty())
+			assert.Equal(t, len(tt.wantDestructive) > 0, plan.HasDestructive())
+		})
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"text", "text"},
+		{" TEXT ", "text"},
+		{"timestamptz", "timestamp with time zone"},
+		{"int", "integer"},
+		{"int8", "bigint"},
+		{"bool", "boolean"},
+		{"varchar(255)", "character varying"},
+		{"numeric(10,2)", "numeric"},
+		{"int4[]", "integer[]"},
+		{"text[][]", "text[][]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeType(tt.input))
+		})
+	}
+}
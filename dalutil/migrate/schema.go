@@ -0,0 +1,324 @@
+// Package migrate generates and applies SQL schema migrations for the tables described by DAL metas.
+package migrate
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/teejays/gokutil/dalutil"
+	"github.com/teejays/gokutil/naam"
+	"github.com/teejays/gokutil/scalars"
+	"github.com/teejays/gokutil/types"
+)
+
+// PARENT_ID_COLUMN is the column that links a sub-table row to the row of its parent table.
+const PARENT_ID_COLUMN = "parent_id"
+
+// Column is a single SQL column of a table.
+type Column struct {
+	Name       string
+	Type       string // SQL type, as reported by information_schema e.g. "uuid", "text", "timestamp with time zone", "text[]"
+	Nullable   bool
+	PrimaryKey bool
+}
+
+// Table is a SQL table along with its columns.
+type Table struct {
+	Name    string
+	Columns []Column
+}
+
+func (t Table) GetColumn(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// Schema is a set of tables that are managed by migrations. Tables which are not part of the schema are never touched.
+type Schema struct {
+	Tables []Table
+}
+
+func (s *Schema) AddTable(t Table) error {
+	for _, existing := range s.Tables {
+		if existing.Name == t.Name {
+			return fmt.Errorf("table [%s] is already registered in the schema", t.Name)
+		}
+	}
+	s.Tables = append(s.Tables, t)
+	return nil
+}
+
+// AddEntity adds the table of an entity, and the tables of its sub-table fields, to the schema.
+func AddEntity[T types.EntityTypeMutable, F types.Field](s *Schema, meta dalutil.IEntityDALMeta[T, F]) error {
+	return AddType(s, meta.GetDbTableName(), meta.GetTypeDALMeta())
+}
+
+// AddType adds the table for a type with the given DAL meta, and the tables of its sub-table fields, to the schema.
+// The SQL column types are derived from the Go types of the fields of T.
+func AddType[T types.BasicType, F types.Field](s *Schema, tableName naam.Name, meta dalutil.ITypeDALMeta[T, F]) error {
+	tables, err := NewTablesFromDALMeta(tableName, meta)
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if err := s.AddTable(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewTablesFromDALMeta returns the main table of the type followed by the tables for its sub-table fields. Sub-tables
+// are named `<table>_<field>`, and get a parent_id column (if their type doesn't already have one).
+func NewTablesFromDALMeta[T types.BasicType, F types.Field](tableName naam.Name, meta dalutil.ITypeDALMeta[T, F]) ([]Table, error) {
+	dalMeta := meta.GetCommonDALMeta()
+	structType := derefType(reflect.TypeOf((*T)(nil)).Elem())
+	if structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("type [%s] is not a struct", structType)
+	}
+
+	table := Table{Name: tableName.FormatSQLTable()}
+	for _, f := range dalMeta.DatabaseColumnFields {
+		sf, ok := getStructField(structType, f.Name())
+		if !ok {
+			return nil, fmt.Errorf("type [%s] has no struct field for column field [%s]", structType, f)
+		}
+		col, err := newColumn(f.Name().FormatSQLColumn(), sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column [%s] of table [%s]: %w", f.Name().FormatSQLColumn(), table.Name, err)
+		}
+		if types.IsFieldInFields(f, dalMeta.DatabaseColumnTimestampFields) {
+			col.Type = "timestamp with time zone"
+		}
+		table.Columns = append(table.Columns, col)
+	}
+
+	tables := []Table{table}
+	for _, f := range dalMeta.DatabaseSubTableFields {
+		sf, ok := getStructField(structType, f.Name())
+		if !ok {
+			return nil, fmt.Errorf("type [%s] has no struct field for sub-table field [%s]", structType, f)
+		}
+		subTables, err := newSubTables(tableName.AppendName(f.Name()), sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("sub-table field [%s] of table [%s]: %w", f, table.Name, err)
+		}
+		tables = append(tables, subTables...)
+	}
+
+	return tables, nil
+}
+
+// newSubTables creates the table for a nested type using all of its exported fields, recursing into any nested slices of structs.
+func newSubTables(tableName naam.Name, typ reflect.Type) ([]Table, error) {
+	typ = derefType(typ)
+	if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = derefType(typ.Elem())
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sub-table type [%s] is not a struct or a slice of structs", typ)
+	}
+
+	table := Table{Name: tableName.FormatSQLTable()}
+	var tables []Table
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := naam.New(sf.Name)
+		if isSubTableType(sf.Type) {
+			subTables, err := newSubTables(tableName.AppendName(name), sf.Type)
+			if err != nil {
+				return nil, err
+			}
+			tables = append(tables, subTables...)
+			continue
+		}
+		col, err := newColumn(name.FormatSQLColumn(), sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column [%s] of table [%s]: %w", name.FormatSQLColumn(), table.Name, err)
+		}
+		table.Columns = append(table.Columns, col)
+	}
+	if _, ok := table.GetColumn(PARENT_ID_COLUMN); !ok {
+		table.Columns = append([]Column{{Name: PARENT_ID_COLUMN, Type: "uuid"}}, table.Columns...)
+	}
+
+	return append([]Table{table}, tables...), nil
+}
+
+func newColumn(name string, typ reflect.Type) (Column, error) {
+	col := Column{Name: name}
+	if typ.Kind() == reflect.Ptr {
+		col.Nullable = true
+	}
+	sqlType, err := GetSQLType(typ)
+	if err != nil {
+		return col, err
+	}
+	col.Type = sqlType
+	if name == naam.ID.FormatSQLColumn() {
+		col.PrimaryKey = true
+		col.Nullable = false
+	}
+	// These are nullable in the DB since the Go zero values are stored as NULL
+	if col.Type == "uuid" || col.Type == "jsonb" {
+		col.Nullable = col.Nullable || !col.PrimaryKey
+	}
+	return col, nil
+}
+
+var (
+	idType          = reflect.TypeOf(scalars.ID{})
+	timestampType   = reflect.TypeOf(scalars.Timestamp{})
+	dateType        = reflect.TypeOf(scalars.Date{})
+	emailType       = reflect.TypeOf(scalars.Email{})
+	linkType        = reflect.TypeOf(scalars.Link{})
+	secretType      = reflect.TypeOf(scalars.Secret{})
+	genericDataType = reflect.TypeOf(scalars.GenericData{})
+	moneyType       = reflect.TypeOf(scalars.Money{})
+	enumType        = reflect.TypeOf((*types.Enum)(nil)).Elem()
+	valuerType      = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// GetSQLType returns the Postgres type used to store a Go type, using the names reported by information_schema.
+func GetSQLType(typ reflect.Type) (string, error) {
+	typ = derefType(typ)
+
+	switch typ {
+	case idType:
+		return "uuid", nil
+	case timestampType:
+		return "timestamp with time zone", nil
+	case dateType:
+		return "date", nil
+	case emailType, linkType, secretType:
+		return "text", nil
+	case genericDataType:
+		return "jsonb", nil
+	case moneyType:
+		return "numeric", nil
+	}
+
+	if typ.Implements(enumType) || reflect.PointerTo(typ).Implements(enumType) {
+		return "text", nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return "text", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint", nil
+	case reflect.Int32, reflect.Uint16:
+		return "integer", nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "bigint", nil
+	case reflect.Float32:
+		return "real", nil
+	case reflect.Float64:
+		return "double precision", nil
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "bytea", nil
+		}
+		elemType, err := GetSQLType(typ.Elem())
+		if err != nil {
+			return "", err
+		}
+		return elemType + "[]", nil
+	}
+
+	if typ.Implements(valuerType) || reflect.PointerTo(typ).Implements(valuerType) {
+		return "", fmt.Errorf("type [%s] implements driver.Valuer but its SQL type is not known", typ)
+	}
+	return "", fmt.Errorf("type [%s] cannot be mapped to a SQL type", typ)
+}
+
+func isSubTableType(typ reflect.Type) bool {
+	typ = derefType(typ)
+	if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = derefType(typ.Elem())
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	_, err := GetSQLType(typ)
+	return err != nil
+}
+
+func getStructField(structType reflect.Type, name naam.Name) (reflect.StructField, bool) {
+	if sf, ok := structType.FieldByName(name.FormatGolangFieldName()); ok {
+		return sf, true
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		sf := structType.Field(i)
+		if naam.New(sf.Name).Equal(name) {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func derefType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+/* * * * * * *
+ * DDL
+ * * * * * * */
+
+// CreateTableSQL returns the CREATE TABLE statement for the table.
+func (t Table) CreateTableSQL() string {
+	var defs []string
+	var pks []string
+	for _, c := range t.Columns {
+		defs = append(defs, c.definitionSQL())
+		if c.PrimaryKey {
+			pks = append(pks, quoteIdent(c.Name))
+		}
+	}
+	if len(pks) > 0 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pks, ", ")))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n\t%s\n);", quoteIdent(t.Name), strings.Join(defs, ",\n\t"))
+}
+
+func (c Column) definitionSQL() string {
+	s := fmt.Sprintf("%s %s", quoteIdent(c.Name), c.Type)
+	if !c.Nullable {
+		s += " NOT NULL"
+	}
+	return s
+}
+
+// CreateSQL returns the DDL to create all the tables of the schema from scratch.
+func (s Schema) CreateSQL() string {
+	var stmts []string
+	for _, t := range s.sortedTables() {
+		stmts = append(stmts, t.CreateTableSQL())
+	}
+	return strings.Join(stmts, "\n\n")
+}
+
+func (s Schema) sortedTables() []Table {
+	tables := append([]Table{}, s.Tables...)
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	return tables
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
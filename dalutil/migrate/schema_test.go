@@ -0,0 +1,72 @@
+package migrate
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/scalars"
+)
+
+type testValuer struct{}
+
+func (testValuer) Value() (driver.Value, error) { return nil, nil }
+
+func TestGetSQLType(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{scalars.ID{}, "uuid", false},
+		{&scalars.ID{}, "uuid", false},
+		{scalars.Timestamp{}, "timestamp with time zone", false},
+		{scalars.Date{}, "date", false},
+		{scalars.Email{}, "text", false},
+		{scalars.Secret{}, "text", false},
+		{scalars.GenericData{}, "jsonb", false},
+		{scalars.Money{}, "numeric", false},
+		{"", "text", false},
+		{true, "boolean", false},
+		{int16(0), "smallint", false},
+		{int32(0), "integer", false},
+		{0, "bigint", false},
+		{float32(0), "real", false},
+		{float64(0), "double precision", false},
+		{[]byte{}, "bytea", false},
+		{[]string{}, "text[]", false},
+		{[]scalars.ID{}, "uuid[]", false},
+		{testValuer{}, "", true},
+		{struct{}{}, "", true},
+		{map[string]string{}, "", true},
+	}
+	for _, tt := range tests {
+		typ := reflect.TypeOf(tt.value)
+		t.Run(typ.String(), func(t *testing.T) {
+			got, err := GetSQLType(typ)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTable_CreateTableSQL(t *testing.T) {
+	table := Table{Name: "order_items", Columns: []Column{
+		{Name: "order_id", Type: "uuid", PrimaryKey: true},
+		{Name: "line", Type: "integer", PrimaryKey: true},
+		{Name: "note", Type: "text", Nullable: true},
+		{Name: `we"ird`, Type: "text"},
+	}}
+	assert.Equal(t, `CREATE TABLE "order_items" (
+	"order_id" uuid NOT NULL,
+	"line" integer NOT NULL,
+	"note" text,
+	"we""ird" text NOT NULL,
+	PRIMARY KEY ("order_id", "line")
+);`, table.CreateTableSQL())
+}
@@ -0,0 +1,160 @@
+package dalutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/teejays/gokutil/client/db"
+	"github.com/teejays/gokutil/errutil"
+	"github.com/teejays/gokutil/scalars"
+	"github.com/teejays/gokutil/types"
+)
+
+// PatchTypeRequest updates an existing object using a patch document (see types.PatchType), rather than a full object
+// and a field mask.
+type PatchTypeRequest[T types.BasicType, F types.Field] struct {
+	Connection *db.Connection
+	TableName  string
+	ID         scalars.ID
+	PatchType  types.PatchType
+	Patch      json.RawMessage
+	Meta       ITypeDALMeta[T, F]
+	AdminMode  bool
+}
+
+// PatchType applies the patch to the object with the ID and saves the fields that the patch modifies. The object is
+// read, patched and saved in one transaction, with its row locked, so that concurrent patches do not overwrite each
+// other. If the connection is not already in a transaction, a new one is run with RunInTxWithRetry.
+func PatchType[T types.BasicType, F types.Field](ctx context.Context, req PatchTypeRequest[T, F]) (UpdateTypeResponse[T], error) {
+	if req.Connection.IsInTransaction(ctx) {
+		return patchType(ctx, req)
+	}
+
+	var resp UpdateTypeResponse[T]
+	err := req.Connection.RunInTxWithRetry(ctx, db.RetryOptions{}, func(ctx context.Context, conn *db.Connection) error {
+		req.Connection = conn
+		var err error
+		resp, err = patchType(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func patchType[T types.BasicType, F types.Field](ctx context.Context, req PatchTypeRequest[T, F]) (UpdateTypeResponse[T], error) {
+	updateReq, err := GetUpdateTypeRequestFromPatch(ctx, req)
+	if err != nil {
+		return UpdateTypeResponse[T]{}, err
+	}
+	return UpdateType(ctx, updateReq)
+}
+
+// GetUpdateTypeRequestFromPatch fetches the object with the ID and applies the patch to it. The returned request has
+// the patched object, and the modified fields as its Fields mask.
+//
+// If the connection is in a transaction, the row of the object is locked until the transaction ends. The request should
+// be run in the same transaction, otherwise a concurrent update between the read and the update is lost.
+func GetUpdateTypeRequestFromPatch[T types.BasicType, F types.Field](ctx context.Context, req PatchTypeRequest[T, F]) (UpdateTypeRequest[T, F], error) {
+	meta := req.Meta
+	var updateReq UpdateTypeRequest[T, F]
+	if req.ID.IsEmpty() {
+		return updateReq, errutil.NewGerror("ID is required to patch type [%s]", meta.GetTypeCommonMeta().Name).SetHTTPStatus(http.StatusBadRequest)
+	}
+
+	if req.Connection.IsInTransaction(ctx) {
+		err := lockTypeByID(ctx, req.Connection, req.TableName, req.ID)
+		if err != nil {
+			return updateReq, fmt.Errorf("Type [%s] with ID [%s]: %w", meta.GetTypeCommonMeta().Name, req.ID, err)
+		}
+	}
+
+	params := db.ListTypeByIDsParams{
+		TableName: req.TableName,
+		IDColumn:  "id",
+		IDs:       []scalars.ID{req.ID},
+	}
+	existing, err := ListTypeByIDs[T, F](ctx, req.Connection, params, meta)
+	if err != nil {
+		return updateReq, fmt.Errorf("could not list by ID %s: %w", req.ID, err)
+	}
+	if len(existing.Items) < 1 {
+		return updateReq, fmt.Errorf("Type [%s] with ID [%s] not found: %w", meta.GetTypeCommonMeta().Name, req.ID, errutil.ErrNotFound)
+	}
+
+	elem, fields, err := ApplyPatchToType(meta, existing.Items[0], req.PatchType, req.Patch, req.AdminMode)
+	if err != nil {
+		return updateReq, err
+	}
+
+	return UpdateTypeRequest[T, F]{
+		Connection: req.Connection,
+		TableName:  req.TableName,
+		Object:     elem,
+		Fields:     fields,
+		Meta:       meta,
+		AdminMode:  req.AdminMode,
+	}, nil
+}
+
+// ApplyPatchToType applies the patch to the object, and returns the patched object and the fields that the patch
+// modifies. Unless in admin mode, patches that modify the ImmutableFields are rejected.
+func ApplyPatchToType[T types.BasicType, F types.Field](meta ITypeDALMeta[T, F], elem T, patchType types.PatchType, patch []byte, adminMode bool) (T, []F, error) {
+	typeName := meta.GetTypeCommonMeta().Name
+	patched, keys, err := types.ApplyPatch(elem, patchType, patch)
+	if err != nil {
+		return elem, nil, err
+	}
+
+	var fields []F
+	var errs = errutil.NewMultiErr()
+	for _, key := range keys {
+		f, ok := types.GetFieldByJSONName(meta.GetTypeCommonMeta().Fields, key)
+		if !ok {
+			errs.AddNew("Type [%s] has no field '%s'", typeName, key)
+			continue
+		}
+		if !adminMode && types.IsFieldInFields(f, meta.GetCommonDALMeta().ImmutableFields) {
+			errs.AddNew("Mutations on field '%s' are not allowed", f)
+			continue
+		}
+		fields = append(fields, f)
+	}
+	if !errs.IsNil() {
+		return elem, nil, errutil.WrapGerror(errs).SetHTTPStatus(http.StatusBadRequest)
+	}
+	if patched.GetID() != elem.GetID() {
+		return elem, nil, errutil.NewGerror("Patch cannot change the ID of type [%s]", typeName).SetHTTPStatus(http.StatusBadRequest)
+	}
+	if len(fields) < 1 {
+		return elem, nil, errutil.NewGerror("Patch for type [%s] does not modify any fields", typeName).SetHTTPStatus(http.StatusBadRequest)
+	}
+	return patched, fields, nil
+}
+
+// lockTypeByID locks the row with the ID until the end of the current transaction.
+func lockTypeByID(ctx context.Context, conn *db.Connection, tableName string, id scalars.ID) error {
+	query, args, err := db.ConstructSelectByIDQuery(ctx, conn.Dialect, db.SelectByIDBuilderRequest{
+		TableName: tableName,
+		Columns:   []string{"id"},
+		IDColumn:  "id",
+		IDs:       []scalars.ID{id},
+		ForUpdate: true,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing lock query: %w", err)
+	}
+	rows, err := conn.QueryRows(ctx, query, args...)
+	if err != nil {
+		return errutil.Wrap(err, "locking row")
+	}
+	defer rows.Close()
+	ids, err := db.SqlRowsToUUIDs(ctx, rows)
+	if err != nil {
+		return err
+	}
+	if len(ids) < 1 {
+		return errutil.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package dalutil
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/errutil"
+	"github.com/teejays/gokutil/naam"
+	"github.com/teejays/gokutil/scalars"
+	"github.com/teejays/gokutil/types"
+)
+
+type testObject struct {
+	ID   scalars.ID `json:"id"`
+	Name string     `json:"name"`
+	Code string     `json:"code"`
+}
+
+func (o testObject) GetID() scalars.ID                { return o.ID }
+func (o testObject) GetUpdatedAt() scalars.Timestamp  { return scalars.Timestamp{} }
+func (o testObject) GetDeletedAt() *scalars.Timestamp { return nil }
+
+type testField string
+
+func (f testField) String() string  { return string(f) }
+func (f testField) Name() naam.Name { return naam.New(string(f)) }
+
+// testMeta only implements the methods that ApplyPatchToType uses.
+type testMeta struct {
+	ITypeDALMeta[testObject, testField]
+	common    *types.TypeCommonMeta[testObject, testField]
+	commonDAL *TypeCommonDALMeta[testObject, testField]
+}
+
+func (m testMeta) GetTypeCommonMeta() *types.TypeCommonMeta[testObject, testField] { return m.common }
+func (m testMeta) GetCommonDALMeta() *TypeCommonDALMeta[testObject, testField]     { return m.commonDAL }
+
+func TestApplyPatchToType(t *testing.T) {
+	meta := testMeta{
+		common:    &types.TypeCommonMeta[testObject, testField]{Name: naam.New("test_object"), Fields: []testField{"id", "name", "code"}},
+		commonDAL: &TypeCommonDALMeta[testObject, testField]{ImmutableFields: []testField{"id", "code"}},
+	}
+	current := testObject{ID: scalars.NewID(), Name: "a", Code: "x"}
+
+	tests := []struct {
+		name       string
+		patchType  types.PatchType
+		patch      string
+		adminMode  bool
+		want       testObject
+		wantFields []testField
+		wantErr    bool
+	}{
+		{
+			name:       "merge patch",
+			patchType:  types.PatchType_MergePatch,
+			patch:      `{"name": "b"}`,
+			want:       testObject{ID: current.ID, Name: "b", Code: "x"},
+			wantFields: []testField{"name"},
+		},
+		{
+			name:       "JSON patch",
+			patchType:  types.PatchType_JSONPatch,
+			patch:      `[{"op": "replace", "path": "/name", "value": "c"}]`,
+			want:       testObject{ID: current.ID, Name: "c", Code: "x"},
+			wantFields: []testField{"name"},
+		},
+		{name: "immutable field", patchType: types.PatchType_MergePatch, patch: `{"name": "b", "code": "y"}`, wantErr: true},
+		{
+			name:       "immutable field in admin mode",
+			patchType:  types.PatchType_MergePatch,
+			patch:      `{"code": "y"}`,
+			adminMode:  true,
+			want:       testObject{ID: current.ID, Name: "a", Code: "y"},
+			wantFields: []testField{"code"},
+		},
+		{name: "ID change", patchType: types.PatchType_JSONPatch, patch: `[{"op": "replace", "path": "/id", "value": "` + scalars.NewID().String() + `"}]`, wantErr: true},
+		{name: "ID change in admin mode", patchType: types.PatchType_MergePatch, patch: `{"id": "` + scalars.NewID().String() + `"}`, adminMode: true, wantErr: true},
+		{name: "unknown field", patchType: types.PatchType_MergePatch, patch: `{"foo": 1}`, wantErr: true},
+		{name: "no fields", patchType: types.PatchType_MergePatch, patch: `{}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fields, err := ApplyPatchToType[testObject, testField](meta, current, tt.patchType, []byte(tt.patch), tt.adminMode)
+			if tt.wantErr {
+				gerr, ok := errutil.AsGokuError(err)
+				if assert.True(t, ok, "error: %v", err) {
+					assert.Equal(t, http.StatusBadRequest, gerr.GetHTTPStatus())
+				}
+				assert.Equal(t, current, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
@@ -87,6 +87,11 @@ func (err GokuError) Error() string {
 	return err.externalMsg
 }
 
+// Unwrap returns the internal error, so errors.Is and errors.As can look through a GokuError.
+func (err GokuError) Unwrap() error {
+	return err.internalError
+}
+
 // GetHTTPStatus returns the status, if set, and defaults to InternalServerError
 func (err GokuError) GetExternalMsg() string {
 	if err.externalMsg != "" {
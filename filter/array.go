@@ -0,0 +1,220 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+/* * * * * * *
+ * Array Columns
+ * * * * * * */
+
+// ArrayMatch is how a condition on an array column is applied to the array.
+type ArrayMatch uint8
+
+const (
+	// ArrayMatch_Any matches if any element of the array satisfies the condition. This is the default.
+	ArrayMatch_Any ArrayMatch = iota
+	// ArrayMatch_All matches if every element of the array satisfies the condition. An empty array matches.
+	ArrayMatch_All
+	// ArrayMatch_Length applies the condition to the number of elements in the array.
+	ArrayMatch_Length
+)
+
+// ARRAY_ELEMENT_ALIAS is the name given to the elements of an array column (using unnest) in the generated SQL.
+const ARRAY_ELEMENT_ALIAS = "elem"
+
+func (m ArrayMatch) String() string {
+	switch m {
+	case ArrayMatch_Any:
+		return "ANY"
+	case ArrayMatch_All:
+		return "ALL"
+	case ArrayMatch_Length:
+		return "LENGTH"
+	}
+	return fmt.Sprintf("ArrayMatch(%d)", m)
+}
+
+func (m *ArrayMatch) FromString(str string) error {
+	switch str {
+	case "ANY", "":
+		*m = ArrayMatch_Any
+	case "ALL":
+		*m = ArrayMatch_All
+	case "LENGTH":
+		*m = ArrayMatch_Length
+	default:
+		return fmt.Errorf("unrecognized array match [%s]", str)
+	}
+	return nil
+}
+
+func (m *ArrayMatch) UnmarshalJSON(data []byte) error {
+	str, err := strconv.Unquote(string(data))
+	if err != nil {
+		str = string(data)
+	}
+	return m.FromString(str)
+}
+
+func (m ArrayMatch) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, m.String()), nil
+}
+
+func (m *ArrayMatch) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var str string
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	return m.FromString(str)
+}
+
+func (m ArrayMatch) MarshalYAML() (interface{}, error) {
+	return m.String(), nil
+}
+
+// ArrayCondition is a Condition that says how it should be applied to an array column. Plain conditions are applied
+// with ArrayMatch_Any.
+type ArrayCondition interface {
+	Condition
+	GetArrayMatch() ArrayMatch
+}
+
+// GenericArrayCondition is a condition for an array column, with elements of type T.
+type GenericArrayCondition[T comparable] struct {
+	Match  ArrayMatch `json:"match,omitempty" yaml:"match,omitempty"`
+	Op     Operator   `json:"op" yaml:"op"`
+	Values []T        `json:"values" yaml:"values"`
+}
+
+func NewAnyElementCondition[T comparable](op Operator, values ...T) *GenericArrayCondition[T] {
+	return &GenericArrayCondition[T]{Match: ArrayMatch_Any, Op: op, Values: values}
+}
+
+func NewAllElementsCondition[T comparable](op Operator, values ...T) *GenericArrayCondition[T] {
+	return &GenericArrayCondition[T]{Match: ArrayMatch_All, Op: op, Values: values}
+}
+
+func NewArrayLengthCondition(op Operator, values ...int) *GenericArrayCondition[int] {
+	return &GenericArrayCondition[int]{Match: ArrayMatch_Length, Op: op, Values: values}
+}
+
+func (f GenericArrayCondition[T]) GetOperator() Operator      { return f.Op }
+func (f GenericArrayCondition[T]) Len() int                   { return len(f.Values) }
+func (f GenericArrayCondition[T]) GetValue(i int) interface{} { return f.Values[i] }
+func (f GenericArrayCondition[T]) GetArrayMatch() ArrayMatch  { return f.Match }
+
+// GetArrayMatch returns how the condition should be applied to an array column.
+func GetArrayMatch(f Condition) ArrayMatch {
+	if ac, ok := f.(ArrayCondition); ok {
+		return ac.GetArrayMatch()
+	}
+	return ArrayMatch_Any
+}
+
+// arrayLengthOperators are the operators that can be used with ArrayMatch_Length.
+var arrayLengthOperators = map[Operator]bool{
+	EQUAL:              true,
+	NOT_EQUAL:          true,
+	IN:                 true,
+	NOT_IN:             true,
+	GREATER_THAN:       true,
+	GREATER_THAN_EQUAL: true,
+	LESS_THAN:          true,
+	LESS_THAN_EQUAL:    true,
+	BETWEEN:            true,
+	NOT_BETWEEN:        true,
+}
+
+// validateArrayMatch checks that the operator can be used with the array match.
+//   - IS_NULL and IS_NOT_NULL check the column itself, so they can only be used with ArrayMatch_Any
+//   - array operators (e.g. CONTAINS_ALL) compare the whole array, so they can only be used with ArrayMatch_Any
+//   - ArrayMatch_Length only allows comparison operators
+func validateArrayMatch(info OperatorInfo, op Operator, match ArrayMatch) error {
+	switch match {
+	case ArrayMatch_Any:
+		return nil
+	case ArrayMatch_All:
+		if op == IS_NULL || op == IS_NOT_NULL || info.IsArrayOperator {
+			return fmt.Errorf("operator [%s] cannot be used with array match [%s]", info, match)
+		}
+		return nil
+	case ArrayMatch_Length:
+		if !arrayLengthOperators[op] {
+			return fmt.Errorf("operator [%s] cannot be used with array match [%s]", info, match)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid array match [%d]", match)
+}
+
+// getArrayConditionExpression returns the where condition for an array column. Element conditions are applied to
+// the unnested elements of the column, e.g. for GREATER_THAN:
+//
+//	ArrayMatch_Any: EXISTS (SELECT 1 FROM unnest("col") AS "elem" WHERE ("elem" > $1))
+//	ArrayMatch_All: ("col" IS NOT NULL AND NOT EXISTS (SELECT 1 FROM unnest("col") AS "elem" WHERE ("elem" > $1) IS NOT TRUE))
+//	ArrayMatch_Length: (cardinality("col") > $1)
+func getArrayConditionExpression(info OperatorInfo, op Operator, match ArrayMatch, col string, values []interface{}) (exp.Expression, error) {
+	if err := validateArrayMatch(info, op, match); err != nil {
+		return nil, err
+	}
+
+	// Operators that apply to the column as a whole
+	if op == IS_NULL || op == IS_NOT_NULL || info.IsArrayOperator {
+		return info.GoquExpression(col, values...), nil
+	}
+
+	if match == ArrayMatch_Length {
+		lengths := make([]interface{}, len(values))
+		for i, v := range values {
+			n, err := toArrayLength(v)
+			if err != nil {
+				return nil, err
+			}
+			lengths[i] = n
+		}
+		return getLiteralConditionExpression(goqu.L("cardinality(?)", goqu.C(col)), info, op, lengths)
+	}
+
+	e := info.GoquExpression(ARRAY_ELEMENT_ALIAS, values...)
+	if e == nil {
+		return nil, nil
+	}
+	if match == ArrayMatch_All {
+		return goqu.And(
+			goqu.C(col).IsNotNull(),
+			goqu.L("NOT EXISTS (SELECT 1 FROM unnest(?) AS ? WHERE ? IS NOT TRUE)", goqu.C(col), goqu.I(ARRAY_ELEMENT_ALIAS), e),
+		), nil
+	}
+	return goqu.L("EXISTS (SELECT 1 FROM unnest(?) AS ? WHERE ?)", goqu.C(col), goqu.I(ARRAY_ELEMENT_ALIAS), e), nil
+}
+
+// toArrayLength converts a condition value to an array length. It accepts integers, and floats and strings that hold
+// a whole number (e.g. from an AnyCondition unmarshaled from JSON).
+func toArrayLength(v interface{}) (int64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("array length [%d] is too large", rv.Uint())
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+			return int64(f), nil
+		}
+	case reflect.String:
+		if n, err := strconv.ParseInt(rv.String(), 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid array length [%v]: expected a whole number", v)
+}
@@ -1,9 +1,12 @@
 package filter
 
 import (
+	"database/sql/driver"
 	"fmt"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/huandu/go-sqlbuilder"
 	"github.com/teejays/gokutil/log"
 	"github.com/teejays/gokutil/scalars"
 )
@@ -30,6 +33,10 @@ func ValidateCondition(f Condition) error {
 	if err != nil {
 		return err
 	}
+	// Some conditions (e.g. EnumCondition) only allow some operators
+	if f, ok := f.(interface{ GetAllowedOperators() []Operator }); ok && !containsOperator(f.GetAllowedOperators(), op) {
+		return fmt.Errorf("operator [%s] is not allowed for condition of type [%T]", info, f)
+	}
 	// Min values
 	switch info.ValuesType {
 	case ValuesType_Zero:
@@ -40,6 +47,10 @@ func ValidateCondition(f Condition) error {
 		if n != 1 {
 			return fmt.Errorf("operator [%s] expects one value but [%d] provided", info, n)
 		}
+	case ValuesType_Two:
+		if n != 2 {
+			return fmt.Errorf("operator [%s] expects two values but [%d] provided", info, n)
+		}
 	case ValuesType_Multiple:
 		if info.MultipleValuesMin > 0 && n < info.MultipleValuesMin {
 			return fmt.Errorf("operator [%s] expects a min of [%d] values but [%d] provided", info, info.MultipleValuesMin, n)
@@ -52,7 +63,7 @@ func ValidateCondition(f Condition) error {
 	default:
 		// Do nothing
 	}
-	return nil
+	return validateRelativeTimeCondition(f, info)
 }
 
 func ListConditionValues(f Condition) []interface{} {
@@ -70,7 +81,63 @@ func ListConditionValues(f Condition) []interface{} {
 // 	IsColumnArray              bool
 // }
 
+// InjectConditionIntoSqlBuilder adds the condition on the column as a where condition to the select query. The condition
+// is checked against the policy set using SetPolicy.
 func InjectConditionIntoSqlBuilder(f Condition, sb *goqu.SelectDataset, col string, isColArray bool) (*goqu.SelectDataset, error) {
+	return GetPolicy().InjectConditionIntoSqlBuilder(f, sb, col, isColArray)
+}
+
+func injectConditionIntoSqlBuilder(f Condition, sb *goqu.SelectDataset, col string, isColArray bool) (*goqu.SelectDataset, error) {
+	e, err := GetConditionExpression(f, col, isColArray)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return sb, nil
+	}
+	return sb.Where(e), nil
+}
+
+// InjectConditionIntoHuanduSqlBuilder is like InjectConditionIntoSqlBuilder, but for a huandu/go-sqlbuilder select
+// query. Relative time operators are resolved into absolute times before the InjectSqlBuilderWhereCond_Huandu of the
+// operator is called. JSON path conditions and array columns are not supported.
+func InjectConditionIntoHuanduSqlBuilder(f Condition, sb *sqlbuilder.SelectBuilder, col string) error {
+	if err := GetPolicy().CheckCondition(col, f); err != nil {
+		return err
+	}
+	op := f.GetOperator()
+	info, err := getOperatorInfo(op)
+	if err != nil {
+		return err
+	}
+	values := ListConditionValues(f)
+	log.DebugWithoutCtx("Injecting condition into huandu SQL Builder", "Operator", info, "Column", col, "Values", values)
+
+	err = ValidateCondition(f)
+	if err != nil {
+		return err
+	}
+	if _, ok := f.(JSONCondition); ok {
+		return fmt.Errorf("JSON path condition cannot be used with a huandu SQL builder")
+	}
+	if match := GetArrayMatch(f); match != ArrayMatch_Any {
+		return fmt.Errorf("array match [%s] cannot be used with a huandu SQL builder", match)
+	}
+
+	// Resolve relative time operators into absolute times
+	if IsRelativeTimeOperator(op) {
+		values, err = resolveRelativeTimeValues(f, info)
+		if err != nil {
+			return err
+		}
+	}
+
+	return info.InjectSqlBuilderWhereCond_Huandu(sb, col, values...)
+}
+
+// GetConditionExpression returns the goqu where condition for the column. It returns nil if the condition adds no constraint.
+// For array columns, the condition is applied based on its ArrayMatch (see getArrayConditionExpression).
+func GetConditionExpression(f Condition, col string, isColArray bool) (exp.Expression, error) {
 	// Get operator
 	op := f.GetOperator()
 	info, err := getOperatorInfo(op)
@@ -87,17 +154,31 @@ func InjectConditionIntoSqlBuilder(f Condition, sb *goqu.SelectDataset, col stri
 		return nil, err
 	}
 
+	// Resolve relative time operators into absolute times
+	if IsRelativeTimeOperator(op) {
+		values, err = resolveRelativeTimeValues(f, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Handle conditions on a value inside a JSON column
+	if jc, ok := f.(JSONCondition); ok {
+		if isColArray {
+			return nil, fmt.Errorf("JSON path condition cannot be used with an array column")
+		}
+		return getJSONConditionExpression(info, op, jc.GetJSONPath(), col, values)
+	}
+
 	// Handle when column is a SQL array
+	match := GetArrayMatch(f)
 	if isColArray {
-		sb := sb.Where(
-			goqu.L(
-				GetRawSQLConditionForArrayColumn(info, col), values[0],
-			),
-		)
-		return sb, nil
+		return getArrayConditionExpression(info, op, match, col, values)
 	}
-	sb = info.InjectSqlBuilderWhereCond_Goqu(sb, col, values...)
-	return sb, nil
+	if match != ArrayMatch_Any {
+		return nil, fmt.Errorf("array match [%s] can only be used with an array column", match)
+	}
+	return info.GoquExpression(col, values...), nil
 }
 
 // func GetRawSQLConditionForArrayColumn(f Condition, col string) (string, error) {
@@ -118,6 +199,8 @@ func InjectConditionIntoSqlBuilder(f Condition, sb *goqu.SelectDataset, col stri
 type GenericCondition[T comparable] struct {
 	Op     Operator `json:"op" yaml:"op"`
 	Values []T      `json:"values" yaml:"values"`
+	// Duration is used by the WITHIN_LAST and OLDER_THAN operators e.g. `7d` (see ParseRelativeDuration)
+	Duration string `json:"duration,omitempty" yaml:"duration,omitempty"`
 }
 
 func NewGenericCondition[T comparable](op Operator, values ...T) *GenericCondition[T] {
@@ -127,6 +210,7 @@ func NewGenericCondition[T comparable](op Operator, values ...T) *GenericConditi
 func (f GenericCondition[T]) GetOperator() Operator      { return f.Op }
 func (f GenericCondition[T]) Len() int                   { return len(f.Values) }
 func (f GenericCondition[T]) GetValue(i int) interface{} { return f.Values[i] }
+func (f GenericCondition[T]) GetDuration() string        { return f.Duration }
 
 type StringCondition = GenericCondition[string]
 
@@ -187,6 +271,14 @@ func NewLinkCondition(op Operator, values ...scalars.Link) *LinkCondition {
 	return NewGenericCondition(op, values...)
 }
 
+// MoneyCondition compares the amounts of money. The currency is not stored in the database, so it is not compared.
+type MoneyCondition = GenericCondition[scalars.Money]
+
+func NewMoneyCondition(op Operator, values ...scalars.Money) *MoneyCondition {
+	return NewGenericCondition(op, values...)
+}
+
+// GenericDataCondition compares the whole JSON value. Use JSONPathCondition for a value inside it.
 type GenericDataCondition = GenericCondition[scalars.GenericData]
 
 func NewGenericDataCondition(op Operator, values ...scalars.GenericData) *GenericDataCondition {
@@ -196,3 +288,26 @@ func NewGenericDataCondition(op Operator, values ...scalars.GenericData) *Generi
 func SecretCondition(op Operator, values ...string) *StringCondition {
 	return NewStringCondition(op, values...)
 }
+
+// EnumValue is implemented by enum types e.g. types.EnumOf. Enums are stored by their names, so the values are passed to
+// the database (and compared by Evaluate) as their driver values.
+type EnumValue interface {
+	comparable
+	driver.Valuer
+}
+
+// EnumOperators are the operators that can be used with an EnumCondition.
+var EnumOperators = joinOperators(equalityOperators, nullOperators)
+
+// EnumCondition is the condition for enum columns. Unlike the other conditions, it only allows the EnumOperators.
+type EnumCondition[E EnumValue] GenericCondition[E]
+
+func NewEnumCondition[E EnumValue](op Operator, values ...E) *EnumCondition[E] {
+	return &EnumCondition[E]{Op: op, Values: values}
+}
+
+func (f EnumCondition[E]) GetOperator() Operator           { return f.Op }
+func (f EnumCondition[E]) Len() int                        { return len(f.Values) }
+func (f EnumCondition[E]) GetValue(i int) interface{}      { return f.Values[i] }
+func (f EnumCondition[E]) GetDuration() string             { return f.Duration }
+func (f EnumCondition[E]) GetAllowedOperators() []Operator { return EnumOperators }
@@ -0,0 +1,226 @@
+package filter
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInjectConditionIntoSqlBuilder(t *testing.T) {
+	tests := []struct {
+		name       string
+		cond       Condition
+		isColArray bool
+		wantSQL    string
+		wantArgs   []interface{}
+		wantErr    bool
+	}{
+		{
+			name:     "BETWEEN",
+			cond:     NewNumberCondition(BETWEEN, 1, 10),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" BETWEEN $1 AND $2)`,
+			wantArgs: []interface{}{int64(1), int64(10)},
+		},
+		{
+			name:    "BETWEEN with one value",
+			cond:    NewNumberCondition(BETWEEN, 1),
+			wantErr: true,
+		},
+		{
+			name:     "NOT_BETWEEN",
+			cond:     NewNumberCondition(NOT_BETWEEN, 1, 10),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" NOT BETWEEN $1 AND $2)`,
+			wantArgs: []interface{}{int64(1), int64(10)},
+		},
+		{
+			name:     "NOT_IN",
+			cond:     NewStringCondition(NOT_IN, "a", "b"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" NOT IN ($1, $2))`,
+			wantArgs: []interface{}{"a", "b"},
+		},
+		{
+			name:     "STARTS_WITH escapes wildcards",
+			cond:     NewStringCondition(STARTS_WITH, `50%_off\`),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" LIKE $1)`,
+			wantArgs: []interface{}{`50\%\_off\\%`},
+		},
+		{
+			name:     "ENDS_WITH",
+			cond:     NewStringCondition(ENDS_WITH, "@example.com"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" LIKE $1)`,
+			wantArgs: []interface{}{"%@example.com"},
+		},
+		{
+			name:     "CONTAINS",
+			cond:     NewStringCondition(CONTAINS, "a_b"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" LIKE $1)`,
+			wantArgs: []interface{}{`%a\_b%`},
+		},
+		{
+			name:     "REGEX",
+			cond:     NewStringCondition(REGEX, "^a.*z$"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" ~ $1)`,
+			wantArgs: []interface{}{"^a.*z$"},
+		},
+		{
+			name:     "IREGEX",
+			cond:     NewStringCondition(IREGEX, "^a"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" ~* $1)`,
+			wantArgs: []interface{}{"^a"},
+		},
+		{
+			name:       "CONTAINS_ALL",
+			cond:       NewStringCondition(CONTAINS_ALL, "a", "b"),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE "col" @> $1`,
+			wantArgs:   []interface{}{`{"a","b"}`},
+		},
+		{
+			name:       "CONTAINS_ANY",
+			cond:       NewStringCondition(CONTAINS_ANY, "a"),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE "col" && $1`,
+			wantArgs:   []interface{}{`{"a"}`},
+		},
+		{
+			name:       "OVERLAPS",
+			cond:       NewStringCondition(OVERLAPS, "a"),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE "col" && $1`,
+			wantArgs:   []interface{}{`{"a"}`},
+		},
+		{
+			name:       "CONTAINED_BY",
+			cond:       NewStringCondition(CONTAINED_BY, "a", "b"),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE "col" <@ $1`,
+			wantArgs:   []interface{}{`{"a","b"}`},
+		},
+		{
+			name:       "array EQUAL any element",
+			cond:       NewStringCondition(EQUAL, "a"),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE EXISTS (SELECT 1 FROM unnest("col") AS "elem" WHERE ("elem" = $1))`,
+			wantArgs:   []interface{}{"a"},
+		},
+		{
+			name:       "array IN any element",
+			cond:       NewStringCondition(IN, "a", "b"),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE EXISTS (SELECT 1 FROM unnest("col") AS "elem" WHERE ("elem" IN ($1, $2)))`,
+			wantArgs:   []interface{}{"a", "b"},
+		},
+		{
+			name:       "array GREATER_THAN any element",
+			cond:       NewAnyElementCondition(GREATER_THAN, 10),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE EXISTS (SELECT 1 FROM unnest("col") AS "elem" WHERE ("elem" > $1))`,
+			wantArgs:   []interface{}{int64(10)},
+		},
+		{
+			name:       "array BETWEEN all elements",
+			cond:       NewAllElementsCondition(BETWEEN, 1, 10),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE (("col" IS NOT NULL) AND NOT EXISTS (SELECT 1 FROM unnest("col") AS "elem" WHERE ("elem" BETWEEN $1 AND $2) IS NOT TRUE))`,
+			wantArgs:   []interface{}{int64(1), int64(10)},
+		},
+		{
+			name:       "array length GREATER_THAN_EQUAL",
+			cond:       NewArrayLengthCondition(GREATER_THAN_EQUAL, 2),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE (cardinality("col") >= $1)`,
+			wantArgs:   []interface{}{int64(2)},
+		},
+		{
+			name:       "array length IN",
+			cond:       NewArrayLengthCondition(IN, 0, 1),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE (cardinality("col") IN ($1, $2))`,
+			wantArgs:   []interface{}{int64(0), int64(1)},
+		},
+		{
+			name:       "array IS_NULL applies to the column",
+			cond:       NewStringCondition(IS_NULL),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE ("col" IS NULL)`,
+			wantArgs:   []interface{}{},
+		},
+		{
+			name:       "array IS_NOT_NULL applies to the column",
+			cond:       NewStringCondition(IS_NOT_NULL),
+			isColArray: true,
+			wantSQL:    `SELECT * FROM "t" WHERE ("col" IS NOT NULL)`,
+			wantArgs:   []interface{}{},
+		},
+		{
+			name:       "array IS_NULL with all elements",
+			cond:       NewAllElementsCondition[string](IS_NULL),
+			isColArray: true,
+			wantErr:    true,
+		},
+		{
+			name:       "array length with LIKE",
+			cond:       NewArrayLengthCondition(LIKE, 1),
+			isColArray: true,
+			wantErr:    true,
+		},
+		{
+			name:       "array CONTAINS_ALL with all elements",
+			cond:       NewAllElementsCondition(CONTAINS_ALL, "a"),
+			isColArray: true,
+			wantErr:    true,
+		},
+		{
+			name:    "array match on a non-array column",
+			cond:    NewAllElementsCondition(EQUAL, "a"),
+			wantErr: true,
+		},
+		{
+			name:     "enum IN uses the names",
+			cond:     NewEnumCondition(IN, testEnum(1), testEnum(2)),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col" IN ($1, $2))`,
+			wantArgs: []interface{}{"ONE", "TWO"},
+		},
+		{
+			name:    "enum with an operator that is not allowed",
+			cond:    NewEnumCondition(GREATER_THAN, testEnum(1)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sb, err := InjectConditionIntoSqlBuilder(tt.cond, goqu.Dialect("postgres").From("t").Prepared(true), "col", tt.isColArray)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			sql, args, err := sb.ToSQL()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestOperator_StringRoundTrip(t *testing.T) {
+	for _, op := range OperatorsList {
+		var got Operator
+		assert.NoError(t, got.FromString(op.String()))
+		assert.Equal(t, op, got)
+
+		info, err := GetOperatorInfo(op)
+		assert.NoError(t, err)
+		assert.Equal(t, op.String(), info.Name)
+		assert.NotNil(t, info.GoquExpression, op.String())
+		assert.NotNil(t, info.InjectSqlBuilderWhereCond_Huandu, op.String())
+	}
+}
+
+type testEnum int
+
+func (e testEnum) Value() (driver.Value, error) {
+	return []string{"ZERO", "ONE", "TWO"}[e], nil
+}
@@ -0,0 +1,519 @@
+package filter
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/teejays/gokutil/scalars"
+)
+
+/* * * * * * *
+ * In-memory Evaluation
+ * * * * * * */
+
+// Evaluate reports whether the value (of a column) satisfies the condition, following the same semantics as the SQL
+// generated by InjectConditionIntoSqlBuilder:
+//   - nil values (and nil pointers, empty IDs etc.) are NULL: they only match IS_NULL, like SQL's three-valued logic
+//   - LIKE and ILIKE look for the value anywhere in the string, with Postgres' `%`, `_` and `\` pattern semantics
+//   - REGEX and IREGEX use Go's regexp syntax, which agrees with Postgres for the common subset
+//   - strings are compared byte-wise, like the "C" collation
+//   - for a slice value (an array column), IS_NULL, IS_NOT_NULL and the array operators (e.g. CONTAINS_ALL) apply to
+//     the whole slice, and other operators are applied based on the ArrayMatch of the condition: to any element, to
+//     all the elements, or to the length of the slice
+//   - a JSONCondition is applied to the value at its path in the JSON value (see JSONPathCondition)
+//   - relative time operators (e.g. WITHIN_LAST) are resolved using the TimeConfig, like when the SQL is generated
+//
+// Condition values are converted to the type of the column value when needed, so that e.g. an AnyCondition
+// unmarshaled from JSON (strings and float64s) can be evaluated against timestamps or ints.
+func Evaluate(cond Condition, value interface{}) (bool, error) {
+	if err := ValidateCondition(cond); err != nil {
+		return false, err
+	}
+	info, err := getOperatorInfo(cond.GetOperator())
+	if err != nil {
+		return false, err
+	}
+	if jc, ok := cond.(JSONCondition); ok {
+		return evaluateJSONCondition(info, cond.GetOperator(), jc.GetJSONPath(), ListConditionValues(cond), value)
+	}
+	match := GetArrayMatch(cond)
+	if err := validateArrayMatch(info, cond.GetOperator(), match); err != nil {
+		return false, err
+	}
+	values := ListConditionValues(cond)
+	if IsRelativeTimeOperator(cond.GetOperator()) {
+		values, err = resolveRelativeTimeValues(cond, info)
+		if err != nil {
+			return false, err
+		}
+	}
+	return evaluate(info, cond.GetOperator(), match, values, value)
+}
+
+func evaluate(info OperatorInfo, op Operator, match ArrayMatch, condValues []interface{}, value interface{}) (bool, error) {
+	v, err := normalizeValue(value)
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case IS_NULL:
+		return v == nil, nil
+	case IS_NOT_NULL:
+		return v != nil, nil
+	}
+	if v == nil {
+		return false, nil
+	}
+
+	// Array columns
+	if elems, isArray := v.([]interface{}); isArray {
+		if info.IsArrayOperator {
+			return evaluateArrayOperator(op, condValues, elems)
+		}
+		switch match {
+		case ArrayMatch_Length:
+			lengths := make([]interface{}, len(condValues))
+			for i, cv := range condValues {
+				n, err := toArrayLength(cv)
+				if err != nil {
+					return false, err
+				}
+				lengths[i] = n
+			}
+			return evaluate(info, op, ArrayMatch_Any, lengths, int64(len(elems)))
+		case ArrayMatch_All:
+			for _, elem := range elems {
+				ok, err := evaluate(info, op, ArrayMatch_Any, condValues, elem)
+				if err != nil || !ok {
+					return false, err
+				}
+			}
+			return true, nil
+		}
+		for _, elem := range elems {
+			ok, err := evaluate(info, op, ArrayMatch_Any, condValues, elem)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if info.IsArrayOperator {
+		return false, fmt.Errorf("operator [%s] can only be used with an array value, got [%T]", info, value)
+	}
+	if match != ArrayMatch_Any {
+		return false, fmt.Errorf("array match [%s] can only be used with an array value, got [%T]", match, value)
+	}
+
+	// Normalize the condition values, in the type of the column value
+	values := make([]interface{}, len(condValues))
+	for i := range condValues {
+		cv, err := normalizeValue(condValues[i])
+		if err != nil {
+			return false, err
+		}
+		values[i], err = coerceValue(cv, v)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	switch op {
+	case EQUAL, NOT_EQUAL:
+		if values[0] == nil {
+			return false, nil
+		}
+		c, err := compareValues(v, values[0])
+		if err != nil {
+			return false, err
+		}
+		return (c == 0) == (op == EQUAL), nil
+
+	case IN, NOT_IN:
+		found := false
+		hasNull := false
+		for _, cv := range values {
+			if cv == nil {
+				hasNull = true
+				continue
+			}
+			c, err := compareValues(v, cv)
+			if err != nil {
+				return false, err
+			}
+			if c == 0 {
+				found = true
+				break
+			}
+		}
+		if op == IN {
+			return found, nil
+		}
+		// `x NOT IN (..., NULL)` is never true in SQL
+		return !found && !hasNull, nil
+
+	case GREATER_THAN, GREATER_THAN_EQUAL, LESS_THAN, LESS_THAN_EQUAL:
+		if values[0] == nil {
+			return false, nil
+		}
+		c, err := compareValues(v, values[0])
+		if err != nil {
+			return false, err
+		}
+		switch op {
+		case GREATER_THAN:
+			return c > 0, nil
+		case GREATER_THAN_EQUAL:
+			return c >= 0, nil
+		case LESS_THAN:
+			return c < 0, nil
+		default:
+			return c <= 0, nil
+		}
+
+	case WITHIN_LAST, OLDER_THAN, TODAY, THIS_WEEK, THIS_MONTH, THIS_YEAR:
+		// Resolved into [start, end] for WITHIN_LAST, an upper bound for OLDER_THAN, and [start, end) for the others
+		cStart, err := compareValues(v, values[0])
+		if err != nil {
+			return false, err
+		}
+		if op == OLDER_THAN {
+			return cStart < 0, nil
+		}
+		cEnd, err := compareValues(v, values[1])
+		if err != nil {
+			return false, err
+		}
+		if op == WITHIN_LAST {
+			return cStart >= 0 && cEnd <= 0, nil
+		}
+		return cStart >= 0 && cEnd < 0, nil
+
+	case BETWEEN, NOT_BETWEEN:
+		if values[0] == nil || values[1] == nil {
+			return false, nil
+		}
+		cLow, err := compareValues(v, values[0])
+		if err != nil {
+			return false, err
+		}
+		cHigh, err := compareValues(v, values[1])
+		if err != nil {
+			return false, err
+		}
+		between := cLow >= 0 && cHigh <= 0
+		return between == (op == BETWEEN), nil
+	}
+
+	// The rest are string operators
+	if values[0] == nil {
+		return false, nil
+	}
+	str := toSQLText(v)
+	pattern := toSQLText(values[0])
+	switch op {
+	case LIKE:
+		return MatchLikePattern("%"+pattern+"%", str, false)
+	case ILIKE:
+		return MatchLikePattern("%"+pattern+"%", str, true)
+	case NOT_LIKE:
+		ok, err := MatchLikePattern(pattern, str, false)
+		return !ok, err
+	case STARTS_WITH:
+		return strings.HasPrefix(str, pattern), nil
+	case ENDS_WITH:
+		return strings.HasSuffix(str, pattern), nil
+	case CONTAINS:
+		return strings.Contains(str, pattern), nil
+	case REGEX, IREGEX:
+		if op == IREGEX {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression [%s]: %w", pattern, err)
+		}
+		return re.MatchString(str), nil
+	}
+
+	return false, fmt.Errorf("operator [%s] cannot be evaluated", info)
+}
+
+func evaluateArrayOperator(op Operator, condValues []interface{}, elems []interface{}) (bool, error) {
+	contains := func(cv interface{}) (bool, error) {
+		cv, err := normalizeValue(cv)
+		if err != nil || cv == nil {
+			return false, err
+		}
+		for _, elem := range elems {
+			if elem == nil {
+				continue
+			}
+			cv, err := coerceValue(cv, elem)
+			if err != nil {
+				return false, err
+			}
+			c, err := compareValues(elem, cv)
+			if err != nil {
+				return false, err
+			}
+			if c == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	switch op {
+	case CONTAINS_ALL:
+		for _, cv := range condValues {
+			ok, err := contains(cv)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case CONTAINS_ANY, OVERLAPS:
+		for _, cv := range condValues {
+			ok, err := contains(cv)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case CONTAINED_BY:
+		// Every element should be one of the values. NULL elements are not equal to anything.
+		for _, elem := range elems {
+			if elem == nil {
+				return false, nil
+			}
+			ok, err := evaluateArrayOperator(CONTAINS_ANY, condValues, []interface{}{elem})
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("operator [%s] is not an array operator", op)
+}
+
+// MatchLikePattern reports whether the string matches a Postgres LIKE pattern: `%` matches any sequence of characters,
+// `_` matches a single character and `\` escapes the next character. If insensitive is true, it behaves like ILIKE.
+func MatchLikePattern(pattern, str string, insensitive bool) (bool, error) {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	if insensitive {
+		b.WriteString("(?i)")
+	}
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		return false, fmt.Errorf("LIKE pattern [%s] must not end with escape character", pattern)
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return false, fmt.Errorf("invalid LIKE pattern [%s]: %w", pattern, err)
+	}
+	return re.MatchString(str), nil
+}
+
+/* * * * * * *
+ * Value Normalization
+ * * * * * * */
+
+// normalizeValue converts a Go value into one of: nil (NULL), string, bool, *big.Rat (numbers), time.Time or
+// []interface{} (arrays, with normalized elements).
+func normalizeValue(value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case nil:
+		return nil, nil
+	case string, bool, *big.Rat, time.Time:
+		// Already normalized
+		return value, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	value = rv.Interface()
+
+	// Types with a special meaning
+	switch v := value.(type) {
+	case scalars.Money:
+		// Compared by amount, like NUMERIC columns are
+		if v.IsEmpty() {
+			return nil, nil
+		}
+		return v.Rat(), nil
+	case time.Time:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+
+	// Scalars e.g. ID, Timestamp, Date, Email are converted like they are when stored in the database
+	if valuer, ok := value.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return nil, fmt.Errorf("getting SQL value of [%T]: %w", value, err)
+		}
+		return normalizeValue(dv)
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		r, ok := new(big.Rat).SetString(fmt.Sprintf("%v", rv.Float()))
+		if !ok {
+			return nil, fmt.Errorf("cannot compare float value [%v]", rv.Float())
+		}
+		return r, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		elems := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			elem, err := normalizeValue(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return elems, nil
+	}
+
+	if s, ok := value.(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+	return nil, fmt.Errorf("type [%T] cannot be evaluated", value)
+}
+
+// coerceValue converts a normalized condition value to the type of the normalized column value, the way Postgres
+// casts an untyped literal to the type of the column it's compared with.
+func coerceValue(cv interface{}, like interface{}) (interface{}, error) {
+	if cv == nil || like == nil {
+		return cv, nil
+	}
+	switch like.(type) {
+	case *big.Rat:
+		if s, ok := cv.(string); ok {
+			r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+			if !ok {
+				return nil, fmt.Errorf("invalid input syntax for a number: [%s]", s)
+			}
+			return r, nil
+		}
+	case time.Time:
+		if s, ok := cv.(string); ok {
+			for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05", "2006-01-02"} {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t, nil
+				}
+			}
+			return nil, fmt.Errorf("invalid input syntax for a timestamp: [%s]", s)
+		}
+	case bool:
+		if s, ok := cv.(string); ok {
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case "true", "t", "yes", "y", "on", "1":
+				return true, nil
+			case "false", "f", "no", "n", "off", "0":
+				return false, nil
+			}
+			return nil, fmt.Errorf("invalid input syntax for a boolean: [%s]", s)
+		}
+	case string:
+		return toSQLText(cv), nil
+	}
+	return cv, nil
+}
+
+// compareValues compares two normalized values of the same kind, returning -1, 0 or 1.
+func compareValues(a, b interface{}) (int, error) {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, nil
+			case !a:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	case *big.Rat:
+		if b, ok := b.(*big.Rat); ok {
+			return a.Cmp(b), nil
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare [%v] (%T) with [%v] (%T)", a, a, b, b)
+}
+
+// toSQLText returns the text representation of a normalized value, like a `::text` cast in Postgres.
+func toSQLText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case *big.Rat:
+		if v.IsInt() {
+			return v.Num().String()
+		}
+		return strings.TrimRight(strings.TrimRight(v.FloatString(20), "0"), ".")
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999-07")
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,245 @@
+package filter
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/scalars"
+)
+
+// GOKUTIL_TEST_POSTGRES_URL can be set to a Postgres connection string to also run the conformance cases as SQL.
+const testPostgresURLEnvVar = "GOKUTIL_TEST_POSTGRES_URL"
+
+type evaluateTestCase struct {
+	name    string
+	sqlType string // type of the column when the case is run as SQL
+	value   interface{}
+	cond    Condition
+	want    bool
+}
+
+func mustDate(t *testing.T, year int, month time.Month, day int) scalars.Date {
+	d, err := scalars.NewDate(year, month, day)
+	assert.NoError(t, err)
+	return d
+}
+
+func mustMoney(t *testing.T, str string) scalars.Money {
+	m, err := scalars.NewMoneyFromString(str)
+	assert.NoError(t, err)
+	return m
+}
+
+func getEvaluateTestCases(t *testing.T) []evaluateTestCase {
+	ts := scalars.NewTimestamp(time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC))
+	tsBefore := scalars.NewTimestamp(time.Date(2024, 5, 10, 11, 0, 0, 0, time.UTC))
+	tsAfter := scalars.NewTimestamp(time.Date(2024, 5, 10, 13, 0, 0, 0, time.UTC))
+	id := scalars.NewStaticID("evaluate")
+	otherID := scalars.NewStaticID("other")
+	email := scalars.NewEmail("jane@example.com")
+	var nullStr *string
+
+	return []evaluateTestCase{
+		// Strings
+		{"string EQUAL", "text", "hello", NewStringCondition(EQUAL, "hello"), true},
+		{"string EQUAL is case sensitive", "text", "hello", NewStringCondition(EQUAL, "Hello"), false},
+		{"string NOT_EQUAL", "text", "hello", NewStringCondition(NOT_EQUAL, "x"), true},
+		{"string IN", "text", "hello", NewStringCondition(IN, "a", "hello"), true},
+		{"string NOT_IN", "text", "hello", NewStringCondition(NOT_IN, "a", "b"), true},
+		{"string NOT_IN matching", "text", "hello", NewStringCondition(NOT_IN, "hello"), false},
+		{"string GREATER_THAN", "text", "hello", NewStringCondition(GREATER_THAN, "a"), true},
+		{"string LESS_THAN", "text", "hello", NewStringCondition(LESS_THAN, "z"), true},
+		{"string BETWEEN", "text", "hello", NewStringCondition(BETWEEN, "a", "m"), true},
+		{"string LIKE", "text", "hello", NewStringCondition(LIKE, "ell"), true},
+		{"string LIKE is case sensitive", "text", "hello", NewStringCondition(LIKE, "ELL"), false},
+		{"string LIKE with _", "text", "hello", NewStringCondition(LIKE, "h_llo"), true},
+		{"string LIKE with escaped _", "text", "hello", NewStringCondition(LIKE, `h\_llo`), false},
+		{"string ILIKE", "text", "hello", NewStringCondition(ILIKE, "ELL"), true},
+		{"string NOT_LIKE", "text", "hello", NewStringCondition(NOT_LIKE, "h%"), false},
+		{"string NOT_LIKE not matching", "text", "hello", NewStringCondition(NOT_LIKE, "x%"), true},
+		{"string STARTS_WITH", "text", "hello", NewStringCondition(STARTS_WITH, "he"), true},
+		{"string STARTS_WITH wildcard is literal", "text", "hello", NewStringCondition(STARTS_WITH, "%"), false},
+		{"string STARTS_WITH literal %", "text", "50% off", NewStringCondition(STARTS_WITH, "50%"), true},
+		{"string ENDS_WITH", "text", "hello", NewStringCondition(ENDS_WITH, "lo"), true},
+		{"string CONTAINS", "text", "hello", NewStringCondition(CONTAINS, "ll"), true},
+		{"string CONTAINS wildcard is literal", "text", "hello", NewStringCondition(CONTAINS, "_"), false},
+		{"string REGEX", "text", "hello", NewStringCondition(REGEX, "^h.*o$"), true},
+		{"string REGEX is case sensitive", "text", "hello", NewStringCondition(REGEX, "^H"), false},
+		{"string IREGEX", "text", "hello", NewStringCondition(IREGEX, "^H"), true},
+		{"string IS_NULL", "text", "hello", NewStringCondition(IS_NULL), false},
+		{"string IS_NOT_NULL", "text", "hello", NewStringCondition(IS_NOT_NULL), true},
+
+		// NULLs
+		{"null EQUAL", "text", nullStr, NewStringCondition(EQUAL, "x"), false},
+		{"null NOT_EQUAL", "text", nullStr, NewStringCondition(NOT_EQUAL, "x"), false},
+		{"null NOT_IN", "text", nullStr, NewStringCondition(NOT_IN, "x"), false},
+		{"null LIKE", "text", nullStr, NewStringCondition(LIKE, "x"), false},
+		{"null IS_NULL", "text", nullStr, NewStringCondition(IS_NULL), true},
+		{"null IS_NOT_NULL", "text", nullStr, NewStringCondition(IS_NOT_NULL), false},
+
+		// Numbers
+		{"int EQUAL", "bigint", 5, NewNumberCondition(EQUAL, 5), true},
+		{"int GREATER_THAN", "bigint", 5, NewNumberCondition(GREATER_THAN, 4), true},
+		{"int GREATER_THAN_EQUAL", "bigint", 5, NewNumberCondition(GREATER_THAN_EQUAL, 5), true},
+		{"int LESS_THAN", "bigint", 5, NewNumberCondition(LESS_THAN, 5), false},
+		{"int LESS_THAN_EQUAL", "bigint", 5, NewNumberCondition(LESS_THAN_EQUAL, 5), true},
+		{"int BETWEEN is inclusive", "bigint", 5, NewNumberCondition(BETWEEN, 1, 5), true},
+		{"int NOT_BETWEEN", "bigint", 5, NewNumberCondition(NOT_BETWEEN, 1, 5), false},
+		{"int IN", "bigint", 5, NewNumberCondition(IN, 1, 5), true},
+		{"int NOT_IN", "bigint", 5, NewNumberCondition(NOT_IN, 1, 2), true},
+		{"float GREATER_THAN", "double precision", 1.5, NewFloatCondition(GREATER_THAN, 1), true},
+		{"float EQUAL", "double precision", 1.5, NewFloatCondition(EQUAL, 1.5), true},
+		{"int with float condition", "bigint", 2, NewFloatCondition(LESS_THAN, 2.5), true},
+
+		// Bools
+		{"bool EQUAL", "boolean", true, NewBoolCondition(EQUAL, true), true},
+		{"bool NOT_EQUAL", "boolean", true, NewBoolCondition(NOT_EQUAL, false), true},
+		{"bool GREATER_THAN", "boolean", true, NewBoolCondition(GREATER_THAN, false), true},
+
+		// Scalars
+		{"ID EQUAL", "uuid", id, NewIDCondition(EQUAL, id), true},
+		{"ID NOT_EQUAL", "uuid", id, NewIDCondition(NOT_EQUAL, otherID), true},
+		{"ID IN", "uuid", id, NewIDCondition(IN, otherID, id), true},
+		{"ID empty IS_NULL", "uuid", scalars.ID{}, NewIDCondition(IS_NULL), true},
+		{"Timestamp EQUAL", "timestamp with time zone", ts, NewTimestampCondition(EQUAL, ts), true},
+		{"Timestamp GREATER_THAN", "timestamp with time zone", ts, NewTimestampCondition(GREATER_THAN, tsBefore), true},
+		{"Timestamp LESS_THAN", "timestamp with time zone", ts, NewTimestampCondition(LESS_THAN, tsBefore), false},
+		{"Timestamp BETWEEN", "timestamp with time zone", ts, NewTimestampCondition(BETWEEN, tsBefore, tsAfter), true},
+		{"Date EQUAL", "date", mustDate(t, 2024, 5, 10), NewDateCondition(EQUAL, mustDate(t, 2024, 5, 10)), true},
+		{"Date LESS_THAN", "date", mustDate(t, 2024, 5, 10), NewDateCondition(LESS_THAN, mustDate(t, 2024, 6, 1)), true},
+		{"Email EQUAL", "text", email, NewEmailCondition(EQUAL, email), true},
+		{"Email ILIKE", "text", email, NewEmailCondition(ILIKE, scalars.NewEmail("EXAMPLE")), true},
+		{"Email ENDS_WITH", "text", email, NewEmailCondition(ENDS_WITH, scalars.NewEmail("@example.com")), true},
+		{"Money EQUAL", "numeric", mustMoney(t, "12.50 USD"), NewGenericCondition(EQUAL, mustMoney(t, "12.50 USD")), true},
+		{"Money GREATER_THAN", "numeric", mustMoney(t, "12.50 USD"), NewGenericCondition(GREATER_THAN, mustMoney(t, "10 USD")), true},
+		{"Money LESS_THAN", "numeric", mustMoney(t, "12.50 USD"), NewGenericCondition(LESS_THAN, mustMoney(t, "12.25 USD")), false},
+		{"Money negative", "numeric", mustMoney(t, "-0.05 USD"), NewMoneyCondition(BETWEEN, mustMoney(t, "-1 USD"), mustMoney(t, "0 USD")), true},
+
+		// Arrays
+		{"array EQUAL any element", "text[]", []string{"a", "b"}, NewStringCondition(EQUAL, "b"), true},
+		{"array CONTAINS_ALL", "text[]", []string{"a", "b"}, NewStringCondition(CONTAINS_ALL, "a", "b"), true},
+		{"array CONTAINS_ALL missing", "text[]", []string{"a", "b"}, NewStringCondition(CONTAINS_ALL, "a", "c"), false},
+		{"array CONTAINS_ANY", "text[]", []string{"a", "b"}, NewStringCondition(CONTAINS_ANY, "c", "b"), true},
+		{"array OVERLAPS", "text[]", []string{"a", "b"}, NewStringCondition(OVERLAPS, "c"), false},
+		{"array CONTAINED_BY", "text[]", []string{"a", "b"}, NewStringCondition(CONTAINED_BY, "a", "b", "c"), true},
+		{"array CONTAINED_BY missing", "text[]", []string{"a", "d"}, NewStringCondition(CONTAINED_BY, "a", "b"), false},
+		{"array IN any element", "text[]", []string{"a", "b"}, NewStringCondition(IN, "c", "b"), true},
+		{"array GREATER_THAN any element", "text[]", []string{"a", "c"}, NewAnyElementCondition(GREATER_THAN, "b"), true},
+		{"array GREATER_THAN all elements", "text[]", []string{"a", "c"}, NewAllElementsCondition(GREATER_THAN, "b"), false},
+		{"array LIKE all elements", "text[]", []string{"ab", "cb"}, NewAllElementsCondition(LIKE, "b"), true},
+		{"array all elements of empty array", "text[]", []string{}, NewAllElementsCondition(EQUAL, "a"), true},
+		{"array length EQUAL", "text[]", []string{"a", "b"}, NewArrayLengthCondition(EQUAL, 2), true},
+		{"array length of empty array", "text[]", []string{}, NewArrayLengthCondition(EQUAL, 0), true},
+		{"array length BETWEEN", "text[]", []string{"a", "b", "c"}, NewArrayLengthCondition(BETWEEN, 1, 2), false},
+	}
+}
+
+// TestEvaluate_Conformance checks that Evaluate gives the expected result for each case. If a Postgres database is
+// available, it also runs each case as SQL (generated by InjectConditionIntoSqlBuilder) and checks that it agrees.
+func TestEvaluate_Conformance(t *testing.T) {
+	var db *sql.DB
+	if url := os.Getenv(testPostgresURLEnvVar); url != "" {
+		var err error
+		db, err = sql.Open("postgres", url)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer db.Close()
+	} else {
+		t.Logf("%s is not set, so the cases are not checked against Postgres", testPostgresURLEnvVar)
+	}
+
+	for _, tt := range getEvaluateTestCases(t) {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.cond, tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got, "Evaluate")
+
+			if db == nil {
+				return
+			}
+			var value interface{} = tt.value
+			_, isArray := tt.value.([]string)
+			if isArray {
+				value = pq.Array(tt.value)
+			}
+			sb := goqu.Dialect("postgres").
+				From(goqu.L("(SELECT CAST(? AS "+tt.sqlType+`) AS "col") AS "t"`, value)).
+				Select(goqu.L("1")).
+				Prepared(true)
+			sb, err = InjectConditionIntoSqlBuilder(tt.cond, sb, "col", isArray)
+			if !assert.NoError(t, err) {
+				return
+			}
+			query, args, err := sb.ToSQL()
+			if !assert.NoError(t, err) {
+				return
+			}
+			var gotSQL bool
+			err = db.QueryRow("SELECT EXISTS ("+query+")", args...).Scan(&gotSQL)
+			if assert.NoError(t, err, query) {
+				assert.Equal(t, tt.want, gotSQL, "SQL: %s %v", query, args)
+			}
+		})
+	}
+}
+
+func TestEvaluate_AnyCondition(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		cond  Condition
+		want  bool
+	}{
+		{"float values against int", 5, AnyCondition{Op: GREATER_THAN, Values: []interface{}{4.0}}, true},
+		{"string values against timestamp", scalars.NewTimestamp(time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)), AnyCondition{Op: LESS_THAN, Values: []interface{}{"2024-06-01T00:00:00Z"}}, true},
+		{"string values against date", mustDate(t, 2024, 5, 10), AnyCondition{Op: EQUAL, Values: []interface{}{"2024-05-10"}}, true},
+		{"string values against bool", true, AnyCondition{Op: EQUAL, Values: []interface{}{"true"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.cond, tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatchLikePattern(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		str         string
+		insensitive bool
+		want        bool
+		wantErr     bool
+	}{
+		{pattern: "abc", str: "abc", want: true},
+		{pattern: "a%", str: "abc", want: true},
+		{pattern: "_b_", str: "abc", want: true},
+		{pattern: "_b", str: "abc", want: false},
+		{pattern: `a\%`, str: "a%", want: true},
+		{pattern: `a\%`, str: "ab", want: false},
+		{pattern: `a\\`, str: `a\`, want: true},
+		{pattern: "A%", str: "abc", want: false},
+		{pattern: "A%", str: "abc", insensitive: true, want: true},
+		{pattern: "a.c", str: "abc", want: false},
+		{pattern: "%", str: "multi\nline", want: true},
+		{pattern: `a\`, str: "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := MatchLikePattern(tt.pattern, tt.str, tt.insensitive)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
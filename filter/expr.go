@@ -0,0 +1,263 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+/* * * * * * *
+ * Expression Trees
+ * * * * * * */
+
+// Expr is a boolean tree of conditions. Exactly one of And, Or, Not or Column (with Condition) should be set:
+//   - And/Or combine all their sub-expressions
+//   - Not negates its sub-expression
+//   - Column + Condition is a leaf that applies the condition to the column
+//
+// Column names are quoted when compiled, but are otherwise used as is. When the expression comes from a client,
+// make sure its Columns are allowed before using it.
+type Expr struct {
+	And       []Expr    `json:"and,omitempty" yaml:"and,omitempty"`
+	Or        []Expr    `json:"or,omitempty" yaml:"or,omitempty"`
+	Not       *Expr     `json:"not,omitempty" yaml:"not,omitempty"`
+	Column    string    `json:"column,omitempty" yaml:"column,omitempty"`
+	Condition Condition `json:"condition,omitempty" yaml:"condition,omitempty"`
+}
+
+// AnyCondition is the condition type used for leaves when an Expr is unmarshaled, since the type of the column is not known.
+type AnyCondition = GenericCondition[interface{}]
+
+func And(exprs ...Expr) Expr {
+	return Expr{And: exprs}
+}
+
+func Or(exprs ...Expr) Expr {
+	return Expr{Or: exprs}
+}
+
+func Not(e Expr) Expr {
+	return Expr{Not: &e}
+}
+
+// Where creates a leaf expression.
+func Where(col string, cond Condition) Expr {
+	return Expr{Column: col, Condition: cond}
+}
+
+func (e Expr) IsLeaf() bool {
+	return e.Column != "" || e.Condition != nil
+}
+
+func (e Expr) numKinds() int {
+	n := 0
+	if e.And != nil {
+		n++
+	}
+	if e.Or != nil {
+		n++
+	}
+	if e.Not != nil {
+		n++
+	}
+	if e.IsLeaf() {
+		n++
+	}
+	return n
+}
+
+// Validate checks the structure of the tree, and validates the condition at each leaf using ValidateCondition.
+func (e Expr) Validate() error {
+	if n := e.numKinds(); n != 1 {
+		return fmt.Errorf("filter expression should have exactly one of and, or, not or column but has [%d]", n)
+	}
+	switch {
+	case e.And != nil:
+		return validateExprs("and", e.And)
+	case e.Or != nil:
+		return validateExprs("or", e.Or)
+	case e.Not != nil:
+		if err := e.Not.Validate(); err != nil {
+			return fmt.Errorf("not: %w", err)
+		}
+		return nil
+	}
+
+	if e.Column == "" {
+		return fmt.Errorf("filter expression has a condition but no column")
+	}
+	if e.Condition == nil {
+		return fmt.Errorf("filter expression for column [%s] has no condition", e.Column)
+	}
+	if err := ValidateCondition(e.Condition); err != nil {
+		return fmt.Errorf("column [%s]: %w", e.Column, err)
+	}
+	return nil
+}
+
+func validateExprs(kind string, exprs []Expr) error {
+	if len(exprs) < 1 {
+		return fmt.Errorf("%s: filter expression has no sub-expressions", kind)
+	}
+	for i := range exprs {
+		if err := exprs[i].Validate(); err != nil {
+			return fmt.Errorf("%s[%d]: %w", kind, i, err)
+		}
+	}
+	return nil
+}
+
+// Columns returns the columns used in the leaves of the tree, in order of appearance and without duplicates.
+func (e Expr) Columns() []string {
+	var cols []string
+	seen := map[string]bool{}
+	e.Walk(func(leaf Expr) {
+		if !seen[leaf.Column] {
+			seen[leaf.Column] = true
+			cols = append(cols, leaf.Column)
+		}
+	})
+	return cols
+}
+
+// Walk calls fn for each leaf of the tree.
+func (e Expr) Walk(fn func(leaf Expr)) {
+	for _, sub := range e.And {
+		sub.Walk(fn)
+	}
+	for _, sub := range e.Or {
+		sub.Walk(fn)
+	}
+	if e.Not != nil {
+		e.Not.Walk(fn)
+	}
+	if e.IsLeaf() {
+		fn(e)
+	}
+}
+
+// Depth returns how deeply the and/or/not expressions in the tree are nested. A leaf has a depth of 0.
+func (e Expr) Depth() int {
+	if e.IsLeaf() {
+		return 0
+	}
+	subs := append(append([]Expr{}, e.And...), e.Or...)
+	if e.Not != nil {
+		subs = append(subs, *e.Not)
+	}
+	max := 0
+	for _, sub := range subs {
+		if d := sub.Depth(); d > max {
+			max = d
+		}
+	}
+	return max + 1
+}
+
+// ToGoquExpression validates the tree and compiles it into a single goqu expression, which can be passed to Where.
+func (e Expr) ToGoquExpression() (exp.Expression, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e.toGoquExpression()
+}
+
+func (e Expr) toGoquExpression() (exp.Expression, error) {
+	switch {
+	case e.And != nil:
+		subs, err := toGoquExpressions(e.And)
+		if err != nil {
+			return nil, err
+		}
+		return goqu.And(subs...), nil
+	case e.Or != nil:
+		subs, err := toGoquExpressions(e.Or)
+		if err != nil {
+			return nil, err
+		}
+		return goqu.Or(subs...), nil
+	case e.Not != nil:
+		sub, err := e.Not.toGoquExpression()
+		if err != nil {
+			return nil, err
+		}
+		return goqu.L("NOT (?)", sub), nil
+	}
+
+	sub, err := GetConditionExpression(e.Condition, e.Column, false)
+	if err != nil {
+		return nil, fmt.Errorf("column [%s]: %w", e.Column, err)
+	}
+	if sub == nil {
+		// A condition that doesn't constrain anything is always true
+		return goqu.L("TRUE"), nil
+	}
+	return sub, nil
+}
+
+func toGoquExpressions(exprs []Expr) ([]exp.Expression, error) {
+	var r []exp.Expression
+	for _, sub := range exprs {
+		e, err := sub.toGoquExpression()
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, e)
+	}
+	return r, nil
+}
+
+// InjectExprIntoSqlBuilder adds the expression as a where condition to the select query. The expression is checked
+// against the policy set using SetPolicy.
+func InjectExprIntoSqlBuilder(e Expr, sb *goqu.SelectDataset) (*goqu.SelectDataset, error) {
+	return GetPolicy().InjectExprIntoSqlBuilder(e, sb)
+}
+
+func injectExprIntoSqlBuilder(e Expr, sb *goqu.SelectDataset) (*goqu.SelectDataset, error) {
+	where, err := e.ToGoquExpression()
+	if err != nil {
+		return nil, err
+	}
+	return sb.Where(where), nil
+}
+
+/* * * * * * *
+ * Marshaling
+ * * * * * * */
+
+// exprRaw is how an Expr is unmarshaled, since Condition is an interface.
+type exprRaw struct {
+	And       []Expr        `json:"and,omitempty" yaml:"and,omitempty"`
+	Or        []Expr        `json:"or,omitempty" yaml:"or,omitempty"`
+	Not       *Expr         `json:"not,omitempty" yaml:"not,omitempty"`
+	Column    string        `json:"column,omitempty" yaml:"column,omitempty"`
+	Condition *AnyCondition `json:"condition,omitempty" yaml:"condition,omitempty"`
+}
+
+func (raw exprRaw) toExpr() Expr {
+	e := Expr{And: raw.And, Or: raw.Or, Not: raw.Not, Column: raw.Column}
+	if raw.Condition != nil {
+		e.Condition = *raw.Condition
+	}
+	return e
+}
+
+func (e *Expr) UnmarshalJSON(data []byte) error {
+	var raw exprRaw
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = raw.toExpr()
+	return nil
+}
+
+func (e *Expr) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw exprRaw
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*e = raw.toExpr()
+	return nil
+}
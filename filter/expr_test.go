@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpr_ToGoquExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     Expr
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "leaf",
+			expr:     Where("status", NewStringCondition(EQUAL, "active")),
+			wantSQL:  `SELECT * FROM "t" WHERE ("status" = $1)`,
+			wantArgs: []interface{}{"active"},
+		},
+		{
+			name: "and, or, not",
+			expr: And(
+				Where("status", NewStringCondition(IN, "a", "b")),
+				Or(
+					Where("age", NewNumberCondition(GREATER_THAN, 18)),
+					Not(Where("deleted_at", NewTimestampCondition(IS_NULL))),
+				),
+			),
+			wantSQL:  `SELECT * FROM "t" WHERE (("status" IN ($1, $2)) AND (("age" > $3) OR NOT (("deleted_at" IS NULL))))`,
+			wantArgs: []interface{}{"a", "b", int64(18)},
+		},
+		{
+			name:    "invalid leaf condition",
+			expr:    And(Where("status", NewStringCondition(EQUAL, "a", "b"))),
+			wantErr: true,
+		},
+		{
+			name:    "empty or",
+			expr:    Expr{Or: []Expr{}},
+			wantErr: true,
+		},
+		{
+			name:    "more than one kind",
+			expr:    Expr{Column: "status", Condition: NewStringCondition(EQUAL, "a"), Not: &Expr{}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sb, err := InjectExprIntoSqlBuilder(tt.expr, goqu.Dialect("postgres").From("t").Prepared(true))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			sql, args, err := sb.ToSQL()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestExpr_MarshalRoundTrip(t *testing.T) {
+	expr := And(
+		Where("status", NewStringCondition(IN, "a", "b")),
+		Not(Where("role", NewStringCondition(EQUAL, "admin"))),
+	)
+	want := And(
+		Where("status", AnyCondition{Op: IN, Values: []interface{}{"a", "b"}}),
+		Not(Where("role", AnyCondition{Op: EQUAL, Values: []interface{}{"admin"}})),
+	)
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(expr)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"and":[{"column":"status","condition":{"op":"IN","values":["a","b"]}},{"not":{"column":"role","condition":{"op":"EQUAL","values":["admin"]}}}]}`, string(data))
+
+		var got Expr
+		assert.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		data, err := yaml.Marshal(expr)
+		assert.NoError(t, err)
+
+		var got Expr
+		assert.NoError(t, yaml.Unmarshal(data, &got))
+		assert.Equal(t, want, got)
+	})
+}
@@ -5,22 +5,31 @@ go 1.23.4
 require (
 	github.com/doug-martin/goqu/v9 v9.19.0
 	github.com/huandu/go-sqlbuilder v1.35.0
+	github.com/invopop/jsonschema v0.13.0
+	github.com/lib/pq v1.10.9
+	github.com/stretchr/testify v1.10.0
+	github.com/teejays/gokutil/errutil v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/log v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/panics v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/scalars v0.0.0-20250426215142-5dc7bd3f1fd0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/Rican7/conjson v0.1.0 // indirect
+	github.com/bahlo/generic-list-go v0.2.0 // indirect
+	github.com/buger/jsonparser v1.1.1 // indirect
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/graph-gophers/graphql-go v1.6.0 // indirect
 	github.com/huandu/xstrings v1.5.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/mailru/easyjson v0.9.0 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/teejays/gokutil/clog v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/ctxutil v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/env v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
-	github.com/teejays/gokutil/errutil v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/gopi v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/sclog v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
+	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 )
@@ -0,0 +1,742 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+
+	"github.com/teejays/gokutil/scalars"
+)
+
+/* * * * * * *
+ * JSON Path Conditions
+ * * * * * * */
+
+// JSONCondition is a Condition on a value inside a JSON (jsonb) column e.g. a scalars.GenericData field, rather than
+// on the whole column.
+type JSONCondition interface {
+	Condition
+	GetJSONPath() string
+}
+
+// JSONPathCondition is a condition on the value at Path inside a jsonb column. The path is a dot separated list of
+// object keys, each optionally followed by array indexes in brackets e.g. `address.city` or `items[0].sku`. Keys can
+// be all digits e.g. `years.2024`. A `*` matches any element of an array e.g. `items.*.sku`.
+//
+// The type of the values decides how the JSON value is compared:
+//   - strings: compared with the text of the JSON value (i.e. `->>`), so all the string operators can be used
+//   - numbers: only JSON numbers match, compared as numeric
+//   - booleans: only JSON booleans match
+//
+// IS_NULL matches if the path doesn't exist or the value is a JSON null. CONTAINS_ALL uses jsonb containment (`@>`):
+// a single value should be contained in the JSON value at the path (e.g. `{"city": "Paris"}`), and multiple values
+// should all be elements of the JSON array at the path.
+type JSONPathCondition struct {
+	Path   string        `json:"path" yaml:"path"`
+	Op     Operator      `json:"op" yaml:"op"`
+	Values []interface{} `json:"values" yaml:"values"`
+}
+
+func NewJSONPathCondition(path string, op Operator, values ...interface{}) *JSONPathCondition {
+	return &JSONPathCondition{Path: path, Op: op, Values: values}
+}
+
+func (f JSONPathCondition) GetOperator() Operator      { return f.Op }
+func (f JSONPathCondition) Len() int                   { return len(f.Values) }
+func (f JSONPathCondition) GetValue(i int) interface{} { return f.Values[i] }
+func (f JSONPathCondition) GetJSONPath() string        { return f.Path }
+
+// JSON_PATH_WILDCARD is the path segment that matches any element of an array.
+const JSON_PATH_WILDCARD = "*"
+
+type jsonPathSegment struct {
+	Key      string
+	Index    int
+	IsIndex  bool
+	Wildcard bool
+}
+
+var jsonPathPartRegex = regexp.MustCompile(`^([A-Za-z0-9_\-]*)((?:\[[0-9]+\])*)$`)
+var jsonPathIndexRegex = regexp.MustCompile(`\[([0-9]+)\]`)
+
+// parseJSONPath parses the dot separated path. Keys are restricted to letters, digits, `_` and `-` so they can be
+// safely put in SQL. Array indexes have to be in brackets, so that an all-digit key is looked up as a key both by the
+// SQL (`->'2024'`) and by Evaluate.
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if path == "" {
+		return nil, nil
+	}
+	var segments []jsonPathSegment
+	for _, s := range strings.Split(path, ".") {
+		if s == JSON_PATH_WILDCARD {
+			segments = append(segments, jsonPathSegment{Wildcard: true})
+			continue
+		}
+		m := jsonPathPartRegex.FindStringSubmatch(s)
+		if s == "" || m == nil {
+			return nil, fmt.Errorf("invalid JSON path [%s]: segment [%s] should be a key of letters, digits, '_' or '-', optionally followed by array indexes e.g. [0]", path, s)
+		}
+		if m[1] != "" {
+			segments = append(segments, jsonPathSegment{Key: m[1]})
+		}
+		for _, im := range jsonPathIndexRegex.FindAllStringSubmatch(m[2], -1) {
+			n, err := strconv.Atoi(im[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON path [%s]: array index [%s] is too large", path, im[1])
+			}
+			segments = append(segments, jsonPathSegment{Index: n, IsIndex: true})
+		}
+	}
+	return segments, nil
+}
+
+func hasJSONPathWildcard(segments []jsonPathSegment) bool {
+	for _, s := range segments {
+		if s.Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonValueKind int
+
+const (
+	jsonValueKind_None jsonValueKind = iota // no values
+	jsonValueKind_Text
+	jsonValueKind_Numeric
+	jsonValueKind_Boolean
+)
+
+// getJSONValueKind returns the kind of the condition values, which should all be of the same kind.
+func getJSONValueKind(values []interface{}) (jsonValueKind, error) {
+	kind := jsonValueKind_None
+	for _, v := range values {
+		var k jsonValueKind
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.String:
+			k = jsonValueKind_Text
+			if _, ok := v.(json.Number); ok {
+				k = jsonValueKind_Numeric
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			k = jsonValueKind_Numeric
+		case reflect.Bool:
+			k = jsonValueKind_Boolean
+		default:
+			return 0, fmt.Errorf("JSON path condition values should be strings, numbers or booleans, got [%T]", v)
+		}
+		if kind != jsonValueKind_None && kind != k {
+			return 0, fmt.Errorf("JSON path condition values should all be of the same type")
+		}
+		kind = k
+	}
+	return kind, nil
+}
+
+// validateJSONCondition checks that the operator can be used with the path and the values.
+func validateJSONCondition(info OperatorInfo, op Operator, segments []jsonPathSegment, values []interface{}) (jsonValueKind, error) {
+	if op == CONTAINS_ALL {
+		if hasJSONPathWildcard(segments) {
+			return 0, fmt.Errorf("operator [%s] cannot be used with a wildcard JSON path", info)
+		}
+		return jsonValueKind_None, nil
+	}
+	if len(segments) == 0 {
+		return 0, fmt.Errorf("operator [%s] needs a JSON path", info)
+	}
+	kind, err := getJSONValueKind(values)
+	if err != nil {
+		return 0, err
+	}
+
+	var allowed bool
+	switch op {
+	case IS_NULL, IS_NOT_NULL:
+		allowed = true
+	case EQUAL, NOT_EQUAL:
+		allowed = true
+	case IN, GREATER_THAN, GREATER_THAN_EQUAL, LESS_THAN, LESS_THAN_EQUAL:
+		allowed = kind != jsonValueKind_Boolean
+	case STARTS_WITH:
+		allowed = kind == jsonValueKind_Text
+	case NOT_IN, BETWEEN, NOT_BETWEEN:
+		allowed = kind != jsonValueKind_Boolean && !hasJSONPathWildcard(segments)
+	case LIKE, ILIKE, NOT_LIKE, ENDS_WITH, CONTAINS, REGEX, IREGEX:
+		allowed = kind == jsonValueKind_Text && !hasJSONPathWildcard(segments)
+	}
+	if !allowed {
+		return 0, fmt.Errorf("operator [%s] cannot be used with these JSON path condition values", info)
+	}
+	return kind, nil
+}
+
+/* * * * * * *
+ * JSON Path Conditions: SQL
+ * * * * * * */
+
+// getJSONConditionExpression returns the where condition for a JSON path condition on a jsonb column e.g. for the path
+// `address.city`:
+//
+//	strings:   ("col"->'address'->>'city' = $1)
+//	numbers:   (CASE WHEN jsonb_typeof("col"->'address'->'zip') = 'number' THEN ("col"->'address'->>'zip')::numeric END > $1)
+//	booleans:  (CASE WHEN jsonb_typeof("col"->'verified') = 'boolean' THEN ("col"->>'verified')::boolean END = $1)
+//	CONTAINS_ALL: "col"->'address' @> $1::jsonb
+//	wildcards: jsonb_path_exists("col", $1::jsonpath, $2::jsonb) e.g. with the path `$."items"[*]."sku" ? (@ == $v0)`
+func getJSONConditionExpression(info OperatorInfo, op Operator, path string, col string, values []interface{}) (exp.Expression, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := validateJSONCondition(info, op, segments, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if op == CONTAINS_ALL {
+		target, err := json.Marshal(getJSONContainsTarget(values))
+		if err != nil {
+			return nil, fmt.Errorf("encoding the values of JSON path condition: %w", err)
+		}
+		return goqu.L(fmt.Sprintf("?%s @> ?::jsonb", getSQLJSONPath(segments, false)), goqu.C(col), string(target)), nil
+	}
+
+	if hasJSONPathWildcard(segments) {
+		jsonPath, vars := getJSONPathExistsQuery(op, segments, values)
+		varsJSON, err := json.Marshal(vars)
+		if err != nil {
+			return nil, fmt.Errorf("encoding the values of JSON path condition: %w", err)
+		}
+		return goqu.L("jsonb_path_exists(?, ?::jsonpath, ?::jsonb)", goqu.C(col), jsonPath, string(varsJSON)), nil
+	}
+
+	text := goqu.L("?"+getSQLJSONPath(segments, true), goqu.C(col))
+	var operand exp.LiteralExpression
+	switch kind {
+	case jsonValueKind_Numeric:
+		operand = goqu.L(fmt.Sprintf("CASE WHEN jsonb_typeof(?%s) = 'number' THEN (?)::numeric END", getSQLJSONPath(segments, false)), goqu.C(col), text)
+	case jsonValueKind_Boolean:
+		operand = goqu.L(fmt.Sprintf("CASE WHEN jsonb_typeof(?%s) = 'boolean' THEN (?)::boolean END", getSQLJSONPath(segments, false)), goqu.C(col), text)
+	default:
+		operand = text
+	}
+	return getLiteralConditionExpression(operand, info, op, values)
+}
+
+// getSQLJSONPath returns the `->` chain for the path. If asText is true, the last one is `->>`.
+func getSQLJSONPath(segments []jsonPathSegment, asText bool) string {
+	var sb strings.Builder
+	for i, s := range segments {
+		sb.WriteString("->")
+		if asText && i == len(segments)-1 {
+			sb.WriteString(">")
+		}
+		if s.IsIndex {
+			sb.WriteString(strconv.Itoa(s.Index))
+		} else {
+			sb.WriteString("'" + s.Key + "'")
+		}
+	}
+	return sb.String()
+}
+
+// getJSONPathExistsQuery returns the SQL/JSON path query and its variables for a wildcard path.
+func getJSONPathExistsQuery(op Operator, segments []jsonPathSegment, values []interface{}) (string, map[string]interface{}) {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, s := range segments {
+		switch {
+		case s.Wildcard:
+			sb.WriteString("[*]")
+		case s.IsIndex:
+			sb.WriteString("[" + strconv.Itoa(s.Index) + "]")
+		default:
+			sb.WriteString(`."` + s.Key + `"`)
+		}
+	}
+
+	vars := map[string]interface{}{}
+	var preds []string
+	for i, v := range values {
+		name := "v" + strconv.Itoa(i)
+		vars[name] = v
+		preds = append(preds, "$"+name)
+	}
+
+	var pred string
+	switch op {
+	case IS_NULL:
+		pred = "@ == null"
+	case IS_NOT_NULL:
+		pred = "@ != null"
+	case EQUAL:
+		pred = "@ == " + preds[0]
+	case NOT_EQUAL:
+		pred = "@ != " + preds[0]
+	case IN:
+		for i := range preds {
+			preds[i] = "@ == " + preds[i]
+		}
+		pred = strings.Join(preds, " || ")
+	case GREATER_THAN:
+		pred = "@ > " + preds[0]
+	case GREATER_THAN_EQUAL:
+		pred = "@ >= " + preds[0]
+	case LESS_THAN:
+		pred = "@ < " + preds[0]
+	case LESS_THAN_EQUAL:
+		pred = "@ <= " + preds[0]
+	case STARTS_WITH:
+		pred = "@ starts with " + preds[0]
+	}
+	return sb.String() + " ? (" + pred + ")", vars
+}
+
+// getJSONContainsTarget returns the JSON value that should be contained in the value at the path.
+func getJSONContainsTarget(values []interface{}) interface{} {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+// getLiteralConditionExpression returns the where condition for an SQL expression (e.g. a function call), rather than
+// a column. It follows the GoquExpression of the operators.
+func getLiteralConditionExpression(operand exp.LiteralExpression, info OperatorInfo, op Operator, values []interface{}) (exp.Expression, error) {
+	switch op {
+	case EQUAL:
+		return operand.Eq(values[0]), nil
+	case NOT_EQUAL:
+		return operand.Neq(values[0]), nil
+	case IN:
+		if len(values) < 1 {
+			return nil, nil
+		}
+		return operand.In(values), nil
+	case NOT_IN:
+		if len(values) < 1 {
+			return nil, nil
+		}
+		return operand.NotIn(values), nil
+	case GREATER_THAN:
+		return operand.Gt(values[0]), nil
+	case GREATER_THAN_EQUAL:
+		return operand.Gte(values[0]), nil
+	case LESS_THAN:
+		return operand.Lt(values[0]), nil
+	case LESS_THAN_EQUAL:
+		return operand.Lte(values[0]), nil
+	case BETWEEN:
+		return operand.Between(exp.NewRangeVal(values[0], values[1])), nil
+	case NOT_BETWEEN:
+		return operand.NotBetween(exp.NewRangeVal(values[0], values[1])), nil
+	case LIKE:
+		return operand.Like(fmt.Sprintf("%%%s%%", values[0])), nil
+	case ILIKE:
+		return operand.ILike(fmt.Sprintf("%%%s%%", values[0])), nil
+	case NOT_LIKE:
+		return operand.NotLike(values[0]), nil
+	case STARTS_WITH:
+		return operand.Like(EscapeLikePattern(values[0]) + "%"), nil
+	case ENDS_WITH:
+		return operand.Like("%" + EscapeLikePattern(values[0])), nil
+	case CONTAINS:
+		return operand.Like("%" + EscapeLikePattern(values[0]) + "%"), nil
+	case REGEX:
+		return operand.RegexpLike(values[0]), nil
+	case IREGEX:
+		return operand.RegexpILike(values[0]), nil
+	case IS_NULL:
+		return operand.IsNull(), nil
+	case IS_NOT_NULL:
+		return operand.IsNotNull(), nil
+	}
+	return nil, fmt.Errorf("operator [%s] cannot be used with an SQL expression", info)
+}
+
+/* * * * * * *
+ * JSON Path Conditions: In-memory Evaluation
+ * * * * * * */
+
+// evaluateJSONCondition evaluates the JSON path condition against a JSON value, which can be a scalars.GenericData,
+// JSON text (string or []byte), or a Go value that is encoded to JSON.
+func evaluateJSONCondition(info OperatorInfo, op Operator, path string, values []interface{}, value interface{}) (bool, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return false, err
+	}
+	kind, err := validateJSONCondition(info, op, segments, values)
+	if err != nil {
+		return false, err
+	}
+
+	doc, isNull, err := decodeJSONValue(value)
+	if err != nil {
+		return false, err
+	}
+	if isNull {
+		// NULL->'a' is NULL
+		return op == IS_NULL, nil
+	}
+
+	if op == CONTAINS_ALL {
+		item, ok := getJSONPathItem(doc, segments)
+		if !ok {
+			return false, nil
+		}
+		target, _, err := decodeJSONValue(getJSONContainsTarget(values))
+		if err != nil {
+			return false, err
+		}
+		return jsonContains(item, target, true), nil
+	}
+
+	if hasJSONPathWildcard(segments) {
+		for _, item := range getJSONPathItemsLax(doc, segments) {
+			ok, err := evaluateJSONPathPredicate(op, values, item)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	item, ok := getJSONPathItem(doc, segments)
+	switch op {
+	case IS_NULL:
+		return !ok || item == nil, nil
+	case IS_NOT_NULL:
+		return ok && item != nil, nil
+	}
+	if !ok || item == nil {
+		return false, nil
+	}
+
+	// Get the value like the SQL does: as text, or cast to numeric or boolean if the JSON type matches
+	var v interface{}
+	switch kind {
+	case jsonValueKind_Numeric:
+		n, isNumber := item.(json.Number)
+		if !isNumber {
+			return false, nil
+		}
+		r, ok := new(big.Rat).SetString(string(n))
+		if !ok {
+			return false, fmt.Errorf("invalid JSON number [%s]", n)
+		}
+		v = r
+	case jsonValueKind_Boolean:
+		b, isBool := item.(bool)
+		if !isBool {
+			return false, nil
+		}
+		v = b
+	default:
+		v = getJSONText(item, true)
+	}
+	return evaluate(info, op, ArrayMatch_Any, values, v)
+}
+
+// decodeJSONValue decodes the value into generic JSON values, with numbers as json.Number.
+func decodeJSONValue(value interface{}) (interface{}, bool, error) {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil, true, nil
+	case scalars.GenericData:
+		if v.IsEmpty() {
+			return nil, true, nil
+		}
+		data = []byte(v.String())
+	case *scalars.GenericData:
+		if v == nil || v.IsEmpty() {
+			return nil, true, nil
+		}
+		data = []byte(v.String())
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return nil, false, fmt.Errorf("encoding [%T] as JSON: %w", value, err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var r interface{}
+	if err := dec.Decode(&r); err != nil {
+		return nil, false, fmt.Errorf("decoding JSON value: %w", err)
+	}
+	return r, false, nil
+}
+
+// getJSONPathItem follows the path like the `->` operator does.
+func getJSONPathItem(doc interface{}, segments []jsonPathSegment) (interface{}, bool) {
+	item := doc
+	for _, s := range segments {
+		switch v := item.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if item, ok = v[s.Key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			if !s.IsIndex {
+				return nil, false
+			}
+			i := s.Index
+			if i < 0 || i >= len(v) {
+				return nil, false
+			}
+			item = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return item, true
+}
+
+// getJSONPathItemsLax follows the path like SQL/JSON path does in lax mode: keys are looked up in each element of an
+// array, and `[*]` on a non-array returns the value itself.
+func getJSONPathItemsLax(doc interface{}, segments []jsonPathSegment) []interface{} {
+	items := []interface{}{doc}
+	for _, s := range segments {
+		var next []interface{}
+		for _, item := range items {
+			arr, isArray := item.([]interface{})
+			switch {
+			case s.Wildcard && isArray:
+				next = append(next, arr...)
+			case s.Wildcard:
+				next = append(next, item)
+			case s.IsIndex && isArray:
+				if s.Index < len(arr) {
+					next = append(next, arr[s.Index])
+				}
+			case s.IsIndex && s.Index == 0:
+				next = append(next, item)
+			case isArray:
+				for _, elem := range arr {
+					if obj, ok := elem.(map[string]interface{}); ok {
+						if v, ok := obj[s.Key]; ok {
+							next = append(next, v)
+						}
+					}
+				}
+			default:
+				if obj, ok := item.(map[string]interface{}); ok {
+					if v, ok := obj[s.Key]; ok {
+						next = append(next, v)
+					}
+				}
+			}
+		}
+		items = next
+	}
+	return items
+}
+
+// evaluateJSONPathPredicate evaluates the SQL/JSON path filter (see getJSONPathExistsQuery) for the item. Comparisons
+// are strict about types: a string is never equal to a number, and null is only equal to null. In lax mode, an array
+// item is unwrapped and matches if any element matches.
+func evaluateJSONPathPredicate(op Operator, values []interface{}, item interface{}) (bool, error) {
+	if arr, ok := item.([]interface{}); ok {
+		for _, elem := range arr {
+			ok, err := evaluateJSONPathPredicate(op, values, elem)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	switch op {
+	case IS_NULL:
+		return item == nil, nil
+	case IS_NOT_NULL:
+		return item != nil, nil
+	case IN:
+		for _, cv := range values {
+			ok, err := evaluateJSONPathPredicate(EQUAL, []interface{}{cv}, item)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	a, err := normalizeJSONPathValue(item)
+	if err != nil {
+		return false, err
+	}
+	b, err := normalizeValue(values[0])
+	if n, ok := values[0].(json.Number); ok {
+		b, err = normalizeJSONPathValue(n)
+	}
+	if err != nil {
+		return false, err
+	}
+	if a == nil || reflect.TypeOf(a) != reflect.TypeOf(b) {
+		// Comparison of different types is unknown, except with null
+		return a == nil && op == NOT_EQUAL, nil
+	}
+	if op == STARTS_WITH {
+		return strings.HasPrefix(a.(string), b.(string)), nil
+	}
+	c, err := compareValues(a, b)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case EQUAL:
+		return c == 0, nil
+	case NOT_EQUAL:
+		return c != 0, nil
+	case GREATER_THAN:
+		return c > 0, nil
+	case GREATER_THAN_EQUAL:
+		return c >= 0, nil
+	case LESS_THAN:
+		return c < 0, nil
+	case LESS_THAN_EQUAL:
+		return c <= 0, nil
+	}
+	return false, fmt.Errorf("operator [%s] cannot be used with a wildcard JSON path", op)
+}
+
+// normalizeJSONPathValue normalizes a decoded JSON scalar for comparison. Objects are not comparable and become nil.
+func normalizeJSONPathValue(item interface{}) (interface{}, error) {
+	switch v := item.(type) {
+	case json.Number:
+		r, ok := new(big.Rat).SetString(string(v))
+		if !ok {
+			return nil, fmt.Errorf("invalid JSON number [%s]", v)
+		}
+		return r, nil
+	case string, bool:
+		return v, nil
+	}
+	return nil, nil
+}
+
+// getJSONText returns the text of the JSON value like Postgres outputs a jsonb value. If top is true, strings are not
+// quoted, like with `->>`.
+func getJSONText(item interface{}, top bool) string {
+	switch v := item.(type) {
+	case nil:
+		return "null"
+	case string:
+		if top {
+			return v
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	case json.Number:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		elems := make([]string, len(v))
+		for i := range v {
+			elems[i] = getJSONText(v[i], false)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case map[string]interface{}:
+		// jsonb sorts the keys by length, and then bytes
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		elems := make([]string, len(keys))
+		for i, k := range keys {
+			elems[i] = getJSONText(k, false) + ": " + getJSONText(v[k], false)
+		}
+		return "{" + strings.Join(elems, ", ") + "}"
+	}
+	return fmt.Sprintf("%v", item)
+}
+
+// jsonContains reports whether a contains b, like the jsonb `@>` operator. At the top level, an array contains a
+// scalar that is one of its elements.
+func jsonContains(a, b interface{}, top bool) bool {
+	switch b := b.(type) {
+	case map[string]interface{}:
+		a, ok := a.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, bv := range b {
+			av, ok := a[k]
+			if !ok || !jsonContains(av, bv, false) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := a.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, bv := range b {
+			found := false
+			for _, av := range a {
+				if jsonContains(av, bv, false) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	if arr, ok := a.([]interface{}); ok && top {
+		for _, av := range arr {
+			if jsonContains(av, b, false) {
+				return true
+			}
+		}
+		return false
+	}
+	an, err := normalizeJSONPathValue(a)
+	if err != nil {
+		return false
+	}
+	bn, err := normalizeJSONPathValue(b)
+	if err != nil {
+		return false
+	}
+	if an == nil || bn == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(an) != reflect.TypeOf(bn) {
+		return false
+	}
+	c, err := compareValues(an, bn)
+	return err == nil && c == 0
+}
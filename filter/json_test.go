@@ -0,0 +1,214 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/teejays/gokutil/scalars"
+)
+
+func TestJSONPathCondition_SQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		cond     *JSONPathCondition
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "text EQUAL",
+			cond:     NewJSONPathCondition("address.city", EQUAL, "Paris"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col"->'address'->>'city' = $1)`,
+			wantArgs: []interface{}{"Paris"},
+		},
+		{
+			name:     "text ILIKE with array index",
+			cond:     NewJSONPathCondition("items[0].name", ILIKE, "pen"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col"->'items'->0->>'name' ILIKE $1)`,
+			wantArgs: []interface{}{"%pen%"},
+		},
+		{
+			name:     "all-digit key",
+			cond:     NewJSONPathCondition("years.2024", EQUAL, "ok"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col"->'years'->>'2024' = $1)`,
+			wantArgs: []interface{}{"ok"},
+		},
+		{
+			name:     "nested array indexes",
+			cond:     NewJSONPathCondition("[1].grid[0][2]", EQUAL, "x"),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col"->1->'grid'->0->>2 = $1)`,
+			wantArgs: []interface{}{"x"},
+		},
+		{
+			name:     "wildcard with array index",
+			cond:     NewJSONPathCondition("items.*.codes[0]", EQUAL, "a"),
+			wantSQL:  `SELECT * FROM "t" WHERE jsonb_path_exists("col", $1::jsonpath, $2::jsonb)`,
+			wantArgs: []interface{}{`$."items"[*]."codes"[0] ? (@ == $v0)`, `{"v0":"a"}`},
+		},
+		{
+			name:     "numeric GREATER_THAN",
+			cond:     NewJSONPathCondition("stats.score", GREATER_THAN, 10),
+			wantSQL:  `SELECT * FROM "t" WHERE (CASE WHEN jsonb_typeof("col"->'stats'->'score') = 'number' THEN ("col"->'stats'->>'score')::numeric END > $1)`,
+			wantArgs: []interface{}{int64(10)},
+		},
+		{
+			name:     "boolean EQUAL",
+			cond:     NewJSONPathCondition("verified", EQUAL, true),
+			wantSQL:  `SELECT * FROM "t" WHERE (CASE WHEN jsonb_typeof("col"->'verified') = 'boolean' THEN ("col"->>'verified')::boolean END IS TRUE)`,
+			wantArgs: []interface{}{},
+		},
+		{
+			name:     "IS_NULL",
+			cond:     NewJSONPathCondition("deleted_at", IS_NULL),
+			wantSQL:  `SELECT * FROM "t" WHERE ("col"->>'deleted_at' IS NULL)`,
+			wantArgs: []interface{}{},
+		},
+		{
+			name:     "CONTAINS_ALL object",
+			cond:     NewJSONPathCondition("address", CONTAINS_ALL, map[string]interface{}{"city": "Paris"}),
+			wantSQL:  `SELECT * FROM "t" WHERE "col"->'address' @> $1::jsonb`,
+			wantArgs: []interface{}{`{"city":"Paris"}`},
+		},
+		{
+			name:     "CONTAINS_ALL array elements",
+			cond:     NewJSONPathCondition("tags", CONTAINS_ALL, "a", "b"),
+			wantSQL:  `SELECT * FROM "t" WHERE "col"->'tags' @> $1::jsonb`,
+			wantArgs: []interface{}{`["a","b"]`},
+		},
+		{
+			name:     "wildcard IN",
+			cond:     NewJSONPathCondition("items.*.sku", IN, "a", "b"),
+			wantSQL:  `SELECT * FROM "t" WHERE jsonb_path_exists("col", $1::jsonpath, $2::jsonb)`,
+			wantArgs: []interface{}{`$."items"[*]."sku" ? (@ == $v0 || @ == $v1)`, `{"v0":"a","v1":"b"}`},
+		},
+		{
+			name:    "invalid path",
+			cond:    NewJSONPathCondition("address.'city", EQUAL, "Paris"),
+			wantErr: true,
+		},
+		{
+			name:    "empty segment",
+			cond:    NewJSONPathCondition("address..city", EQUAL, "Paris"),
+			wantErr: true,
+		},
+		{
+			name:    "unclosed array index",
+			cond:    NewJSONPathCondition("items[0.sku", EQUAL, "a"),
+			wantErr: true,
+		},
+		{
+			name:    "LIKE with a number",
+			cond:    NewJSONPathCondition("score", LIKE, 1),
+			wantErr: true,
+		},
+		{
+			name:    "mixed value types",
+			cond:    NewJSONPathCondition("score", IN, 1, "a"),
+			wantErr: true,
+		},
+		{
+			name:    "wildcard LIKE",
+			cond:    NewJSONPathCondition("items.*.sku", LIKE, "a"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sb, err := InjectConditionIntoSqlBuilder(tt.cond, goqu.Dialect("postgres").From("t").Prepared(true), "col", false)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			sql, args, err := sb.ToSQL()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestJSONPathCondition_Evaluate(t *testing.T) {
+	data, err := scalars.NewGenericDataFromStruct(map[string]interface{}{
+		"address":  map[string]interface{}{"city": "Paris", "zip": "75001"},
+		"score":    12.5,
+		"verified": true,
+		"note":     nil,
+		"tags":     []string{"a", "b"},
+		"items":    []interface{}{map[string]interface{}{"sku": "x1", "qty": 2}, map[string]interface{}{"sku": "y2", "qty": 5}},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		cond  *JSONPathCondition
+		want  bool
+	}{
+		{"text EQUAL", data, NewJSONPathCondition("address.city", EQUAL, "Paris"), true},
+		{"text ILIKE", data, NewJSONPathCondition("address.city", ILIKE, "AR"), true},
+		{"text of a number", data, NewJSONPathCondition("score", EQUAL, "12.5"), true},
+		{"numeric GREATER_THAN", data, NewJSONPathCondition("score", GREATER_THAN, 12), true},
+		{"numeric against a string", data, NewJSONPathCondition("address.zip", GREATER_THAN, 1), false},
+		{"numeric from JSON", data, NewJSONPathCondition("score", BETWEEN, 12.0, 13.0), true},
+		{"boolean EQUAL", data, NewJSONPathCondition("verified", EQUAL, true), true},
+		{"boolean against a string", data, NewJSONPathCondition("address.city", EQUAL, true), false},
+		{"IS_NULL missing", data, NewJSONPathCondition("address.country", IS_NULL), true},
+		{"IS_NULL JSON null", data, NewJSONPathCondition("note", IS_NULL), true},
+		{"IS_NOT_NULL", data, NewJSONPathCondition("address.city", IS_NOT_NULL), true},
+		{"array index", data, NewJSONPathCondition("items[1].sku", EQUAL, "y2"), true},
+		{"all-digit key on an array", data, NewJSONPathCondition("items.1.sku", EQUAL, "y2"), false},
+		{"NOT_EQUAL missing", data, NewJSONPathCondition("address.country", NOT_EQUAL, "FR"), false},
+		{"CONTAINS_ALL object", data, NewJSONPathCondition("address", CONTAINS_ALL, map[string]interface{}{"city": "Paris"}), true},
+		{"CONTAINS_ALL array elements", data, NewJSONPathCondition("tags", CONTAINS_ALL, "b", "a"), true},
+		{"CONTAINS_ALL missing element", data, NewJSONPathCondition("tags", CONTAINS_ALL, "a", "c"), false},
+		{"wildcard EQUAL", data, NewJSONPathCondition("items.*.sku", EQUAL, "y2"), true},
+		{"wildcard GREATER_THAN", data, NewJSONPathCondition("items.*.qty", GREATER_THAN, 4), true},
+		{"wildcard type mismatch", data, NewJSONPathCondition("items.*.qty", EQUAL, "5"), false},
+		{"wildcard STARTS_WITH", data, NewJSONPathCondition("tags.*", STARTS_WITH, "b"), true},
+		{"NULL column", scalars.GenericData{}, NewJSONPathCondition("address.city", EQUAL, "Paris"), false},
+		{"NULL column IS_NULL", scalars.GenericData{}, NewJSONPathCondition("address.city", IS_NULL), true},
+		{"JSON text", `{"a": {"b": 1}}`, NewJSONPathCondition("a.b", EQUAL, 1), true},
+		{"JSON text of an object", `{"a": {"bb": 1, "c": [true, null]}}`, NewJSONPathCondition("a", EQUAL, `{"c": [true, null], "bb": 1}`), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.cond, tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestJSONPathCondition_NumericKey checks that the SQL and Evaluate agree on paths with all-digit segments, which are
+// object keys unless they are in brackets.
+func TestJSONPathCondition_NumericKey(t *testing.T) {
+	data := `{"years": {"2024": "ok"}, "list": ["a", "ok"]}`
+
+	tests := []struct {
+		path    string
+		wantSQL string
+		want    bool
+	}{
+		{"years.2024", `("col"->'years'->>'2024' = $1)`, true},
+		{"years[2024]", `("col"->'years'->>2024 = $1)`, false},
+		{"list.1", `("col"->'list'->>'1' = $1)`, false},
+		{"list[1]", `("col"->'list'->>1 = $1)`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			cond := NewJSONPathCondition(tt.path, EQUAL, "ok")
+			sb, err := InjectConditionIntoSqlBuilder(cond, goqu.Dialect("postgres").From("t").Prepared(true), "col", false)
+			assert.NoError(t, err)
+			sql, _, err := sb.ToSQL()
+			assert.NoError(t, err)
+			assert.Equal(t, `SELECT * FROM "t" WHERE `+tt.wantSQL, sql)
+
+			got, err := Evaluate(cond, data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
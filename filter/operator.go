@@ -3,9 +3,12 @@ package filter
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/huandu/go-sqlbuilder"
+	"github.com/lib/pq"
 	"github.com/teejays/gokutil/panics"
 )
 
@@ -25,6 +28,24 @@ const (
 	NOT_LIKE
 	IS_NULL
 	IS_NOT_NULL
+	BETWEEN
+	NOT_BETWEEN
+	NOT_IN
+	STARTS_WITH
+	ENDS_WITH
+	CONTAINS
+	REGEX
+	IREGEX
+	CONTAINS_ALL
+	CONTAINS_ANY
+	OVERLAPS
+	CONTAINED_BY
+	WITHIN_LAST
+	OLDER_THAN
+	TODAY
+	THIS_WEEK
+	THIS_MONTH
+	THIS_YEAR
 )
 
 // OperatorsList is a list of all the operators. It allows us to iterate over all the operators.
@@ -41,6 +62,24 @@ var OperatorsList = []Operator{
 	NOT_LIKE,
 	IS_NULL,
 	IS_NOT_NULL,
+	BETWEEN,
+	NOT_BETWEEN,
+	NOT_IN,
+	STARTS_WITH,
+	ENDS_WITH,
+	CONTAINS,
+	REGEX,
+	IREGEX,
+	CONTAINS_ALL,
+	CONTAINS_ANY,
+	OVERLAPS,
+	CONTAINED_BY,
+	WITHIN_LAST,
+	OLDER_THAN,
+	TODAY,
+	THIS_WEEK,
+	THIS_MONTH,
+	THIS_YEAR,
 }
 
 func (o *Operator) UnmarshalJSON(data []byte) error {
@@ -56,6 +95,18 @@ func (f Operator) MarshalJSON() ([]byte, error) {
 	return strconv.AppendQuote(nil, f.String()), nil
 }
 
+func (o *Operator) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var str string
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	return o.FromString(str)
+}
+
+func (f Operator) MarshalYAML() (interface{}, error) {
+	return f.String(), nil
+}
+
 func (o Operator) String() string {
 	switch o {
 	case EQUAL:
@@ -82,6 +133,42 @@ func (o Operator) String() string {
 		return "IS_NULL"
 	case IS_NOT_NULL:
 		return "IS_NOT_NULL"
+	case BETWEEN:
+		return "BETWEEN"
+	case NOT_BETWEEN:
+		return "NOT_BETWEEN"
+	case NOT_IN:
+		return "NOT_IN"
+	case STARTS_WITH:
+		return "STARTS_WITH"
+	case ENDS_WITH:
+		return "ENDS_WITH"
+	case CONTAINS:
+		return "CONTAINS"
+	case REGEX:
+		return "REGEX"
+	case IREGEX:
+		return "IREGEX"
+	case CONTAINS_ALL:
+		return "CONTAINS_ALL"
+	case CONTAINS_ANY:
+		return "CONTAINS_ANY"
+	case OVERLAPS:
+		return "OVERLAPS"
+	case CONTAINED_BY:
+		return "CONTAINED_BY"
+	case WITHIN_LAST:
+		return "WITHIN_LAST"
+	case OLDER_THAN:
+		return "OLDER_THAN"
+	case TODAY:
+		return "TODAY"
+	case THIS_WEEK:
+		return "THIS_WEEK"
+	case THIS_MONTH:
+		return "THIS_MONTH"
+	case THIS_YEAR:
+		return "THIS_YEAR"
 	default:
 		panics.P("Unrecognized Filter Operator [%d]", o)
 	}
@@ -115,6 +202,42 @@ func (o *Operator) FromString(str string) error {
 		*o = IS_NULL
 	case "IS_NOT_NULL":
 		*o = IS_NOT_NULL
+	case "BETWEEN":
+		*o = BETWEEN
+	case "NOT_BETWEEN":
+		*o = NOT_BETWEEN
+	case "NOT_IN":
+		*o = NOT_IN
+	case "STARTS_WITH":
+		*o = STARTS_WITH
+	case "ENDS_WITH":
+		*o = ENDS_WITH
+	case "CONTAINS":
+		*o = CONTAINS
+	case "REGEX":
+		*o = REGEX
+	case "IREGEX":
+		*o = IREGEX
+	case "CONTAINS_ALL":
+		*o = CONTAINS_ALL
+	case "CONTAINS_ANY":
+		*o = CONTAINS_ANY
+	case "OVERLAPS":
+		*o = OVERLAPS
+	case "CONTAINED_BY":
+		*o = CONTAINED_BY
+	case "WITHIN_LAST":
+		*o = WITHIN_LAST
+	case "OLDER_THAN":
+		*o = OLDER_THAN
+	case "TODAY":
+		*o = TODAY
+	case "THIS_WEEK":
+		*o = THIS_WEEK
+	case "THIS_MONTH":
+		*o = THIS_MONTH
+	case "THIS_YEAR":
+		*o = THIS_YEAR
 	default:
 		return fmt.Errorf("unrecognized filter operator [%s]", str)
 	}
@@ -153,13 +276,18 @@ type OperatorInfo struct {
 	TypescriptSign string
 
 	InjectSqlBuilderWhereCond_Huandu func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error
-	InjectSqlBuilderWhereCond_Goqu   func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset
+	InjectSqlBuilderWhereCond_Goqu   func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset // set from GoquExpression
+	// GoquExpression returns the where condition for the column and values. It returns nil if there is no condition to add.
+	GoquExpression func(col string, values ...interface{}) exp.Expression
 
 	ValuesType ValuesType
 	// DisallowValues      bool
 	// AllowMultipleValues bool
 	MultipleValuesMin int // When AllowMultipleValues is true, this is the min number of values
 	MultipleValuesMax int // When AllowMultipleValues is true, this is the max number of values
+	// IsArrayOperator is true for operators that compare a whole SQL array column with the values (e.g. `@>`),
+	// rather than an element of the column.
+	IsArrayOperator bool
 }
 
 type ValuesType uint8
@@ -181,6 +309,12 @@ func (t OperatorInfo) GetSqlSign() string {
 	}
 	return t.Sign
 }
+func (t OperatorInfo) GetTypescriptSign() string {
+	if t.TypescriptSign != "" {
+		return t.TypescriptSign
+	}
+	return t.Sign
+}
 func (t OperatorInfo) GetSqlFormatString() string {
 	if t.SqlSign != "" {
 		return t.SqlSign
@@ -198,28 +332,30 @@ func (t OperatorInfo) GetSqlFormatString() string {
 
 var store = map[Operator]OperatorInfo{
 	EQUAL: {
-		Name:       "EQUAL",
-		Sign:       "==",
-		SqlSign:    "=",
-		ValuesType: ValuesType_One,
+		Name:           "EQUAL",
+		Sign:           "==",
+		SqlSign:        "=",
+		TypescriptSign: "===",
+		ValuesType:     ValuesType_One,
 		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
 			sb.Where(sb.Equal(col, values[0]))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).Eq(values[0]))
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Eq(values[0])
 		},
 	},
 	NOT_EQUAL: {
-		Name:       "NOT_EQUAL",
-		Sign:       "!=",
-		ValuesType: ValuesType_Zero,
+		Name:           "NOT_EQUAL",
+		Sign:           "!=",
+		TypescriptSign: "!==",
+		ValuesType:     ValuesType_One,
 		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
 			sb.Where(sb.NotEqual(col, values[0]))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).Neq(values[0]))
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Neq(values[0])
 		},
 	},
 	IN: {
@@ -235,11 +371,11 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.In(col, values...))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
 			if len(values) < 1 {
-				return sb
+				return nil
 			}
-			return sb.Where(goqu.C(col).In(values))
+			return goqu.C(col).In(values)
 		},
 	},
 	GREATER_THAN: {
@@ -250,8 +386,8 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.GreaterThan(col, values[0]))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).Gt(values[0]))
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Gt(values[0])
 		},
 	},
 	GREATER_THAN_EQUAL: {
@@ -262,8 +398,8 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.GreaterEqualThan(col, values[0]))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).Gte(values[0]))
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Gte(values[0])
 		},
 	},
 	LESS_THAN: {
@@ -274,8 +410,8 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.LessThan(col, values[0]))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).Lt(values[0]))
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Lt(values[0])
 		},
 	},
 	LESS_THAN_EQUAL: {
@@ -286,8 +422,8 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.LessEqualThan(col, values[0]))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).Lte(values[0]))
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Lte(values[0])
 		},
 	},
 	LIKE: {
@@ -299,8 +435,8 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.Like(col, values[0]))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).Like(fmt.Sprintf("%%%s%%", values[0]))) // `%%` results in `%`
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Like(fmt.Sprintf("%%%s%%", values[0])) // `%%` results in `%`
 		},
 	},
 	ILIKE: {
@@ -312,8 +448,8 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.Like(col, values[0])) // No ILike implemented?
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).Cast("text").ILike(fmt.Sprintf("%%%s%%", values[0]))) // `%%` results in `%`
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Cast("text").ILike(fmt.Sprintf("%%%s%%", values[0])) // `%%` results in `%`
 		},
 	},
 	NOT_LIKE: {
@@ -325,8 +461,8 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.NotLike(col, values[0]))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).NotLike(values[0]))
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).NotLike(values[0])
 		},
 	},
 	IS_NULL: {
@@ -338,8 +474,8 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.IsNull(col))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).IsNull())
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).IsNull()
 		},
 	},
 	IS_NOT_NULL: {
@@ -351,10 +487,233 @@ var store = map[Operator]OperatorInfo{
 			sb.Where(sb.IsNotNull(col))
 			return nil
 		},
-		InjectSqlBuilderWhereCond_Goqu: func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
-			return sb.Where(goqu.C(col).IsNotNull())
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).IsNotNull()
+		},
+	},
+	BETWEEN: {
+		Name:       "BETWEEN",
+		SqlSign:    "BETWEEN",
+		ValuesType: ValuesType_Two,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(sb.Between(col, values[0], values[1]))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Between(exp.NewRangeVal(values[0], values[1]))
+		},
+	},
+	NOT_BETWEEN: {
+		Name:       "NOT_BETWEEN",
+		SqlSign:    "NOT BETWEEN",
+		ValuesType: ValuesType_Two,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(sb.NotBetween(col, values[0], values[1]))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).NotBetween(exp.NewRangeVal(values[0], values[1]))
+		},
+	},
+	NOT_IN: {
+		Name:              "NOT_IN",
+		SqlSign:           "NOT IN",
+		ValuesType:        ValuesType_Multiple,
+		MultipleValuesMin: 1,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			if len(values) < 1 {
+				return fmt.Errorf("NOT_IN operator requires at least one value")
+			}
+			sb.Where(sb.NotIn(col, values...))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			if len(values) < 1 {
+				return nil
+			}
+			return goqu.C(col).NotIn(values)
+		},
+	},
+	STARTS_WITH: {
+		Name:       "STARTS_WITH",
+		SqlSign:    "LIKE",
+		ValuesType: ValuesType_One,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(sb.Like(col, EscapeLikePattern(values[0])+"%"))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Like(EscapeLikePattern(values[0]) + "%")
+		},
+	},
+	ENDS_WITH: {
+		Name:       "ENDS_WITH",
+		SqlSign:    "LIKE",
+		ValuesType: ValuesType_One,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(sb.Like(col, "%"+EscapeLikePattern(values[0])))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Like("%" + EscapeLikePattern(values[0]))
+		},
+	},
+	CONTAINS: {
+		Name:       "CONTAINS",
+		SqlSign:    "LIKE",
+		ValuesType: ValuesType_One,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(sb.Like(col, "%"+EscapeLikePattern(values[0])+"%"))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Like("%" + EscapeLikePattern(values[0]) + "%")
+		},
+	},
+	REGEX: {
+		Name:       "REGEX",
+		SqlSign:    "~",
+		ValuesType: ValuesType_One,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(fmt.Sprintf("%s ~ %s", sb.Var(sqlbuilder.Raw(col)), sb.Var(values[0])))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).RegexpLike(values[0])
+		},
+	},
+	IREGEX: {
+		Name:       "IREGEX",
+		SqlSign:    "~*",
+		ValuesType: ValuesType_One,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(fmt.Sprintf("%s ~* %s", sb.Var(sqlbuilder.Raw(col)), sb.Var(values[0])))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).RegexpILike(values[0])
 		},
 	},
+	CONTAINS_ALL: {
+		Name:              "CONTAINS_ALL",
+		SqlSign:           "@>",
+		ValuesType:        ValuesType_Multiple,
+		MultipleValuesMin: 1,
+		IsArrayOperator:   true,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(fmt.Sprintf("%s @> %s", sb.Var(sqlbuilder.Raw(col)), sb.Var(pq.Array(values))))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.L("? @> ?", goqu.C(col), pq.Array(values))
+		},
+	},
+	CONTAINS_ANY: {
+		Name:              "CONTAINS_ANY",
+		SqlSign:           "&&",
+		ValuesType:        ValuesType_Multiple,
+		MultipleValuesMin: 1,
+		IsArrayOperator:   true,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(fmt.Sprintf("%s && %s", sb.Var(sqlbuilder.Raw(col)), sb.Var(pq.Array(values))))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.L("? && ?", goqu.C(col), pq.Array(values))
+		},
+	},
+	// OVERLAPS is the same as CONTAINS_ANY, named after the SQL `&&` (overlap) operator.
+	OVERLAPS: {
+		Name:              "OVERLAPS",
+		SqlSign:           "&&",
+		ValuesType:        ValuesType_Multiple,
+		MultipleValuesMin: 1,
+		IsArrayOperator:   true,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(fmt.Sprintf("%s && %s", sb.Var(sqlbuilder.Raw(col)), sb.Var(pq.Array(values))))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.L("? && ?", goqu.C(col), pq.Array(values))
+		},
+	},
+	// CONTAINED_BY matches arrays whose elements are all among the values.
+	CONTAINED_BY: {
+		Name:              "CONTAINED_BY",
+		SqlSign:           "<@",
+		ValuesType:        ValuesType_Multiple,
+		MultipleValuesMin: 1,
+		IsArrayOperator:   true,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			sb.Where(fmt.Sprintf("%s <@ %s", sb.Var(sqlbuilder.Raw(col)), sb.Var(pq.Array(values))))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.L("? <@ ?", goqu.C(col), pq.Array(values))
+		},
+	},
+	// Relative time operators take no values (WITHIN_LAST and OLDER_THAN take a duration instead), and are resolved
+	// against the current time into absolute values before their SQL is generated (see TimeConfig).
+	// Their huandu injectors expect the resolved values, which InjectConditionIntoHuanduSqlBuilder passes.
+	WITHIN_LAST: {
+		Name:       "WITHIN_LAST",
+		ValuesType: ValuesType_Zero,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			if err := checkResolvedTimeValues("WITHIN_LAST", 2, values); err != nil {
+				return err
+			}
+			sb.Where(sb.GreaterEqualThan(col, values[0]), sb.LessEqualThan(col, values[1]))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.And(goqu.C(col).Gte(values[0]), goqu.C(col).Lte(values[1]))
+		},
+	},
+	OLDER_THAN: {
+		Name:       "OLDER_THAN",
+		ValuesType: ValuesType_Zero,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			if err := checkResolvedTimeValues("OLDER_THAN", 1, values); err != nil {
+				return err
+			}
+			sb.Where(sb.LessThan(col, values[0]))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.C(col).Lt(values[0])
+		},
+	},
+	TODAY:      newTimeRangeOperatorInfo("TODAY"),
+	THIS_WEEK:  newTimeRangeOperatorInfo("THIS_WEEK"),
+	THIS_MONTH: newTimeRangeOperatorInfo("THIS_MONTH"),
+	THIS_YEAR:  newTimeRangeOperatorInfo("THIS_YEAR"),
+}
+
+// newTimeRangeOperatorInfo returns the info for a relative time operator that is resolved into a [start, end) range.
+func newTimeRangeOperatorInfo(name string) OperatorInfo {
+	return OperatorInfo{
+		Name:       name,
+		ValuesType: ValuesType_Zero,
+		InjectSqlBuilderWhereCond_Huandu: func(sb *sqlbuilder.SelectBuilder, col string, values ...interface{}) error {
+			if err := checkResolvedTimeValues(name, 2, values); err != nil {
+				return err
+			}
+			sb.Where(sb.GreaterEqualThan(col, values[0]), sb.LessThan(col, values[1]))
+			return nil
+		},
+		GoquExpression: func(col string, values ...interface{}) exp.Expression {
+			return goqu.And(goqu.C(col).Gte(values[0]), goqu.C(col).Lt(values[1]))
+		},
+	}
+}
+
+// checkResolvedTimeValues returns an error if a relative time operator is given the values of the condition (which
+// are none) rather than the absolute times it is resolved into.
+func checkResolvedTimeValues(name string, n int, values []interface{}) error {
+	if len(values) != n {
+		return fmt.Errorf("operator [%s] expects the [%d] times it is resolved into but [%d] values provided: use InjectConditionIntoHuanduSqlBuilder", name, n, len(values))
+	}
+	return nil
 }
 
 // func singleSqlCondition(col, sign, d string, values ...interface{}) string {
@@ -371,6 +730,23 @@ var store = map[Operator]OperatorInfo{
 // 	return multipleConds
 // }
 
+func init() {
+	for op, info := range store {
+		info.InjectSqlBuilderWhereCond_Goqu = newGoquInjector(info.GoquExpression)
+		store[op] = info
+	}
+}
+
+func newGoquInjector(fn func(col string, values ...interface{}) exp.Expression) func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
+	return func(sb *goqu.SelectDataset, col string, values ...interface{}) *goqu.SelectDataset {
+		e := fn(col, values...)
+		if e == nil {
+			return sb
+		}
+		return sb.Where(e)
+	}
+}
+
 func getOperatorInfo(op Operator) (OperatorInfo, error) {
 	if info, exists := store[op]; exists {
 		return info, nil
@@ -382,9 +758,20 @@ func GetOperatorInfo(op Operator) (OperatorInfo, error) {
 	return getOperatorInfo(op)
 }
 
+// EscapeLikePattern escapes the LIKE wildcards (`%` and `_`) and the escape character (`\`) in the value, so that it
+// is matched literally.
+func EscapeLikePattern(v interface{}) string {
+	return likePatternEscaper.Replace(fmt.Sprintf("%v", v))
+}
+
+var likePatternEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 // GetRawSQLConditionForArrayColumn returns the raw SQL which is a single where condition (with the WHERE keyword)
 // for comparing a array SQL column with a value.
 // Assume that the condition is already validated
+//
+// Deprecated: the value is on the left side of the operator and only a single value is supported, so this is only
+// correct for EQUAL. Use GetConditionExpression with isColArray instead.
 func GetRawSQLConditionForArrayColumn(oi OperatorInfo, col string) string {
 	// <value> = ANY("<column>")
 	return fmt.Sprintf(`? %s ANY("%s")`, oi.GetSqlSign(), col)
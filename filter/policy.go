@@ -0,0 +1,143 @@
+package filter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/teejays/gokutil/errutil"
+)
+
+/* * * * * * *
+ * Policy
+ * * * * * * */
+
+// Policy limits what a client supplied filter can ask of the database. A zero value for any of the limits means that
+// there is no limit. Violations are returned as GokuErrors with a 400 HTTP status.
+type Policy struct {
+	// MaxInValues caps the number of values for operators that take a list e.g. IN, NOT_IN, CONTAINS_ANY.
+	MaxInValues int
+	// MaxDepth caps how deeply and/or/not expressions can be nested. A single condition has a depth of 0.
+	MaxDepth int
+	// MaxConditions caps the total number of conditions in an expression.
+	MaxConditions int
+	// MinLikePatternLength is the min number of characters (excluding the `%` and `_` wildcards) in the pattern of a
+	// LIKE, ILIKE or NOT_LIKE condition.
+	MinLikePatternLength int
+	// ColumnOperators restricts the operators allowed for a column. Columns that are not in the map can use any operator.
+	ColumnOperators map[string][]Operator
+}
+
+var policy Policy
+var policyLock sync.RWMutex
+
+// SetPolicy sets the policy that is enforced by InjectConditionIntoSqlBuilder and InjectExprIntoSqlBuilder.
+func SetPolicy(p Policy) {
+	policyLock.Lock()
+	defer policyLock.Unlock()
+	policy = p
+}
+
+func GetPolicy() Policy {
+	policyLock.RLock()
+	defer policyLock.RUnlock()
+	return policy
+}
+
+// AllowOperators restricts the column to the given operators.
+func (p *Policy) AllowOperators(col string, ops ...Operator) {
+	if p.ColumnOperators == nil {
+		p.ColumnOperators = map[string][]Operator{}
+	}
+	p.ColumnOperators[col] = ops
+}
+
+// CheckCondition checks a single condition on the column against the policy.
+func (p Policy) CheckCondition(col string, f Condition) error {
+	if f == nil {
+		return nil
+	}
+	op := f.GetOperator()
+
+	if allowed, ok := p.ColumnOperators[col]; ok && !containsOperator(allowed, op) {
+		return newPolicyError("operator [%s] is not allowed for [%s]", op, col)
+	}
+
+	info, err := getOperatorInfo(op)
+	if err != nil {
+		// Invalid operators are reported when the condition is validated
+		return nil
+	}
+
+	if p.MaxInValues > 0 && info.ValuesType == ValuesType_Multiple && f.Len() > p.MaxInValues {
+		return newPolicyError("operator [%s] for [%s] has [%d] values, but a max of [%d] is allowed", op, col, f.Len(), p.MaxInValues)
+	}
+
+	if p.MinLikePatternLength > 0 && (op == LIKE || op == ILIKE || op == NOT_LIKE) {
+		for i := 0; i < f.Len(); i++ {
+			pattern := fmt.Sprint(f.GetValue(i))
+			n := utf8.RuneCountInString(strings.NewReplacer("%", "", "_", "").Replace(pattern))
+			if n < p.MinLikePatternLength {
+				return newPolicyError("pattern [%s] for [%s] is too short, it should have at least [%d] characters", pattern, col, p.MinLikePatternLength)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckExpr checks the expression tree, and each of its conditions, against the policy.
+func (p Policy) CheckExpr(e Expr) error {
+	if p.MaxDepth > 0 {
+		if depth := e.Depth(); depth > p.MaxDepth {
+			return newPolicyError("filter is nested [%d] levels deep, but a max of [%d] is allowed", depth, p.MaxDepth)
+		}
+	}
+
+	var leaves []Expr
+	e.Walk(func(leaf Expr) { leaves = append(leaves, leaf) })
+	if p.MaxConditions > 0 && len(leaves) > p.MaxConditions {
+		return newPolicyError("filter has [%d] conditions, but a max of [%d] is allowed", len(leaves), p.MaxConditions)
+	}
+
+	for _, leaf := range leaves {
+		if err := p.CheckCondition(leaf.Column, leaf.Condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InjectConditionIntoSqlBuilder is like the package level InjectConditionIntoSqlBuilder, but enforces p instead of
+// the policy set using SetPolicy.
+func (p Policy) InjectConditionIntoSqlBuilder(f Condition, sb *goqu.SelectDataset, col string, isColArray bool) (*goqu.SelectDataset, error) {
+	if err := p.CheckCondition(col, f); err != nil {
+		return nil, err
+	}
+	return injectConditionIntoSqlBuilder(f, sb, col, isColArray)
+}
+
+// InjectExprIntoSqlBuilder is like the package level InjectExprIntoSqlBuilder, but enforces p instead of the policy
+// set using SetPolicy.
+func (p Policy) InjectExprIntoSqlBuilder(e Expr, sb *goqu.SelectDataset) (*goqu.SelectDataset, error) {
+	if err := p.CheckExpr(e); err != nil {
+		return nil, err
+	}
+	return injectExprIntoSqlBuilder(e, sb)
+}
+
+func containsOperator(ops []Operator, op Operator) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func newPolicyError(msg string, args ...interface{}) error {
+	return errutil.NewGerror(msg, args...).SetHTTPStatus(http.StatusBadRequest)
+}
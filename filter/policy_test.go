@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/errutil"
+)
+
+func TestPolicy_CheckCondition(t *testing.T) {
+	p := Policy{
+		MaxInValues:          3,
+		MinLikePatternLength: 3,
+	}
+	p.AllowOperators("id", EQUAL, IN)
+
+	tests := []struct {
+		name    string
+		col     string
+		cond    Condition
+		wantErr bool
+	}{
+		{"IN within the cap", "name", NewStringCondition(IN, "a", "b", "c"), false},
+		{"IN over the cap", "name", NewStringCondition(IN, "a", "b", "c", "d"), true},
+		{"NOT_IN over the cap", "name", NewStringCondition(NOT_IN, "a", "b", "c", "d"), true},
+		{"BETWEEN is not a list", "age", NewNumberCondition(BETWEEN, 1, 2), false},
+		{"ILIKE long enough", "name", NewStringCondition(ILIKE, "abc"), false},
+		{"ILIKE too short", "name", NewStringCondition(ILIKE, "ab"), true},
+		{"LIKE wildcards are not counted", "name", NewStringCondition(LIKE, "a%_b"), true},
+		{"LIKE counts characters not bytes", "name", NewStringCondition(LIKE, "éüö"), false},
+		{"STARTS_WITH is not limited", "name", NewStringCondition(STARTS_WITH, "a"), false},
+		{"allowed operator", "id", NewStringCondition(EQUAL, "x"), false},
+		{"operator not allowed", "id", NewStringCondition(ILIKE, "abcdef"), true},
+		{"JSON path condition", "data", NewJSONPathCondition("a.b", IN, 1, 2, 3, 4), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.CheckCondition(tt.col, tt.cond)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			gerr, ok := errutil.AsGokuError(err)
+			assert.True(t, ok)
+			assert.Equal(t, http.StatusBadRequest, gerr.GetHTTPStatus())
+		})
+	}
+}
+
+func TestPolicy_CheckExpr(t *testing.T) {
+	p := Policy{MaxDepth: 2, MaxConditions: 3}
+	p.AllowOperators("status", EQUAL)
+
+	leaf := Where("age", NewNumberCondition(GREATER_THAN, 18))
+	tests := []struct {
+		name    string
+		expr    Expr
+		wantErr bool
+	}{
+		{"leaf", leaf, false},
+		{"at the max depth", And(leaf, Or(leaf, leaf)), false},
+		{"too deep", And(Or(Not(leaf))), true},
+		{"too many conditions", Or(leaf, leaf, leaf, leaf), true},
+		{"operator not allowed in a leaf", And(leaf, Where("status", NewStringCondition(IN, "a"))), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.CheckExpr(tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestInjectConditionIntoSqlBuilder_Policy(t *testing.T) {
+	defer SetPolicy(GetPolicy())
+	SetPolicy(Policy{MaxInValues: 2})
+
+	sb := goqu.Dialect("postgres").From("t").Prepared(true)
+	_, err := InjectConditionIntoSqlBuilder(NewStringCondition(IN, "a", "b", "c"), sb, "name", false)
+	assert.Error(t, err)
+
+	_, err = InjectExprIntoSqlBuilder(Where("name", NewStringCondition(IN, "a", "b", "c")), sb)
+	assert.Error(t, err)
+
+	sb, err = InjectConditionIntoSqlBuilder(NewStringCondition(IN, "a", "b"), sb, "name", false)
+	assert.NoError(t, err)
+	sql, _, err := sb.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "t" WHERE ("name" IN ($1, $2))`, sql)
+}
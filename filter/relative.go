@@ -0,0 +1,241 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teejays/gokutil/scalars"
+)
+
+/* * * * * * *
+ * Relative Time
+ * * * * * * */
+
+// TimeConfig is used to resolve the relative time operators (e.g. WITHIN_LAST, THIS_MONTH) into absolute times, when
+// the query is built or the condition is evaluated.
+type TimeConfig struct {
+	// Now returns the current time. Defaults to scalars.NewTimestampNow, which respects GOKUTIL_TIMESTAMP_NOW.
+	Now func() time.Time
+	// Location is the timezone in which days, weeks, months and years start. Defaults to UTC.
+	Location *time.Location
+	// WeekStart is the first day of the week for THIS_WEEK.
+	WeekStart time.Weekday
+}
+
+var timeConfig = TimeConfig{
+	Now:       func() time.Time { return scalars.NewTimestampNow().ToGolangTime() },
+	Location:  time.UTC,
+	WeekStart: time.Monday,
+}
+var timeConfigLock sync.RWMutex
+
+// SetTimeConfig sets the config used to resolve relative time conditions. Now and Location are defaulted if not set.
+func SetTimeConfig(c TimeConfig) {
+	if c.Now == nil {
+		c.Now = func() time.Time { return scalars.NewTimestampNow().ToGolangTime() }
+	}
+	if c.Location == nil {
+		c.Location = time.UTC
+	}
+	timeConfigLock.Lock()
+	defer timeConfigLock.Unlock()
+	timeConfig = c
+}
+
+func GetTimeConfig() TimeConfig {
+	timeConfigLock.RLock()
+	defer timeConfigLock.RUnlock()
+	return timeConfig
+}
+
+// IsRelativeTimeOperator returns true for the operators that are resolved against the current time.
+func IsRelativeTimeOperator(op Operator) bool {
+	switch op {
+	case WITHIN_LAST, OLDER_THAN, TODAY, THIS_WEEK, THIS_MONTH, THIS_YEAR:
+		return true
+	}
+	return false
+}
+
+// DurationCondition is a Condition that can carry a duration, for the WITHIN_LAST and OLDER_THAN operators.
+type DurationCondition interface {
+	Condition
+	GetDuration() string
+}
+
+// NewDurationCondition returns a WITHIN_LAST or OLDER_THAN condition e.g.
+// NewDurationCondition[scalars.Timestamp](WITHIN_LAST, "7d").
+func NewDurationCondition[T comparable](op Operator, duration string) *GenericCondition[T] {
+	return &GenericCondition[T]{Op: op, Duration: duration}
+}
+
+// RelativeDuration is a duration with calendar units, e.g. `7d` or `1y6mo`. Calendar units are added in the timezone
+// of the time, so a day is not always 24 hours.
+type RelativeDuration struct {
+	Years    int
+	Months   int
+	Days     int
+	Duration time.Duration
+}
+
+// ParseRelativeDuration parses a sequence of numbers with units: `y` (years), `mo` (months), `w` (weeks), `d` (days),
+// `h` (hours), `m` (minutes) and `s` (seconds), e.g. `7d`, `2w`, `1y6mo` or `36h`. The long names (e.g. `days`) can be
+// used as well.
+func ParseRelativeDuration(str string) (RelativeDuration, error) {
+	var d RelativeDuration
+	s := strings.ToLower(strings.ReplaceAll(str, " ", ""))
+	if s == "" {
+		return d, fmt.Errorf("duration is empty")
+	}
+	for s != "" {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		j := i
+		for j < len(s) && s[j] >= 'a' && s[j] <= 'z' {
+			j++
+		}
+		if i == 0 || j == i {
+			return RelativeDuration{}, fmt.Errorf("invalid duration [%s]: expected a number followed by a unit e.g. `7d`", str)
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return RelativeDuration{}, fmt.Errorf("invalid duration [%s]: %w", str, err)
+		}
+		switch s[i:j] {
+		case "y", "year", "years":
+			d.Years += n
+		case "mo", "month", "months":
+			d.Months += n
+		case "w", "week", "weeks":
+			d.Days += 7 * n
+		case "d", "day", "days":
+			d.Days += n
+		case "h", "hour", "hours":
+			d.Duration += time.Duration(n) * time.Hour
+		case "m", "min", "minute", "minutes":
+			d.Duration += time.Duration(n) * time.Minute
+		case "s", "sec", "second", "seconds":
+			d.Duration += time.Duration(n) * time.Second
+		default:
+			return RelativeDuration{}, fmt.Errorf("invalid duration [%s]: unknown unit [%s]", str, s[i:j])
+		}
+		s = s[j:]
+	}
+	return d, nil
+}
+
+// Before returns the time that is d before t.
+func (d RelativeDuration) Before(t time.Time) time.Time {
+	return t.AddDate(-d.Years, -d.Months, -d.Days).Add(-d.Duration)
+}
+
+// ResolveRelativeTime returns the time range for the relative time operator: [start, end] for WITHIN_LAST, the time
+// before which values match for OLDER_THAN (end is zero), and [start, end) for the others.
+func (c TimeConfig) ResolveRelativeTime(op Operator, duration string) (time.Time, time.Time, error) {
+	now := c.Now().In(c.Location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.Location)
+
+	switch op {
+	case WITHIN_LAST, OLDER_THAN:
+		d, err := ParseRelativeDuration(duration)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if op == OLDER_THAN {
+			return d.Before(now), time.Time{}, nil
+		}
+		return d.Before(now), now, nil
+	case TODAY:
+		return today, today.AddDate(0, 0, 1), nil
+	case THIS_WEEK:
+		start := today.AddDate(0, 0, -((int(today.Weekday()) - int(c.WeekStart) + 7) % 7))
+		return start, start.AddDate(0, 0, 7), nil
+	case THIS_MONTH:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, c.Location)
+		return start, start.AddDate(0, 1, 0), nil
+	case THIS_YEAR:
+		start := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, c.Location)
+		return start, start.AddDate(1, 0, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("operator [%s] is not a relative time operator", op)
+}
+
+// validateRelativeTimeCondition checks the duration of the condition against the operator.
+func validateRelativeTimeCondition(f Condition, info OperatorInfo) error {
+	var duration string
+	if dc, ok := f.(DurationCondition); ok {
+		duration = dc.GetDuration()
+	}
+	switch op := f.GetOperator(); {
+	case op == WITHIN_LAST || op == OLDER_THAN:
+		if _, err := ParseRelativeDuration(duration); err != nil {
+			return fmt.Errorf("operator [%s] needs a valid duration: %w", info, err)
+		}
+	case duration != "":
+		return fmt.Errorf("operator [%s] does not expect a duration", info)
+	}
+	return nil
+}
+
+var (
+	timestampType = reflect.TypeOf(scalars.Timestamp{})
+	dateType      = reflect.TypeOf(scalars.Date{})
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// resolveRelativeTimeValues returns the values that the relative time operator is resolved into, in the type of the
+// condition values (scalars.Timestamp, scalars.Date, or time.Time if it's not known).
+func resolveRelativeTimeValues(f Condition, info OperatorInfo) ([]interface{}, error) {
+	op := f.GetOperator()
+	var duration string
+	if dc, ok := f.(DurationCondition); ok {
+		duration = dc.GetDuration()
+	}
+	start, end, err := GetTimeConfig().ResolveRelativeTime(op, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	var convert func(t time.Time) interface{}
+	switch t := getConditionValueType(f); t {
+	case timestampType:
+		convert = func(t time.Time) interface{} { return scalars.NewTimestamp(t) }
+	case dateType:
+		convert = func(t time.Time) interface{} { return scalars.NewDateFromTime(t) }
+	case timeType, nil:
+		convert = func(t time.Time) interface{} { return t }
+	default:
+		if t.Kind() != reflect.Interface {
+			return nil, fmt.Errorf("operator [%s] can only be used with timestamps or dates, got [%s]", info, t)
+		}
+		convert = func(t time.Time) interface{} { return t }
+	}
+
+	if op == OLDER_THAN {
+		return []interface{}{convert(start)}, nil
+	}
+	return []interface{}{convert(start), convert(end)}, nil
+}
+
+// getConditionValueType returns the type of the Values of a condition struct e.g. scalars.Timestamp for a
+// TimestampCondition, or nil if it is not known.
+func getConditionValueType(f Condition) reflect.Type {
+	t := reflect.TypeOf(f)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	sf, ok := t.FieldByName("Values")
+	if !ok || sf.Type.Kind() != reflect.Slice {
+		return nil
+	}
+	return sf.Type.Elem()
+}
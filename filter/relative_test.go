@@ -0,0 +1,240 @@
+package filter
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/teejays/gokutil/scalars"
+)
+
+func TestParseRelativeDuration(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    RelativeDuration
+		wantErr bool
+	}{
+		{str: "7d", want: RelativeDuration{Days: 7}},
+		{str: "2w", want: RelativeDuration{Days: 14}},
+		{str: "1y6mo", want: RelativeDuration{Years: 1, Months: 6}},
+		{str: "36h", want: RelativeDuration{Duration: 36 * time.Hour}},
+		{str: "1 day 30 minutes", want: RelativeDuration{Days: 1, Duration: 30 * time.Minute}},
+		{str: "", wantErr: true},
+		{str: "7", wantErr: true},
+		{str: "d", wantErr: true},
+		{str: "3 fortnights", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			got, err := ParseRelativeDuration(tt.str)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRelativeTimeCondition(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// Wednesday 2024-05-15 23:30 in Paris
+	now := time.Date(2024, 5, 15, 21, 30, 0, 0, time.UTC)
+
+	orig := GetTimeConfig()
+	defer SetTimeConfig(orig)
+	SetTimeConfig(TimeConfig{Now: func() time.Time { return now }, Location: paris, WeekStart: time.Monday})
+
+	inParis := func(year int, month time.Month, day, hour int) scalars.Timestamp {
+		return scalars.NewTimestamp(time.Date(year, month, day, hour, 0, 0, 0, paris))
+	}
+	date := func(year int, month time.Month, day int) scalars.Date {
+		d, err := scalars.NewDate(year, month, day)
+		assert.NoError(t, err)
+		return d
+	}
+
+	t.Run("SQL", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			cond     Condition
+			wantSQL  string
+			wantArgs []interface{}
+			wantErr  bool
+		}{
+			{
+				name:     "WITHIN_LAST timestamp",
+				cond:     NewDurationCondition[scalars.Timestamp](WITHIN_LAST, "7d"),
+				wantSQL:  `SELECT * FROM "t" WHERE (("col" >= $1) AND ("col" <= $2))`,
+				wantArgs: []interface{}{now.In(paris).AddDate(0, 0, -7), now},
+			},
+			{
+				name:     "OLDER_THAN date",
+				cond:     NewDurationCondition[scalars.Date](OLDER_THAN, "1mo"),
+				wantSQL:  `SELECT * FROM "t" WHERE ("col" < $1)`,
+				wantArgs: []interface{}{time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC)},
+			},
+			{
+				name:     "TODAY in the timezone",
+				cond:     NewTimestampCondition(TODAY),
+				wantSQL:  `SELECT * FROM "t" WHERE (("col" >= $1) AND ("col" < $2))`,
+				wantArgs: []interface{}{time.Date(2024, 5, 15, 0, 0, 0, 0, paris), time.Date(2024, 5, 16, 0, 0, 0, 0, paris)},
+			},
+			{
+				name:     "THIS_WEEK date",
+				cond:     NewDateCondition(THIS_WEEK),
+				wantSQL:  `SELECT * FROM "t" WHERE (("col" >= $1) AND ("col" < $2))`,
+				wantArgs: []interface{}{time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)},
+			},
+			{
+				name:     "THIS_YEAR",
+				cond:     NewTimestampCondition(THIS_YEAR),
+				wantSQL:  `SELECT * FROM "t" WHERE (("col" >= $1) AND ("col" < $2))`,
+				wantArgs: []interface{}{time.Date(2024, 1, 1, 0, 0, 0, 0, paris), time.Date(2025, 1, 1, 0, 0, 0, 0, paris)},
+			},
+			{
+				name:    "WITHIN_LAST without duration",
+				cond:    NewTimestampCondition(WITHIN_LAST),
+				wantErr: true,
+			},
+			{
+				name:    "duration with another operator",
+				cond:    &TimestampCondition{Op: IS_NULL, Duration: "7d"},
+				wantErr: true,
+			},
+			{
+				name:    "relative operator on a string",
+				cond:    NewStringCondition(TODAY),
+				wantErr: true,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				sb, err := InjectConditionIntoSqlBuilder(tt.cond, goqu.Dialect("postgres").From("t").Prepared(true), "col", false)
+				if tt.wantErr {
+					assert.Error(t, err)
+					return
+				}
+				assert.NoError(t, err)
+				sql, args, err := sb.ToSQL()
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantSQL, sql)
+				if assert.Len(t, args, len(tt.wantArgs)) {
+					for i := range args {
+						// Times are compared as instants, since the locations are not comparable
+						want, got := tt.wantArgs[i].(time.Time), args[i].(time.Time)
+						assert.True(t, want.Equal(got), "arg %d: want %s, got %s", i, want, got)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("Huandu", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			cond     Condition
+			wantSQL  string
+			wantArgs []time.Time
+		}{
+			{
+				name:     "WITHIN_LAST",
+				cond:     NewDurationCondition[scalars.Timestamp](WITHIN_LAST, "7d"),
+				wantSQL:  `SELECT * FROM t WHERE col >= $1 AND col <= $2`,
+				wantArgs: []time.Time{now.AddDate(0, 0, -7), now},
+			},
+			{
+				name:     "OLDER_THAN",
+				cond:     NewDurationCondition[scalars.Date](OLDER_THAN, "1mo"),
+				wantSQL:  `SELECT * FROM t WHERE col < $1`,
+				wantArgs: []time.Time{time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC)},
+			},
+			{
+				name:     "TODAY",
+				cond:     NewTimestampCondition(TODAY),
+				wantSQL:  `SELECT * FROM t WHERE col >= $1 AND col < $2`,
+				wantArgs: []time.Time{time.Date(2024, 5, 15, 0, 0, 0, 0, paris), time.Date(2024, 5, 16, 0, 0, 0, 0, paris)},
+			},
+			{
+				name:     "THIS_WEEK",
+				cond:     NewDateCondition(THIS_WEEK),
+				wantSQL:  `SELECT * FROM t WHERE col >= $1 AND col < $2`,
+				wantArgs: []time.Time{time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)},
+			},
+			{
+				name:     "THIS_MONTH",
+				cond:     NewTimestampCondition(THIS_MONTH),
+				wantSQL:  `SELECT * FROM t WHERE col >= $1 AND col < $2`,
+				wantArgs: []time.Time{time.Date(2024, 5, 1, 0, 0, 0, 0, paris), time.Date(2024, 6, 1, 0, 0, 0, 0, paris)},
+			},
+			{
+				name:     "THIS_YEAR",
+				cond:     NewTimestampCondition(THIS_YEAR),
+				wantSQL:  `SELECT * FROM t WHERE col >= $1 AND col < $2`,
+				wantArgs: []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, paris), time.Date(2025, 1, 1, 0, 0, 0, 0, paris)},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// The injector of the operator needs the resolved times, and does not panic without them
+				info, err := getOperatorInfo(tt.cond.GetOperator())
+				assert.NoError(t, err)
+				assert.Error(t, info.InjectSqlBuilderWhereCond_Huandu(sqlbuilder.PostgreSQL.NewSelectBuilder(), "col", ListConditionValues(tt.cond)...))
+
+				sb := sqlbuilder.PostgreSQL.NewSelectBuilder().Select("*").From("t")
+				err = InjectConditionIntoHuanduSqlBuilder(tt.cond, sb, "col")
+				assert.NoError(t, err)
+				sql, args := sb.Build()
+				assert.Equal(t, tt.wantSQL, sql)
+				if assert.Len(t, args, len(tt.wantArgs)) {
+					for i := range args {
+						v, err := args[i].(driver.Valuer).Value()
+						assert.NoError(t, err)
+						got, ok := v.(time.Time)
+						assert.True(t, ok, "arg %d: got %T", i, v)
+						assert.True(t, tt.wantArgs[i].Equal(got), "arg %d: want %s, got %s", i, tt.wantArgs[i], got)
+					}
+				}
+			})
+		}
+
+		// Conditions are still checked
+		err := InjectConditionIntoHuanduSqlBuilder(NewTimestampCondition(WITHIN_LAST), sqlbuilder.PostgreSQL.NewSelectBuilder(), "col")
+		assert.Error(t, err)
+	})
+
+	t.Run("Evaluate", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			value interface{}
+			cond  Condition
+			want  bool
+		}{
+			{"WITHIN_LAST", inParis(2024, 5, 10, 12), NewDurationCondition[scalars.Timestamp](WITHIN_LAST, "7d"), true},
+			{"WITHIN_LAST too old", inParis(2024, 5, 1, 12), NewDurationCondition[scalars.Timestamp](WITHIN_LAST, "7d"), false},
+			{"WITHIN_LAST in the future", inParis(2024, 5, 16, 12), NewDurationCondition[scalars.Timestamp](WITHIN_LAST, "7d"), false},
+			{"OLDER_THAN", inParis(2024, 5, 1, 12), NewDurationCondition[scalars.Timestamp](OLDER_THAN, "1w"), true},
+			{"TODAY in the timezone", inParis(2024, 5, 15, 23), NewTimestampCondition(TODAY), true},
+			{"TODAY in UTC is not today in the timezone", inParis(2024, 5, 16, 0), NewTimestampCondition(TODAY), false},
+			{"THIS_WEEK date", date(2024, 5, 19), NewDateCondition(THIS_WEEK), true},
+			{"THIS_WEEK date last week", date(2024, 5, 12), NewDateCondition(THIS_WEEK), false},
+			{"THIS_MONTH", inParis(2024, 5, 1, 0), NewTimestampCondition(THIS_MONTH), true},
+			{"THIS_MONTH next month", inParis(2024, 6, 1, 0), NewTimestampCondition(THIS_MONTH), false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := Evaluate(tt.cond, tt.value)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			})
+		}
+	})
+}
@@ -0,0 +1,399 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/teejays/gokutil/scalars"
+)
+
+/* * * * * * *
+ * Schema Export
+ * * * * * * */
+
+// ScalarConditionInfo describes the condition type for a scalar (e.g. StringCondition), so that clients in other
+// languages can be told which operators apply to it and what values it takes.
+type ScalarConditionInfo struct {
+	// Name is the name of the condition type e.g. `StringCondition`
+	Name string
+	// GoType is the type of the condition values
+	GoType reflect.Type
+	// TypescriptType is the type of the values in TypeScript
+	TypescriptType string
+	// GraphQLType is the type of the values in GraphQL. Custom scalars (e.g. Timestamp) should be declared in the schema.
+	GraphQLType string
+	// JSONSchema is the schema of a single value
+	JSONSchema *jsonschema.Schema
+	// Operators are the operators that can be used with the scalar
+	Operators []Operator
+}
+
+// HasDuration is true if the condition takes a duration (i.e. it supports WITHIN_LAST and OLDER_THAN).
+func (s ScalarConditionInfo) HasDuration() bool {
+	for _, op := range s.Operators {
+		if op == WITHIN_LAST || op == OLDER_THAN {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	nullOperators       = []Operator{IS_NULL, IS_NOT_NULL}
+	equalityOperators   = []Operator{EQUAL, NOT_EQUAL, IN, NOT_IN}
+	comparisonOperators = []Operator{GREATER_THAN, GREATER_THAN_EQUAL, LESS_THAN, LESS_THAN_EQUAL, BETWEEN, NOT_BETWEEN}
+	stringOperators     = []Operator{LIKE, ILIKE, NOT_LIKE, STARTS_WITH, ENDS_WITH, CONTAINS, REGEX, IREGEX}
+	relativeOperators   = []Operator{WITHIN_LAST, OLDER_THAN, TODAY, THIS_WEEK, THIS_MONTH, THIS_YEAR}
+)
+
+func joinOperators(lists ...[]Operator) []Operator {
+	var r []Operator
+	for _, l := range lists {
+		r = append(r, l...)
+	}
+	return r
+}
+
+// ScalarConditions lists the condition aliases for the scalars, in the order they are generated.
+var ScalarConditions = []ScalarConditionInfo{
+	{
+		Name:           "StringCondition",
+		GoType:         reflect.TypeOf(""),
+		TypescriptType: "string",
+		GraphQLType:    "String",
+		JSONSchema:     &jsonschema.Schema{Type: "string"},
+		Operators:      joinOperators(equalityOperators, comparisonOperators, stringOperators, nullOperators),
+	},
+	{
+		Name:           "NumberCondition",
+		GoType:         reflect.TypeOf(int(0)),
+		TypescriptType: "number",
+		GraphQLType:    "Int",
+		JSONSchema:     &jsonschema.Schema{Type: "integer"},
+		Operators:      joinOperators(equalityOperators, comparisonOperators, nullOperators),
+	},
+	{
+		Name:           "FloatCondition",
+		GoType:         reflect.TypeOf(float64(0)),
+		TypescriptType: "number",
+		GraphQLType:    "Float",
+		JSONSchema:     &jsonschema.Schema{Type: "number"},
+		Operators:      joinOperators(equalityOperators, comparisonOperators, nullOperators),
+	},
+	{
+		Name:           "BoolCondition",
+		GoType:         reflect.TypeOf(false),
+		TypescriptType: "boolean",
+		GraphQLType:    "Boolean",
+		JSONSchema:     &jsonschema.Schema{Type: "boolean"},
+		Operators:      joinOperators([]Operator{EQUAL, NOT_EQUAL}, nullOperators),
+	},
+	{
+		Name:           "IDCondition",
+		GoType:         reflect.TypeOf(scalars.ID{}),
+		TypescriptType: "string",
+		GraphQLType:    "ID",
+		JSONSchema:     &jsonschema.Schema{Type: "string", Format: "uuid"},
+		Operators:      joinOperators(equalityOperators, nullOperators),
+	},
+	{
+		Name:           "TimestampCondition",
+		GoType:         reflect.TypeOf(scalars.Timestamp{}),
+		TypescriptType: "string",
+		GraphQLType:    "Timestamp",
+		JSONSchema:     &jsonschema.Schema{Type: "string", Format: "date-time"},
+		Operators:      joinOperators(equalityOperators, comparisonOperators, relativeOperators, nullOperators),
+	},
+	{
+		Name:           "DateCondition",
+		GoType:         reflect.TypeOf(scalars.Date{}),
+		TypescriptType: "string",
+		GraphQLType:    "Date",
+		JSONSchema:     &jsonschema.Schema{Type: "string", Format: "date"},
+		Operators:      joinOperators(equalityOperators, comparisonOperators, relativeOperators, nullOperators),
+	},
+	{
+		Name:           "EmailCondition",
+		GoType:         reflect.TypeOf(scalars.Email{}),
+		TypescriptType: "string",
+		GraphQLType:    "Email",
+		JSONSchema:     &jsonschema.Schema{Type: "string", Format: "email"},
+		Operators:      joinOperators(equalityOperators, stringOperators, nullOperators),
+	},
+	{
+		Name:           "LinkCondition",
+		GoType:         reflect.TypeOf(scalars.Link{}),
+		TypescriptType: "string",
+		GraphQLType:    "Link",
+		JSONSchema:     &jsonschema.Schema{Type: "string", Format: "uri"},
+		Operators:      joinOperators(equalityOperators, stringOperators, nullOperators),
+	},
+	{
+		Name:           "MoneyCondition",
+		GoType:         reflect.TypeOf(scalars.Money{}),
+		TypescriptType: "string",
+		GraphQLType:    "Money",
+		JSONSchema:     &jsonschema.Schema{Type: "string", Pattern: `^([A-Z]{3} )?-?[0-9]+(\.[0-9]+)?( [A-Z]{3})?$`},
+		Operators:      joinOperators(equalityOperators, comparisonOperators, nullOperators),
+	},
+	{
+		Name:           "GenericDataCondition",
+		GoType:         reflect.TypeOf(scalars.GenericData{}),
+		TypescriptType: "unknown",
+		GraphQLType:    "GenericData",
+		JSONSchema:     &jsonschema.Schema{},
+		Operators:      joinOperators([]Operator{EQUAL, NOT_EQUAL}, nullOperators),
+	},
+}
+
+// GetScalarConditionInfo returns the info for the condition with values of type t.
+func GetScalarConditionInfo(t reflect.Type) (ScalarConditionInfo, bool) {
+	for _, s := range ScalarConditions {
+		if s.GoType == t {
+			return s, true
+		}
+	}
+	return ScalarConditionInfo{}, false
+}
+
+func getValuesTypeName(vt ValuesType) string {
+	switch vt {
+	case ValuesType_Zero:
+		return "zero"
+	case ValuesType_One:
+		return "one"
+	case ValuesType_Two:
+		return "two"
+	case ValuesType_Multiple:
+		return "multiple"
+	}
+	return "free"
+}
+
+// getValuesCount returns the min and max number of values for the operator. Max is -1 if there is no max.
+func getValuesCount(info OperatorInfo) (int, int) {
+	switch info.ValuesType {
+	case ValuesType_Zero:
+		return 0, 0
+	case ValuesType_One:
+		return 1, 1
+	case ValuesType_Two:
+		return 2, 2
+	case ValuesType_Multiple:
+		if info.MultipleValuesMax > 0 {
+			return info.MultipleValuesMin, info.MultipleValuesMax
+		}
+		return info.MultipleValuesMin, -1
+	}
+	return 0, -1
+}
+
+/* * * * * * *
+ * Schema Export: TypeScript
+ * * * * * * */
+
+// GenerateTypescript returns a TypeScript module with the filter operators and a type for each of the ScalarConditions.
+func GenerateTypescript() string {
+	var sb strings.Builder
+	sb.WriteString("// Code generated by gokutil/filter. DO NOT EDIT.\n\n")
+
+	sb.WriteString("export type FilterOperator =\n")
+	for _, op := range OperatorsList {
+		sb.WriteString("    | " + strconv.Quote(op.String()) + "\n")
+	}
+	sb.WriteString(";\n\n")
+
+	sb.WriteString("export interface FilterOperatorInfo {\n")
+	sb.WriteString("    name: FilterOperator;\n")
+	sb.WriteString("    sign?: string;\n")
+	sb.WriteString("    sqlSign?: string;\n")
+	sb.WriteString("    valuesType: \"zero\" | \"one\" | \"two\" | \"multiple\" | \"free\";\n")
+	sb.WriteString("    minValues: number;\n")
+	sb.WriteString("    maxValues?: number;\n")
+	sb.WriteString("    isArrayOperator: boolean;\n")
+	sb.WriteString("}\n\n")
+
+	sb.WriteString("export const FilterOperators: Record<FilterOperator, FilterOperatorInfo> = {\n")
+	for _, op := range OperatorsList {
+		info := store[op]
+		min, max := getValuesCount(info)
+		fields := []string{"name: " + strconv.Quote(info.Name)}
+		if sign := info.GetTypescriptSign(); sign != "" {
+			fields = append(fields, "sign: "+strconv.Quote(sign))
+		}
+		if sign := info.GetSqlSign(); sign != "" {
+			fields = append(fields, "sqlSign: "+strconv.Quote(sign))
+		}
+		fields = append(fields, "valuesType: "+strconv.Quote(getValuesTypeName(info.ValuesType)), fmt.Sprintf("minValues: %d", min))
+		if max >= 0 {
+			fields = append(fields, fmt.Sprintf("maxValues: %d", max))
+		}
+		fields = append(fields, fmt.Sprintf("isArrayOperator: %t", info.IsArrayOperator))
+		sb.WriteString(fmt.Sprintf("    %s: { %s },\n", op, strings.Join(fields, ", ")))
+	}
+	sb.WriteString("};\n")
+
+	for _, s := range ScalarConditions {
+		opType := strings.TrimSuffix(s.Name, "Condition") + "Operator"
+		sb.WriteString("\nexport type " + opType + " =\n")
+		for _, op := range s.Operators {
+			sb.WriteString("    | " + strconv.Quote(op.String()) + "\n")
+		}
+		sb.WriteString(";\n\n")
+		sb.WriteString("export interface " + s.Name + " {\n")
+		sb.WriteString("    op: " + opType + ";\n")
+		sb.WriteString("    values?: " + s.TypescriptType + "[];\n")
+		if s.HasDuration() {
+			sb.WriteString("    duration?: string;\n")
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+/* * * * * * *
+ * Schema Export: GraphQL
+ * * * * * * */
+
+// GenerateGraphQLSchema returns the GraphQL SDL for the FilterOperator enum, and an input type for each of the
+// ScalarConditions. The custom scalars (e.g. Timestamp) are expected to be declared elsewhere in the schema.
+func GenerateGraphQLSchema() string {
+	var sb strings.Builder
+	sb.WriteString("# Code generated by gokutil/filter. DO NOT EDIT.\n\n")
+
+	sb.WriteString("enum FilterOperator {\n")
+	for _, op := range OperatorsList {
+		sb.WriteString("    " + op.String() + "\n")
+	}
+	sb.WriteString("}\n")
+
+	for _, s := range ScalarConditions {
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("# Operators: %s\n", joinOperatorNames(s.Operators, ", ")))
+		sb.WriteString("input " + s.Name + "Input {\n")
+		sb.WriteString("    op: FilterOperator!\n")
+		sb.WriteString("    values: [" + s.GraphQLType + "!]\n")
+		if s.HasDuration() {
+			sb.WriteString("    duration: String\n")
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+func joinOperatorNames(ops []Operator, sep string) string {
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = op.String()
+	}
+	return strings.Join(names, sep)
+}
+
+/* * * * * * *
+ * Schema Export: JSON Schema
+ * * * * * * */
+
+// GetConditionJSONSchema returns the JSON Schema for the condition: an object with an op (one of the scalar's
+// operators) and values, with the number of values checked for each operator.
+func GetConditionJSONSchema(s ScalarConditionInfo) *jsonschema.Schema {
+	props := jsonschema.NewProperties()
+
+	opEnum := make([]interface{}, len(s.Operators))
+	for i, op := range s.Operators {
+		opEnum[i] = op.String()
+	}
+	props.Set("op", &jsonschema.Schema{Type: "string", Enum: opEnum})
+	props.Set("values", &jsonschema.Schema{Type: "array", Items: s.JSONSchema})
+	if s.HasDuration() {
+		props.Set("duration", &jsonschema.Schema{Type: "string", Description: "Duration for WITHIN_LAST and OLDER_THAN e.g. `7d`"})
+	}
+
+	schema := &jsonschema.Schema{
+		Type:                 "object",
+		Title:                s.Name,
+		Properties:           props,
+		Required:             []string{"op"},
+		AdditionalProperties: jsonschema.FalseSchema,
+	}
+
+	// Number of values, for each group of operators that take the same number of values
+	var groups [][2]int
+	opsByCount := map[[2]int][]interface{}{}
+	for _, op := range s.Operators {
+		min, max := getValuesCount(store[op])
+		key := [2]int{min, max}
+		if _, exists := opsByCount[key]; !exists {
+			groups = append(groups, key)
+		}
+		opsByCount[key] = append(opsByCount[key], op.String())
+	}
+	for _, key := range groups {
+		min, max := key[0], key[1]
+		values := &jsonschema.Schema{Type: "array"}
+		if min > 0 {
+			m := uint64(min)
+			values.MinItems = &m
+		}
+		if max >= 0 {
+			m := uint64(max)
+			values.MaxItems = &m
+		}
+		thenProps := jsonschema.NewProperties()
+		thenProps.Set("values", values)
+		ifProps := jsonschema.NewProperties()
+		ifProps.Set("op", &jsonschema.Schema{Enum: opsByCount[key]})
+		then := &jsonschema.Schema{Properties: thenProps}
+		if min > 0 {
+			then.Required = []string{"values"}
+		}
+		schema.AllOf = append(schema.AllOf, &jsonschema.Schema{
+			If:   &jsonschema.Schema{Properties: ifProps},
+			Then: then,
+		})
+	}
+	return schema
+}
+
+// GenerateJSONSchema returns a JSON Schema document with the FilterOperator and each of the ScalarConditions in $defs.
+func GenerateJSONSchema() ([]byte, error) {
+	defs := jsonschema.Definitions{}
+
+	opEnum := make([]interface{}, len(OperatorsList))
+	for i, op := range OperatorsList {
+		opEnum[i] = op.String()
+	}
+	defs["FilterOperator"] = &jsonschema.Schema{Type: "string", Enum: opEnum}
+
+	for _, s := range ScalarConditions {
+		defs[s.Name] = GetConditionJSONSchema(s)
+	}
+
+	schema := &jsonschema.Schema{
+		Version:     jsonschema.Version,
+		ID:          "https://github.com/teejays/gokutil/filter",
+		Title:       "Filter Conditions",
+		Definitions: defs,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// JSONSchema implements the jsonschema.JSONSchemer interface, so that structs with conditions can be reflected into a
+// JSON Schema.
+func (GenericCondition[T]) JSONSchema() *jsonschema.Schema {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if s, ok := GetScalarConditionInfo(t); ok {
+		return GetConditionJSONSchema(s)
+	}
+	// Conditions on other types can use any operator
+	return GetConditionJSONSchema(ScalarConditionInfo{
+		Name:       fmt.Sprintf("GenericCondition[%s]", t),
+		GoType:     t,
+		JSONSchema: &jsonschema.Schema{},
+		Operators:  OperatorsList,
+	})
+}
@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalarConditions(t *testing.T) {
+	for _, s := range ScalarConditions {
+		for _, op := range s.Operators {
+			_, err := GetOperatorInfo(op)
+			assert.NoError(t, err, "%s: %s", s.Name, op)
+		}
+	}
+}
+
+func TestGenerateTypescript(t *testing.T) {
+	ts := GenerateTypescript()
+	assert.Contains(t, ts, "export type FilterOperator =\n    | \"EQUAL\"\n")
+	assert.Contains(t, ts, `    EQUAL: { name: "EQUAL", sign: "===", sqlSign: "=", valuesType: "one", minValues: 1, maxValues: 1, isArrayOperator: false },`)
+	assert.Contains(t, ts, `    IN: { name: "IN", sqlSign: "IN", valuesType: "multiple", minValues: 1, isArrayOperator: false },`)
+	assert.Contains(t, ts, "export interface TimestampCondition {\n    op: TimestampOperator;\n    values?: string[];\n    duration?: string;\n}\n")
+	assert.Contains(t, ts, "export interface BoolCondition {\n    op: BoolOperator;\n    values?: boolean[];\n}\n")
+}
+
+func TestGenerateGraphQLSchema(t *testing.T) {
+	sdl := GenerateGraphQLSchema()
+	assert.Contains(t, sdl, "enum FilterOperator {\n    EQUAL\n    NOT_EQUAL\n")
+	assert.Contains(t, sdl, "input NumberConditionInput {\n    op: FilterOperator!\n    values: [Int!]\n}\n")
+	assert.Contains(t, sdl, "input DateConditionInput {\n    op: FilterOperator!\n    values: [Date!]\n    duration: String\n}\n")
+	for _, op := range OperatorsList {
+		assert.True(t, strings.Contains(sdl, "    "+op.String()+"\n"), op.String())
+	}
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	data, err := GenerateJSONSchema()
+	assert.NoError(t, err)
+
+	var doc struct {
+		Defs map[string]json.RawMessage `json:"$defs"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Contains(t, doc.Defs, "FilterOperator")
+	for _, s := range ScalarConditions {
+		assert.Contains(t, doc.Defs, s.Name)
+	}
+
+	// Conditions are reflected using their JSONSchema method
+	type listFilter struct {
+		Name *StringCondition `json:"name"`
+	}
+	schema := jsonschema.Reflect(&listFilter{})
+	data, err = json.Marshal(schema)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"title":"StringCondition"`)
+	assert.Contains(t, string(data), `"if":{"properties":{"op":{"enum":["IS_NULL","IS_NOT_NULL"]}}},"then":{"properties":{"values":{"type":"array","maxItems":0}}}`)
+}
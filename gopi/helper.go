@@ -201,18 +201,17 @@ func GetGenericGetHandler[ReqT, RespT any](fn func(context.Context, ReqT) (RespT
 
 		log.Debug(ctx, "[START] HTTP Handle GetDelete")
 
-		// Get the req data from URL
-		reqParam, ok := r.URL.Query()["req"]
-		if !ok || len(reqParam) < 1 {
-			log.Warn(ctx, "An expected URL param is missing", "param", "req")
-		}
+		// Get the req data from URL: either JSON encoded in the `req` param, or as query string filters
+		query := r.URL.Query()
+		reqParam, ok := query[QUERY_PARAM_REQ]
 		if len(reqParam) > 1 {
 			WriteError(w, http.StatusBadRequest, fmt.Errorf("multiple URL params with name 'req' found"))
 			return
 		}
 
 		var req ReqT
-		if len(reqParam) == 1 {
+		var decoded bool
+		if ok && len(reqParam) == 1 {
 			// Strict Unmarshal so we don't mess up things.
 			err := json.UnmarshalStrict([]byte(reqParam[0]), &req)
 			if err != nil {
@@ -220,8 +219,26 @@ func GetGenericGetHandler[ReqT, RespT any](fn func(context.Context, ReqT) (RespT
 				return
 			}
 			log.Debug(ctx, "[HTTP Handler] Request unmarshaled from URL", "req", json.MustPrettyPrint(req))
+			decoded = true
+		} else {
+			// Params that are not fields of the request are ignored
+			n, err := decodeQueryString(query, &req)
+			if err != nil {
+				err = errutil.Wrap(err, "Decoding the query string")
+				WriteError(w, http.StatusBadRequest, err)
+				return
+			}
+			if n > 0 {
+				log.Debug(ctx, "[HTTP Handler] Request decoded from URL query string", "req", json.MustPrettyPrint(req))
+				decoded = true
+			} else {
+				log.Warn(ctx, "An expected URL param is missing", "param", QUERY_PARAM_REQ)
+			}
+		}
+
+		if decoded {
 			// Validate the request
-			err = validate.Struct(req)
+			err := validate.Struct(req)
 			if err != nil {
 				err = errutil.Wrap(err, "Validating the request param")
 				WriteError(w, http.StatusBadRequest, err)
@@ -0,0 +1,442 @@
+package gopi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* * * * * * *
+ * Query String Filters
+ * * * * * * */
+
+// QUERY_PARAM_REQ is the URL param that holds the whole JSON encoded request for GET handlers.
+const QUERY_PARAM_REQ = "req"
+
+// QueryStringOperators maps the short operator names that can be used in the query string (e.g. `age[gte]=18`) to the
+// names of the filter operators. The full operator names (e.g. `age[greater_than_equal]=18`) can be used as well.
+var QueryStringOperators = map[string]string{
+	"eq":          "EQUAL",
+	"ne":          "NOT_EQUAL",
+	"neq":         "NOT_EQUAL",
+	"in":          "IN",
+	"nin":         "NOT_IN",
+	"gt":          "GREATER_THAN",
+	"gte":         "GREATER_THAN_EQUAL",
+	"lt":          "LESS_THAN",
+	"lte":         "LESS_THAN_EQUAL",
+	"like":        "LIKE",
+	"ilike":       "ILIKE",
+	"nlike":       "NOT_LIKE",
+	"null":        "IS_NULL",
+	"notnull":     "IS_NOT_NULL",
+	"between":     "BETWEEN",
+	"nbetween":    "NOT_BETWEEN",
+	"starts_with": "STARTS_WITH",
+	"ends_with":   "ENDS_WITH",
+	"contains":    "CONTAINS",
+	"regex":       "REGEX",
+	"iregex":      "IREGEX",
+}
+
+// queryStringListOperators take a comma separated list of values.
+var queryStringListOperators = map[string]bool{
+	"IN":           true,
+	"NOT_IN":       true,
+	"BETWEEN":      true,
+	"NOT_BETWEEN":  true,
+	"CONTAINS_ALL": true,
+	"CONTAINS_ANY": true,
+	"OVERLAPS":     true,
+	"CONTAINED_BY": true,
+}
+
+// queryStringDurationOperators take a duration (e.g. `7d`) which is set in the condition's Duration field.
+var queryStringDurationOperators = map[string]bool{
+	"WITHIN_LAST": true,
+	"OLDER_THAN":  true,
+}
+
+// DecodeQueryString populates dst, which should be a pointer to a struct, from the URL query params. This is a compact
+// alternative to JSON encoding the request into the `req` param, e.g.
+//
+//	?status[in]=active,pending&created_at[gte]=2024-01-01&name[ilike]=bob&limit=10
+//
+// Keys are matched to the struct fields by their JSON name or Go name, ignoring case and underscores, and can use dots
+// for nested structs (e.g. `filter.status`). Keys that don't match a field of dst are looked up in its `filter` field,
+// if it has one. Keys that match neither (e.g. a cache buster like `_=123`, or tracking params) are ignored.
+//
+// Filter condition fields (structs with an `Op` operator and `Values` slice, like filter.GenericCondition) take an
+// operator in brackets, which defaults to EQUAL. List operators (e.g. IN, BETWEEN) take comma separated values; a
+// literal comma can be escaped as `\,`. `[null]` and `[notnull]` take no value, or a boolean. `[within_last]` and
+// `[older_than]` take a duration e.g. `7d`. Values are parsed into
+// the type of the condition values, so IDs, timestamps etc. are validated. If there is more than one condition for the
+// same field (e.g. `age[gte]=18&age[lt]=65`), the extra ones are added to the struct's `And` list.
+//
+// Other fields are set from a single value.
+func DecodeQueryString(q url.Values, dst interface{}) error {
+	_, err := decodeQueryString(q, dst)
+	return err
+}
+
+// decodeQueryString is DecodeQueryString, which also returns the number of params that were decoded into dst.
+func decodeQueryString(q url.Values, dst interface{}) (int, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return 0, fmt.Errorf("destination for the query string should be a pointer to a struct, got [%T]", dst)
+	}
+
+	keys := make([]string, 0, len(q))
+	for key := range q {
+		if key == QUERY_PARAM_REQ || !hasQueryField(rv.Elem(), key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range q[key] {
+			if err := decodeQueryParam(rv.Elem(), key, value); err != nil {
+				return 0, fmt.Errorf("query param [%s]: %w", key, err)
+			}
+		}
+	}
+	return len(keys), nil
+}
+
+// hasQueryField returns true if the first field of the key (e.g. `filter` for `filter.status[in]`) is a field of the
+// struct, or of its filter field.
+func hasQueryField(v reflect.Value, key string) bool {
+	name := key
+	if i := strings.IndexAny(key, ".["); i >= 0 {
+		name = key[:i]
+	}
+	if _, _, ok := getQueryStructField(v, name); ok {
+		return true
+	}
+	filterField, _, ok := getQueryStructField(v, "filter")
+	if !ok || derefType(filterField.Type()).Kind() != reflect.Struct {
+		return false
+	}
+	_, _, ok = getQueryStructField(reflect.New(derefType(filterField.Type())).Elem(), name)
+	return ok
+}
+
+func decodeQueryParam(v reflect.Value, key string, value string) error {
+	path, op, err := parseQueryKey(key)
+	if err != nil {
+		return err
+	}
+
+	target, parent, index, err := getQueryFieldByPath(v, path)
+	if err != nil {
+		// Fallback to the filter field (e.g. of list requests)
+		filterField, _, _, ferr := getQueryFieldByPath(v, []string{"filter"})
+		if ferr != nil || derefType(filterField.Type()).Kind() != reflect.Struct {
+			return err
+		}
+		target, parent, index, err = getQueryFieldByPath(filterField, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if isConditionType(target.Type()) {
+		return setQueryCondition(target, parent, index, op, value)
+	}
+	if op != "" {
+		return fmt.Errorf("operator [%s] is only allowed for filter conditions", op)
+	}
+	return setQueryValue(target, value)
+}
+
+// parseQueryKey splits `a.b[op]` into the path [a, b] and the op.
+func parseQueryKey(key string) ([]string, string, error) {
+	var op string
+	if i := strings.Index(key, "["); i >= 0 {
+		if !strings.HasSuffix(key, "]") || i == 0 {
+			return nil, "", fmt.Errorf("invalid key, expected the format `field[operator]`")
+		}
+		op = key[i+1 : len(key)-1]
+		key = key[:i]
+	}
+	path := strings.Split(key, ".")
+	for _, p := range path {
+		if p == "" {
+			return nil, "", fmt.Errorf("invalid key, field name is empty")
+		}
+	}
+	return path, op, nil
+}
+
+// getQueryFieldByPath returns the field for the path, along with the struct that holds it and its index in that
+// struct. Nil pointers on the way are initialized.
+func getQueryFieldByPath(v reflect.Value, path []string) (reflect.Value, reflect.Value, int, error) {
+	parent := v
+	for i, name := range path {
+		parent = derefValue(parent)
+		if parent.Kind() != reflect.Struct {
+			return reflect.Value{}, reflect.Value{}, -1, fmt.Errorf("field [%s] is not a struct", strings.Join(path[:i], "."))
+		}
+		field, index, ok := getQueryStructField(parent, name)
+		if !ok {
+			return reflect.Value{}, reflect.Value{}, -1, fmt.Errorf("unknown field [%s]", strings.Join(path[:i+1], "."))
+		}
+		if i == len(path)-1 {
+			return field, parent, index, nil
+		}
+		parent = field
+	}
+	return reflect.Value{}, reflect.Value{}, -1, fmt.Errorf("empty field path")
+}
+
+func getQueryStructField(v reflect.Value, name string) (reflect.Value, int, bool) {
+	want := normalizeQueryName(name)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		jsonName := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		if normalizeQueryName(sf.Name) == want || (jsonName != "" && normalizeQueryName(jsonName) == want) {
+			return v.Field(i), i, true
+		}
+	}
+	return reflect.Value{}, -1, false
+}
+
+func normalizeQueryName(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+}
+
+// isConditionType returns true for filter condition types i.e. structs (or pointers to them) with an Op field that can
+// be parsed from a string, and a Values slice.
+func isConditionType(t reflect.Type) bool {
+	t = derefType(t)
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	opField, ok := t.FieldByName("Op")
+	if !ok || !reflect.PointerTo(opField.Type).Implements(operatorParserType) {
+		return false
+	}
+	valuesField, ok := t.FieldByName("Values")
+	return ok && valuesField.Type.Kind() == reflect.Slice
+}
+
+type operatorParser interface {
+	FromString(string) error
+}
+
+var operatorParserType = reflect.TypeOf((*operatorParser)(nil)).Elem()
+
+// setQueryCondition sets the condition field, which is the field at index in the parent struct.
+func setQueryCondition(field reflect.Value, parent reflect.Value, index int, op string, value string) error {
+	opName := strings.ToUpper(op)
+	if op == "" {
+		opName = "EQUAL"
+	}
+	if alias, ok := QueryStringOperators[strings.ToLower(op)]; ok {
+		opName = alias
+	}
+
+	// Null checks take an optional boolean e.g. `deleted_at[null]=false`
+	if opName == "IS_NULL" || opName == "IS_NOT_NULL" {
+		if value != "" {
+			isTrue, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("operator [%s] expects no value or a boolean, got [%s]", op, value)
+			}
+			if !isTrue {
+				opName = map[string]string{"IS_NULL": "IS_NOT_NULL", "IS_NOT_NULL": "IS_NULL"}[opName]
+			}
+		}
+		value = ""
+	}
+
+	// If the field already has a condition, put this one in the parent's And list
+	if !isZeroCondition(field) {
+		andField, _, ok := getQueryStructField(parent, "and")
+		if !ok || andField.Kind() != reflect.Slice || derefType(andField.Type().Elem()) != parent.Type() {
+			return fmt.Errorf("multiple conditions for the same field are not supported")
+		}
+		elem := reflect.New(parent.Type()).Elem()
+		if err := setQueryCondition(elem.Field(index), elem, index, op, value); err != nil {
+			return err
+		}
+		if andField.Type().Elem().Kind() == reflect.Ptr {
+			andField.Set(reflect.Append(andField, elem.Addr()))
+		} else {
+			andField.Set(reflect.Append(andField, elem))
+		}
+		return nil
+	}
+
+	cond := derefValue(field)
+	opValue := cond.FieldByName("Op")
+	if err := opValue.Addr().Interface().(operatorParser).FromString(opName); err != nil {
+		return fmt.Errorf("invalid operator [%s]: %w", op, err)
+	}
+
+	// Relative time operators take a duration instead of values e.g. `created_at[within_last]=7d`
+	if queryStringDurationOperators[opName] {
+		durationField := cond.FieldByName("Duration")
+		if !durationField.IsValid() || durationField.Kind() != reflect.String {
+			return fmt.Errorf("operator [%s] is not supported for this field", op)
+		}
+		durationField.SetString(value)
+		value = ""
+	}
+
+	var rawValues []string
+	switch {
+	case value == "":
+		// No values
+	case queryStringListOperators[opName]:
+		rawValues = splitQueryList(value)
+	default:
+		rawValues = []string{value}
+	}
+
+	values := cond.FieldByName("Values")
+	values.Set(reflect.MakeSlice(values.Type(), 0, len(rawValues)))
+	for _, raw := range rawValues {
+		elem := reflect.New(values.Type().Elem()).Elem()
+		if err := setQueryValue(elem, raw); err != nil {
+			return err
+		}
+		values.Set(reflect.Append(values, elem))
+	}
+	return nil
+}
+
+func isZeroCondition(field reflect.Value) bool {
+	if field.Kind() == reflect.Ptr {
+		return field.IsNil()
+	}
+	return field.FieldByName("Op").IsZero()
+}
+
+// splitQueryList splits a comma separated list, where `\,` is a literal comma.
+func splitQueryList(s string) []string {
+	var r []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			b.WriteByte(',')
+			i++
+		case s[i] == ',':
+			r = append(r, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(r, b.String())
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var stringParserType = reflect.TypeOf((*interface{ ParseString(string) error })(nil)).Elem()
+
+// setQueryValue parses the string into the value, based on its type.
+func setQueryValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	ptr := v.Addr()
+	var parse func(string) error
+	switch {
+	case ptr.Type().Implements(stringParserType):
+		// Scalars
+		parse = ptr.Interface().(interface{ ParseString(string) error }).ParseString
+	case ptr.Type().Implements(textUnmarshalerType):
+		parse = func(s string) error { return ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)) }
+	}
+	if parse != nil {
+		err := parse(s)
+		if err != nil {
+			// Allow dates for timestamps e.g. `created_at[gte]=2024-01-01`, as midnight UTC
+			if d, dErr := time.Parse(time.DateOnly, s); dErr == nil {
+				if parse(d.Format(time.RFC3339)) == nil {
+					return nil
+				}
+			}
+			return fmt.Errorf("invalid value [%s] for type [%s]: %w", s, v.Type(), err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean [%s]", s)
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer [%s]", s)
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer [%s]", s)
+		}
+		v.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number [%s]", s)
+		}
+		v.SetFloat(n)
+		return nil
+	}
+
+	// Everything else (e.g. scalars with a custom JSON format) is decoded as a JSON string
+	if err := json.Unmarshal([]byte(strconv.Quote(s)), ptr.Interface()); err != nil {
+		return fmt.Errorf("invalid value [%s] for type [%s]: %w", s, v.Type(), err)
+	}
+	return nil
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// derefValue follows pointers, initializing them if they are nil.
+func derefValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,173 @@
+package gopi_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/gopi"
+)
+
+// testOperator mimics filter.Operator, which satisfies the condition interface expected by the decoder.
+type testOperator string
+
+func (o *testOperator) FromString(s string) error {
+	if s == "" || strings.ToUpper(s) != s {
+		return fmt.Errorf("unknown operator [%s]", s)
+	}
+	*o = testOperator(s)
+	return nil
+}
+
+type testCondition[T any] struct {
+	Op       testOperator
+	Values   []T
+	Duration string
+}
+
+type testFilter struct {
+	Status    *testCondition[string]    `json:"status"`
+	Age       *testCondition[int]       `json:"age"`
+	CreatedAt *testCondition[time.Time] `json:"created_at"`
+	And       []testFilter              `json:"and"`
+}
+
+type testListReq struct {
+	Filter testFilter `json:"filter"`
+	Limit  int        `json:"limit"`
+}
+
+func TestDecodeQueryString(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    testListReq
+		wantErr bool
+	}{
+		{
+			name:  "default operator and plain field",
+			query: "status=active&limit=10",
+			want: testListReq{
+				Filter: testFilter{Status: &testCondition[string]{Op: "EQUAL", Values: []string{"active"}}},
+				Limit:  10,
+			},
+		},
+		{
+			name:  "list operator with escaped comma",
+			query: `filter.status[in]=a,b\,c`,
+			want: testListReq{
+				Filter: testFilter{Status: &testCondition[string]{Op: "IN", Values: []string{"a", "b,c"}}},
+			},
+		},
+		{
+			name:  "date value",
+			query: "created_at[gte]=2024-01-01",
+			want: testListReq{
+				Filter: testFilter{CreatedAt: &testCondition[time.Time]{Op: "GREATER_THAN_EQUAL", Values: []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}},
+			},
+		},
+		{
+			name:  "null with false",
+			query: "status[null]=false",
+			want: testListReq{
+				Filter: testFilter{Status: &testCondition[string]{Op: "IS_NOT_NULL", Values: []string{}}},
+			},
+		},
+		{
+			name:  "repeated field goes into and",
+			query: "age[gte]=18&age[lt]=65",
+			want: testListReq{
+				Filter: testFilter{
+					Age: &testCondition[int]{Op: "GREATER_THAN_EQUAL", Values: []int{18}},
+					And: []testFilter{{Age: &testCondition[int]{Op: "LESS_THAN", Values: []int{65}}}},
+				},
+			},
+		},
+		{
+			name:  "duration operator",
+			query: "created_at[within_last]=7d",
+			want: testListReq{
+				Filter: testFilter{CreatedAt: &testCondition[time.Time]{Op: "WITHIN_LAST", Values: []time.Time{}, Duration: "7d"}},
+			},
+		},
+		{
+			name:    "invalid value",
+			query:   "age[gt]=abc",
+			wantErr: true,
+		},
+		{
+			name:  "unrelated params are ignored",
+			query: "_=123&utm_source=mail&status=active",
+			want: testListReq{
+				Filter: testFilter{Status: &testCondition[string]{Op: "EQUAL", Values: []string{"active"}}},
+			},
+		},
+		{
+			name:    "unknown nested field",
+			query:   "filter.color=red",
+			wantErr: true,
+		},
+		{
+			name:    "operator on a plain field",
+			query:   "limit[gt]=1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+
+			var got testListReq
+			err = gopi.DecodeQueryString(q, &got)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type testGetReq struct {
+	Filter testFilter `json:"filter"`
+	Limit  int        `json:"limit" validate:"required"`
+}
+
+func TestGetGenericGetHandler_QueryString(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       testGetReq
+	}{
+		// As without any params, the request is empty and not validated
+		{name: "unrelated param", query: "_=123", wantStatus: http.StatusOK},
+		{name: "fields and unrelated params", query: "limit=5&status=active&_=123", wantStatus: http.StatusOK, want: testGetReq{
+			Filter: testFilter{Status: &testCondition[string]{Op: "EQUAL", Values: []string{"active"}}},
+			Limit:  5,
+		}},
+		{name: "decoded request is validated", query: "status=active", wantStatus: http.StatusBadRequest},
+		{name: "invalid value", query: "limit=abc", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testGetReq
+			handler := gopi.GetGenericGetHandler(func(ctx context.Context, req testGetReq) (string, error) {
+				got = req
+				return "ok", nil
+			})
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil))
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package scalars
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/teejays/gokutil/env/envutil"
+)
+
+/* * * * * * *
+ * Keyring
+* * * * * * */
+
+// Keyring provides the AES keys used by Encrypted values. Each key has an ID, which is stored with the ciphertext so
+// that values encrypted with an older key can still be decrypted after the primary key is rotated.
+type Keyring interface {
+	// GetPrimaryKey returns the key that new values are encrypted with.
+	GetPrimaryKey() (keyID string, key []byte, err error)
+	// GetKey returns the key with the ID.
+	GetKey(keyID string) ([]byte, error)
+}
+
+const (
+	// EnvEncryptionKeys has the keys of the default keyring, as comma separated `<key ID>:<base64 key>` pairs.
+	EnvEncryptionKeys = "GOKU_ENCRYPTION_KEYS"
+	// EnvEncryptionPrimaryKeyID has the ID of the key that new values are encrypted with.
+	EnvEncryptionPrimaryKeyID = "GOKU_ENCRYPTION_PRIMARY_KEY_ID"
+)
+
+// StaticKeyring is a Keyring with a fixed set of keys.
+type StaticKeyring struct {
+	primaryKeyID string
+	keys         map[string][]byte
+}
+
+// NewStaticKeyring returns a keyring with the keys by their IDs. Keys should be 16, 24 or 32 bytes long, for AES-128,
+// AES-192 or AES-256.
+func NewStaticKeyring(primaryKeyID string, keys map[string][]byte) (*StaticKeyring, error) {
+	r := &StaticKeyring{primaryKeyID: primaryKeyID, keys: map[string][]byte{}}
+	for id, key := range keys {
+		if id == "" || strings.ContainsAny(id, ":,") {
+			return nil, fmt.Errorf("key ID [%s] should not be empty or have a `:` or `,`", id)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("key [%s] is invalid: %w", id, err)
+		}
+		r.keys[id] = key
+	}
+	if _, ok := r.keys[primaryKeyID]; !ok {
+		return nil, fmt.Errorf("primary key [%s] is not one of the keys", primaryKeyID)
+	}
+	return r, nil
+}
+
+// NewKeyringFromEnv returns a keyring with the keys from the EnvEncryptionKeys and EnvEncryptionPrimaryKeyID
+// env variables e.g. `GOKU_ENCRYPTION_KEYS=2024:<base64 key>,2025:<base64 key>` and `GOKU_ENCRYPTION_PRIMARY_KEY_ID=2025`.
+func NewKeyringFromEnv() (*StaticKeyring, error) {
+	keysStr := envutil.GetEnvVarStr(EnvEncryptionKeys)
+	if keysStr == "" {
+		return nil, fmt.Errorf("env variable [%s] is not set", EnvEncryptionKeys)
+	}
+	primaryKeyID := envutil.GetEnvVarStr(EnvEncryptionPrimaryKeyID)
+	if primaryKeyID == "" {
+		return nil, fmt.Errorf("env variable [%s] is not set", EnvEncryptionPrimaryKeyID)
+	}
+	keys := map[string][]byte{}
+	for _, pair := range strings.Split(keysStr, ",") {
+		id, keyStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("env variable [%s] should have `<key ID>:<base64 key>` pairs", EnvEncryptionKeys)
+		}
+		key, err := base64.StdEncoding.DecodeString(keyStr)
+		if err != nil {
+			return nil, fmt.Errorf("decoding key [%s] in env variable [%s]: %w", id, EnvEncryptionKeys, err)
+		}
+		keys[id] = key
+	}
+	return NewStaticKeyring(primaryKeyID, keys)
+}
+
+func (r *StaticKeyring) GetPrimaryKey() (string, []byte, error) {
+	return r.primaryKeyID, r.keys[r.primaryKeyID], nil
+}
+
+func (r *StaticKeyring) GetKey(keyID string) ([]byte, error) {
+	key, ok := r.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key [%s] is not in the keyring", keyID)
+	}
+	return key, nil
+}
+
+var keyring Keyring
+var keyringLock sync.RWMutex
+
+// SetKeyring sets the keyring used by Encrypted values.
+func SetKeyring(r Keyring) {
+	keyringLock.Lock()
+	defer keyringLock.Unlock()
+	keyring = r
+}
+
+// GetKeyring returns the keyring used by Encrypted values. If none has been set, it is loaded from the env variables
+// (see NewKeyringFromEnv) on first use.
+func GetKeyring() (Keyring, error) {
+	keyringLock.RLock()
+	r := keyring
+	keyringLock.RUnlock()
+	if r != nil {
+		return r, nil
+	}
+
+	keyringLock.Lock()
+	defer keyringLock.Unlock()
+	if keyring == nil {
+		envKeyring, err := NewKeyringFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("loading encryption keyring: %w", err)
+		}
+		keyring = envKeyring
+	}
+	return keyring, nil
+}
+
+/* * * * * * *
+ * Encryption
+* * * * * * */
+
+// ciphertextVersion is the first part of the stored ciphertext, so that the format can change.
+const ciphertextVersion = "v1"
+
+// Encrypt encrypts the plaintext with the primary key of the keyring using AES-GCM. The ciphertext is
+// `v1:<key ID>:<base64 of the nonce and the sealed data>`. The key ID is authenticated along with the data.
+func Encrypt(r Keyring, plaintext []byte) (string, error) {
+	keyID, key, err := r.GetPrimaryKey()
+	if err != nil {
+		return "", fmt.Errorf("getting primary encryption key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", fmt.Errorf("key [%s]: %w", keyID, err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, []byte(keyID))
+	return ciphertextVersion + ":" + keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a ciphertext created by Encrypt, using the key that it was encrypted with.
+func Decrypt(r Keyring, ciphertext string) ([]byte, error) {
+	keyID, data, err := parseCiphertext(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	key, err := r.GetKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("key [%s]: %w", keyID, err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting with key [%s]: %w", keyID, err)
+	}
+	return plaintext, nil
+}
+
+// GetCiphertextKeyID returns the ID of the key that the ciphertext was encrypted with.
+func GetCiphertextKeyID(ciphertext string) (string, error) {
+	keyID, _, err := parseCiphertext(ciphertext)
+	return keyID, err
+}
+
+// ReEncrypt decrypts the ciphertext and encrypts it again with the primary key of the keyring, so that older keys can
+// be retired. It returns false, and the ciphertext as it is, if it is already encrypted with the primary key. It can
+// be run over the rows of a table, as it does not need to know the type of the encrypted values.
+func ReEncrypt(r Keyring, ciphertext string) (string, bool, error) {
+	keyID, err := GetCiphertextKeyID(ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	primaryKeyID, _, err := r.GetPrimaryKey()
+	if err != nil {
+		return "", false, fmt.Errorf("getting primary encryption key: %w", err)
+	}
+	if keyID == primaryKeyID {
+		return ciphertext, false, nil
+	}
+	plaintext, err := Decrypt(r, ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	newCiphertext, err := Encrypt(r, plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return newCiphertext, true, nil
+}
+
+func parseCiphertext(ciphertext string) (string, []byte, error) {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != ciphertextVersion {
+		return "", nil, fmt.Errorf("ciphertext is not in the `%s:<key ID>:<data>` format", ciphertextVersion)
+	}
+	data, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, fmt.Errorf("decoding ciphertext: %w", err)
+	}
+	return parts[1], data, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+/* * * * * * *
+ * Encrypted
+* * * * * * */
+
+// Redacted is what Encrypted values are shown as in JSON, fmt and slog output.
+const Redacted = "[REDACTED]"
+
+// Encrypted holds a value that is encrypted at rest. It is encrypted (as JSON) with the primary key of the keyring
+// (see GetKeyring) when saved to the database, and decrypted when scanned. The plaintext is never written out: it is
+// redacted in JSON, fmt and slog output, and can only be read using Get.
+type Encrypted[T any] struct {
+	value T
+	set   bool
+}
+
+func NewEncrypted[T any](v T) Encrypted[T] {
+	return Encrypted[T]{value: v, set: true}
+}
+
+// Get returns the plaintext value.
+func (e Encrypted[T]) Get() T {
+	return e.value
+}
+
+func (e Encrypted[T]) IsEmpty() bool {
+	return !e.set
+}
+
+func (e Encrypted[T]) Validate() error {
+	if e.IsEmpty() {
+		return fmt.Errorf("encrypted value is empty")
+	}
+	return nil
+}
+
+// Redaction
+
+// EqualRedacted is true if the other value is an Encrypted with the same plaintext. Since all values look the same in
+// JSON, it is used to compare them instead e.g. by types.Diff.
+func (e Encrypted[T]) EqualRedacted(other interface{}) bool {
+	o, ok := other.(Encrypted[T])
+	return ok && e.set == o.set && reflect.DeepEqual(e.value, o.value)
+}
+
+func (e Encrypted[T]) String() string {
+	return Redacted
+}
+
+func (e Encrypted[T]) GoString() string {
+	return Redacted
+}
+
+// Format redacts the value for all the fmt verbs, including `%v` and `%+v` which would otherwise print the fields.
+func (e Encrypted[T]) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, Redacted)
+}
+
+func (e Encrypted[T]) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+// JSON
+
+func (e Encrypted[T]) MarshalJSON() ([]byte, error) {
+	if e.IsEmpty() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(Redacted)
+}
+
+// UnmarshalJSON sets the plaintext value. The Redacted placeholder is rejected, so that an object that was read cannot
+// be saved back with its encrypted values replaced by the placeholder.
+func (e *Encrypted[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*e = Encrypted[T]{}
+		return nil
+	}
+	if string(data) == `"`+Redacted+`"` {
+		return fmt.Errorf("encrypted value cannot be set to the redacted placeholder")
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = NewEncrypted(v)
+	return nil
+}
+
+// SQL
+
+func (e *Encrypted[T]) Scan(value interface{}) error {
+	var ciphertext string
+	switch value := value.(type) {
+	case nil:
+		*e = Encrypted[T]{}
+		return nil
+	case string:
+		ciphertext = value
+	case []byte:
+		ciphertext = string(value)
+	default:
+		return fmt.Errorf("could not decode SQL db value into scalars.Encrypted field: %T", value)
+	}
+	r, err := GetKeyring()
+	if err != nil {
+		return err
+	}
+	plaintext, err := Decrypt(r, ciphertext)
+	if err != nil {
+		return err
+	}
+	var v T
+	if err := json.Unmarshal(plaintext, &v); err != nil {
+		return fmt.Errorf("decoding decrypted value: %w", err)
+	}
+	*e = NewEncrypted(v)
+	return nil
+}
+
+// Value encrypts the value. A new nonce is used each time, so the same value gives a different ciphertext.
+func (e Encrypted[T]) Value() (driver.Value, error) {
+	if e.IsEmpty() {
+		return nil, nil
+	}
+	plaintext, err := json.Marshal(e.value)
+	if err != nil {
+		return nil, fmt.Errorf("encoding value to encrypt: %w", err)
+	}
+	r, err := GetKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return Encrypt(r, plaintext)
+}
+
+// GraphQL
+
+func (e Encrypted[T]) ImplementsGraphQLType(name string) bool {
+	return name == "Encrypted"
+}
+
+func (e *Encrypted[T]) UnmarshalGraphQL(input interface{}) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("wrong type for Encrypted: %T", input)
+	}
+	return e.UnmarshalJSON(data)
+}
@@ -0,0 +1,175 @@
+package scalars
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCredentials struct {
+	User     string
+	Password string
+}
+
+func testKeyring(t *testing.T, primaryKeyID string) *StaticKeyring {
+	r, err := NewStaticKeyring(primaryKeyID, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 16),
+	})
+	assert.NoError(t, err)
+	return r
+}
+
+func TestEncrypted_SQL(t *testing.T) {
+	defer SetKeyring(keyring)
+	SetKeyring(testKeyring(t, "k1"))
+
+	e := NewEncrypted(testCredentials{User: "admin", Password: "hunter2"})
+	v, err := e.Value()
+	assert.NoError(t, err)
+	ciphertext, ok := v.(string)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(ciphertext, "v1:k1:"), ciphertext)
+	assert.NotContains(t, ciphertext, "hunter2")
+
+	// A new nonce is used each time
+	v2, err := e.Value()
+	assert.NoError(t, err)
+	assert.NotEqual(t, v, v2)
+
+	var got Encrypted[testCredentials]
+	assert.NoError(t, got.Scan([]byte(ciphertext)))
+	assert.Equal(t, e, got)
+
+	// Empty values are NULL
+	v, err = Encrypted[string]{}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+	assert.NoError(t, got.Scan(nil))
+	assert.True(t, got.IsEmpty())
+
+	// Tampered ciphertexts, or a key ID swapped in, are rejected
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, "v1:k1:"))
+	assert.NoError(t, err)
+	data[len(data)-1] ^= 1
+	assert.Error(t, got.Scan("v1:k1:"+base64.StdEncoding.EncodeToString(data)))
+	assert.Error(t, got.Scan(strings.Replace(ciphertext, ":k1:", ":k2:", 1)))
+	assert.Error(t, got.Scan(strings.Replace(ciphertext, ":k1:", ":k3:", 1)))
+	assert.Error(t, got.Scan("hunter2"))
+}
+
+func TestEncrypted_KeyRotation(t *testing.T) {
+	old := testKeyring(t, "k1")
+	ciphertext, err := Encrypt(old, []byte(`"hunter2"`))
+	assert.NoError(t, err)
+
+	rotated := testKeyring(t, "k2")
+	newCiphertext, changed, err := ReEncrypt(rotated, ciphertext)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	keyID, err := GetCiphertextKeyID(newCiphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", keyID)
+
+	// Values already on the primary key are left as they are
+	same, changed, err := ReEncrypt(rotated, newCiphertext)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, newCiphertext, same)
+
+	// Once the old key is retired, only the re-encrypted value can be read
+	retired, err := NewStaticKeyring("k2", map[string][]byte{"k2": bytes.Repeat([]byte{2}, 16)})
+	assert.NoError(t, err)
+	_, err = Decrypt(retired, ciphertext)
+	assert.Error(t, err)
+	plaintext, err := Decrypt(retired, newCiphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, `"hunter2"`, string(plaintext))
+}
+
+func TestEncrypted_Redaction(t *testing.T) {
+	e := NewEncrypted(testCredentials{User: "admin", Password: "hunter2"})
+	s := struct {
+		Name   string
+		Secret Encrypted[testCredentials]
+	}{Name: "db", Secret: e}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x"} {
+		assert.NotContains(t, fmt.Sprintf(format, e), "hunter2", format)
+		assert.NotContains(t, fmt.Sprintf(format, s), "hunter2", format)
+	}
+	assert.Equal(t, "[REDACTED]", fmt.Sprint(e))
+
+	b, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Name":"db","Secret":"[REDACTED]"}`, string(b))
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("connecting", "secret", e, "config", s)
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Contains(t, buf.String(), `"secret":"[REDACTED]"`)
+
+	// Plaintext can be set from JSON and GraphQL, but not the redacted placeholder
+	var got Encrypted[testCredentials]
+	assert.NoError(t, json.Unmarshal([]byte(`{"User":"admin","Password":"hunter2"}`), &got))
+	assert.Equal(t, e, got)
+	assert.Error(t, json.Unmarshal(b, &s))
+	var gql Encrypted[string]
+	assert.True(t, gql.ImplementsGraphQLType("Encrypted"))
+	assert.NoError(t, gql.UnmarshalGraphQL("hunter2"))
+	assert.Equal(t, "hunter2", gql.Get())
+
+	// Values are compared by their plaintext
+	assert.True(t, e.EqualRedacted(got))
+	assert.False(t, e.EqualRedacted(NewEncrypted(testCredentials{User: "admin"})))
+	assert.False(t, gql.EqualRedacted(Encrypted[string]{}))
+	assert.False(t, gql.EqualRedacted("hunter2"))
+}
+
+func TestNewKeyringFromEnv(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	k2 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 16))
+
+	t.Setenv(EnvEncryptionKeys, "k1:"+k1+", k2:"+k2)
+	t.Setenv(EnvEncryptionPrimaryKeyID, "k2")
+	r, err := NewKeyringFromEnv()
+	assert.NoError(t, err)
+	keyID, key, err := r.GetPrimaryKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", keyID)
+	assert.Equal(t, bytes.Repeat([]byte{2}, 16), key)
+
+	// The keyring is loaded from env when none is set
+	defer SetKeyring(keyring)
+	SetKeyring(nil)
+	got, err := GetKeyring()
+	assert.NoError(t, err)
+	assert.Equal(t, r, got)
+
+	tests := []struct {
+		name         string
+		keys         string
+		primaryKeyID string
+	}{
+		{"no keys", "", "k1"},
+		{"no primary key", "k1:" + k1, ""},
+		{"unknown primary key", "k1:" + k1, "k2"},
+		{"no key ID", k1, "k1"},
+		{"not base64", "k1:not-base64", "k1"},
+		{"wrong key length", "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), "k1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvEncryptionKeys, tt.keys)
+			t.Setenv(EnvEncryptionPrimaryKeyID, tt.primaryKeyID)
+			_, err := NewKeyringFromEnv()
+			assert.Error(t, err)
+		})
+	}
+}
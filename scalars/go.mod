@@ -5,6 +5,8 @@ go 1.23.4
 require (
 	github.com/google/uuid v1.6.0
 	github.com/graph-gophers/graphql-go v1.6.0
+	github.com/stretchr/testify v1.10.0
+	github.com/teejays/gokutil/env v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/errutil v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/gopi v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/panics v0.0.0-20250426215142-5dc7bd3f1fd0
@@ -12,9 +14,12 @@ require (
 
 require (
 	github.com/Rican7/conjson v0.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/joho/godotenv v1.5.1 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/teejays/gokutil/clog v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/ctxutil v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
-	github.com/teejays/gokutil/env v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/log v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/sclog v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,591 @@
+package scalars
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/* * * * * * *
+ * Currency
+* * * * * * */
+
+// Currency is an ISO 4217 currency code e.g. `USD`.
+type Currency string
+
+const (
+	Currency_Invalid Currency = ""
+	Currency_USD     Currency = "USD"
+	Currency_EUR     Currency = "EUR"
+	Currency_GBP     Currency = "GBP"
+	Currency_JPY     Currency = "JPY"
+	Currency_PKR     Currency = "PKR"
+)
+
+// currencyMinorUnits is the number of digits after the decimal point for each currency, as per ISO 4217.
+var currencyMinorUnits = map[Currency]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BDT": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2, "COP": 2,
+	"CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0,
+	"JOD": 3, "JPY": 0, "KES": 2, "KRW": 0, "KWD": 3, "LKR": 2, "MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2, "NZD": 2,
+	"OMR": 3, "PHP": 2, "PKR": 2, "PLN": 2, "QAR": 2, "RON": 2, "RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2,
+	"TND": 3, "TRY": 2, "TWD": 2, "UAH": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+var currencyLock sync.RWMutex
+
+var defaultCurrency = Currency_USD
+
+// RegisterCurrency adds (or updates) a currency and the number of digits after its decimal point.
+func RegisterCurrency(c Currency, minorUnits int) error {
+	if len(c) != 3 || strings.ToUpper(string(c)) != string(c) {
+		return fmt.Errorf("currency code [%s] should be three upper case letters", c)
+	}
+	if minorUnits < 0 || minorUnits > 4 {
+		return fmt.Errorf("minor units [%d] for currency [%s] is out of range [0-4]", minorUnits, c)
+	}
+	currencyLock.Lock()
+	defer currencyLock.Unlock()
+	currencyMinorUnits[c] = minorUnits
+	return nil
+}
+
+// SetDefaultCurrency sets the currency of money values that are parsed without one e.g. NUMERIC database columns.
+func SetDefaultCurrency(c Currency) error {
+	if !c.IsValid() {
+		return fmt.Errorf("currency [%s] is not registered", c)
+	}
+	currencyLock.Lock()
+	defer currencyLock.Unlock()
+	defaultCurrency = c
+	return nil
+}
+
+func GetDefaultCurrency() Currency {
+	currencyLock.RLock()
+	defer currencyLock.RUnlock()
+	return defaultCurrency
+}
+
+// ParseCurrency returns the registered currency with the code, ignoring the case.
+func ParseCurrency(str string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(str)))
+	if !c.IsValid() {
+		return Currency_Invalid, fmt.Errorf("currency [%s] is not registered", str)
+	}
+	return c, nil
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
+
+func (c Currency) IsValid() bool {
+	_, err := c.GetMinorUnits()
+	return err == nil
+}
+
+// GetMinorUnits returns the number of digits after the decimal point for the currency e.g. 2 for USD and 0 for JPY.
+func (c Currency) GetMinorUnits() (int, error) {
+	currencyLock.RLock()
+	defer currencyLock.RUnlock()
+	n, ok := currencyMinorUnits[c]
+	if !ok {
+		return 0, fmt.Errorf("currency [%s] is not registered", c)
+	}
+	return n, nil
+}
+
+/* * * * * * *
+ * Rounding
+* * * * * * */
+
+// RoundingMode is how the results of Money operations are rounded to the minor unit of the currency.
+type RoundingMode int
+
+const (
+	// RoundingMode_HalfEven rounds to the nearest value, and ties to the even value (i.e. banker's rounding).
+	RoundingMode_HalfEven RoundingMode = iota
+	// RoundingMode_HalfUp rounds to the nearest value, and ties away from zero.
+	RoundingMode_HalfUp
+	// RoundingMode_HalfDown rounds to the nearest value, and ties towards zero.
+	RoundingMode_HalfDown
+	// RoundingMode_Up rounds away from zero.
+	RoundingMode_Up
+	// RoundingMode_Down rounds towards zero i.e. truncates.
+	RoundingMode_Down
+	// RoundingMode_Ceiling rounds towards positive infinity.
+	RoundingMode_Ceiling
+	// RoundingMode_Floor rounds towards negative infinity.
+	RoundingMode_Floor
+)
+
+// roundRat rounds the rational number to an integer using the mode.
+func roundRat(r *big.Rat, mode RoundingMode) (int64, error) {
+	num, den := r.Num(), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int)) // q is truncated towards zero
+	if rem.Sign() != 0 {
+		negative := r.Sign() < 0
+		// cmpHalf compares the remainder with half of the denominator
+		cmpHalf := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den)
+		var awayFromZero bool
+		switch mode {
+		case RoundingMode_HalfEven:
+			awayFromZero = cmpHalf > 0 || (cmpHalf == 0 && q.Bit(0) == 1)
+		case RoundingMode_HalfUp:
+			awayFromZero = cmpHalf >= 0
+		case RoundingMode_HalfDown:
+			awayFromZero = cmpHalf > 0
+		case RoundingMode_Up:
+			awayFromZero = true
+		case RoundingMode_Down:
+			awayFromZero = false
+		case RoundingMode_Ceiling:
+			awayFromZero = !negative
+		case RoundingMode_Floor:
+			awayFromZero = negative
+		default:
+			return 0, fmt.Errorf("invalid rounding mode [%d]", mode)
+		}
+		if awayFromZero {
+			q.Add(q, big.NewInt(int64(r.Sign())))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("amount overflows the range of money")
+	}
+	return q.Int64(), nil
+}
+
+/* * * * * * *
+ * Money
+* * * * * * */
+
+// Money is an amount in a currency. It is stored as an integer number of the minor unit of the currency (e.g. cents),
+// so it can be negative and arithmetic on it is exact. The zero value is empty.
+//
+// In the database, Money is stored as a NUMERIC amount without the currency, so only money in the default currency (see
+// SetDefaultCurrency) can be stored. Values scanned from the database take the currency of the value being scanned into
+// if it has one, or the default currency.
+// In JSON and GraphQL, it is a string with the amount and the currency e.g. `"-12.50 USD"`.
+type Money struct {
+	amount   int64
+	currency Currency
+}
+
+// NewMoney returns the money for the amount in the minor unit of the currency e.g. NewMoney(1250, Currency_USD) is
+// $12.50.
+func NewMoney(amountMinor int64, currency Currency) (Money, error) {
+	v := Money{amount: amountMinor, currency: currency}
+	if err := v.Validate(); err != nil {
+		return Money{}, err
+	}
+	return v, nil
+}
+
+// NewMoneyFromString parses money from an amount and an optional currency code, either before or after the amount
+// e.g. `12.50`, `-12.50 USD` or `USD 12.50`. If there is no currency, the default currency is used. The amount should
+// not have more digits after the decimal point than the currency allows, unless they are zeros.
+func NewMoneyFromString(str string) (Money, error) {
+	return parseMoney(str, Currency_Invalid)
+}
+
+func parseMoney(str string, currency Currency) (Money, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return Money{}, fmt.Errorf("empty string")
+	}
+	amountStr := str
+	if parts := strings.Fields(str); len(parts) == 2 {
+		codeStr := parts[1]
+		amountStr = parts[0]
+		if _, err := strconv.ParseFloat(parts[1], 64); err == nil {
+			codeStr, amountStr = parts[0], parts[1]
+		}
+		c, err := ParseCurrency(codeStr)
+		if err != nil {
+			return Money{}, fmt.Errorf("invalid string for money [%s]: %w", str, err)
+		}
+		currency = c
+	} else if len(parts) > 2 {
+		return Money{}, fmt.Errorf("invalid string for money [%s]", str)
+	}
+	if currency == Currency_Invalid {
+		currency = GetDefaultCurrency()
+	}
+	amount, err := parseAmountMinor(amountStr, currency)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid string for money [%s]: %w", str, err)
+	}
+	return NewMoney(amount, currency)
+}
+
+// parseAmountMinor parses a decimal amount e.g. `-12.50` into the minor units of the currency.
+func parseAmountMinor(str string, currency Currency) (int64, error) {
+	n, err := currency.GetMinorUnits()
+	if err != nil {
+		return 0, err
+	}
+	sign := ""
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		sign, str = str[:1], str[1:]
+	}
+	whole, frac, _ := strings.Cut(str, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("no digits")
+	}
+	for _, part := range []string{whole, frac} {
+		if strings.TrimLeft(part, "0123456789") != "" {
+			return 0, fmt.Errorf("amount [%s] is not a decimal number", str)
+		}
+	}
+	if len(frac) > n {
+		if strings.Trim(frac[n:], "0") != "" {
+			return 0, fmt.Errorf("amount [%s] has more than [%d] digits after the decimal point for currency [%s]", str, n, currency)
+		}
+		frac = frac[:n]
+	}
+	digits := sign + whole + frac + strings.Repeat("0", n-len(frac))
+	i, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return 0, fmt.Errorf("amount [%s] is not a decimal number", str)
+	}
+	if !i.IsInt64() {
+		return 0, fmt.Errorf("amount [%s] overflows the range of money", str)
+	}
+	return i.Int64(), nil
+}
+
+// MustNewMoney is like NewMoney, but panics if there is an error.
+func MustNewMoney(amountMinor int64, currency Currency) Money {
+	v, err := NewMoney(amountMinor, currency)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String returns the amount and the currency e.g. `-12.50 USD`.
+func (v Money) String() string {
+	if v.IsEmpty() {
+		return ""
+	}
+	return v.GetAmount() + " " + v.currency.String()
+}
+
+func (v Money) IsEmpty() bool {
+	return v.currency == Currency_Invalid
+}
+
+func (v Money) Validate() error {
+	if v.IsEmpty() {
+		return fmt.Errorf("is empty")
+	}
+	if !v.currency.IsValid() {
+		return fmt.Errorf("currency [%s] is not registered", v.currency)
+	}
+	return nil
+}
+
+func (v *Money) ParseString(str string) error {
+	_v, err := NewMoneyFromString(str)
+	if err != nil {
+		return err
+	}
+	*v = _v
+	return nil
+}
+
+// GetAmount returns the amount as a decimal number, with the number of digits after the decimal point of the currency
+// e.g. `-12.50` for USD and `-1250` for JPY.
+func (v Money) GetAmount() string {
+	n, _ := v.currency.GetMinorUnits()
+	s := strconv.FormatInt(v.amount, 10)
+	sign := ""
+	if v.amount < 0 {
+		sign, s = "-", s[1:]
+	}
+	if n == 0 {
+		return sign + s
+	}
+	if len(s) <= n {
+		s = strings.Repeat("0", n-len(s)+1) + s
+	}
+	return sign + s[:len(s)-n] + "." + s[len(s)-n:]
+}
+
+// GetAmountMinor returns the amount in the minor unit of the currency e.g. 1250 for $12.50.
+func (v Money) GetAmountMinor() int64 {
+	return v.amount
+}
+
+func (v Money) GetCurrency() Currency {
+	return v.currency
+}
+
+// Rat returns the amount, in the major unit of the currency, as a rational number.
+func (v Money) Rat() *big.Rat {
+	n, _ := v.currency.GetMinorUnits()
+	return new(big.Rat).SetFrac(big.NewInt(v.amount), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
+
+func (v Money) ToFloat64() float64 {
+	f, _ := v.Rat().Float64()
+	return f
+}
+
+// JSON
+
+func (v Money) MarshalJSON() ([]byte, error) {
+	if v.IsEmpty() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON accepts a string (see NewMoneyFromString), or a number in the default currency.
+func (v *Money) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		// the value did not have qoutes. Try using the raw value.
+		s = string(data)
+	}
+	if s == "" || s == "null" {
+		// money is nil
+		*v = Money{}
+		return nil
+	}
+	// Parse
+	_v, err := NewMoneyFromString(s)
+	if err != nil {
+		return err
+	}
+	*v = _v
+	return nil
+}
+
+// SQL
+
+func (v *Money) Scan(value interface{}) error {
+	if value == nil {
+		*v = Money{}
+		return nil
+	}
+
+	currency := v.currency
+	var _v Money
+	var err error
+
+	switch value := value.(type) {
+	case string:
+		_v, err = parseMoney(value, currency)
+	case []byte:
+		_v, err = parseMoney(string(value), currency)
+	case int64:
+		_v, err = parseMoney(strconv.FormatInt(value, 10), currency)
+	case float64:
+		_v, err = parseMoney(strconv.FormatFloat(value, 'f', -1, 64), currency)
+	default:
+		return fmt.Errorf("could not decode SQL db value into scalar.Money field: %v", value)
+	}
+	if err != nil {
+		return err
+	}
+	*v = _v
+	return nil
+}
+
+// Value returns the amount for a NUMERIC column. The currency is not stored, so money in a currency other than the
+// default one cannot be stored, since it would be read back in the default currency.
+func (v Money) Value() (driver.Value, error) {
+	if v.IsEmpty() {
+		return nil, nil
+	}
+	if c := GetDefaultCurrency(); v.currency != c {
+		return nil, fmt.Errorf("cannot store money [%s] in the database: only the default currency [%s] can be stored", v, c)
+	}
+	return v.GetAmount(), nil
+}
+
+// GraphQL
+
+func (v Money) ImplementsGraphQLType(name string) bool {
+	return name == "Money"
+}
+
+func (v *Money) UnmarshalGraphQL(input interface{}) error {
+	var err error
+	switch input := input.(type) {
+	case string:
+		money, err := NewMoneyFromString(input)
+		if err != nil {
+			return err
+		}
+		*v = money
+	case int32:
+		return v.UnmarshalGraphQL(strconv.FormatInt(int64(input), 10))
+	case float64:
+		return v.UnmarshalGraphQL(strconv.FormatFloat(input, 'f', -1, 64))
+	default:
+		err = fmt.Errorf("wrong type for Money: %T", input)
+	}
+	return err
+}
+
+// Arithmetic
+
+func (v Money) checkCurrency(other Money) error {
+	if v.currency != other.currency {
+		return fmt.Errorf("cannot operate on money in different currencies [%s] and [%s]", v.currency, other.currency)
+	}
+	return nil
+}
+
+func (v Money) Add(other Money) (Money, error) {
+	if err := v.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	sum := v.amount + other.amount
+	if (other.amount > 0 && sum < v.amount) || (other.amount < 0 && sum > v.amount) {
+		return Money{}, fmt.Errorf("adding [%s] to [%s] overflows the range of money", other, v)
+	}
+	return Money{amount: sum, currency: v.currency}, nil
+}
+
+func (v Money) Sub(other Money) (Money, error) {
+	if err := v.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	neg, err := other.Neg()
+	if err != nil {
+		return Money{}, fmt.Errorf("subtracting [%s] from [%s]: %w", other, v, err)
+	}
+	return v.Add(neg)
+}
+
+// Mul multiplies the amount by the factor e.g. a tax rate of big.NewRat(8, 100), rounding to the minor unit.
+func (v Money) Mul(factor *big.Rat, mode RoundingMode) (Money, error) {
+	r := new(big.Rat).Mul(new(big.Rat).SetInt64(v.amount), factor)
+	amount, err := roundRat(r, mode)
+	if err != nil {
+		return Money{}, fmt.Errorf("multiplying [%s] by [%s]: %w", v, factor.RatString(), err)
+	}
+	return Money{amount: amount, currency: v.currency}, nil
+}
+
+// Div divides the amount by the divisor, rounding to the minor unit. Use Allocate to split money without losing any
+// minor units to rounding.
+func (v Money) Div(divisor *big.Rat, mode RoundingMode) (Money, error) {
+	if divisor.Sign() == 0 {
+		return Money{}, fmt.Errorf("cannot divide money by zero")
+	}
+	r := new(big.Rat).Quo(new(big.Rat).SetInt64(v.amount), divisor)
+	amount, err := roundRat(r, mode)
+	if err != nil {
+		return Money{}, fmt.Errorf("dividing [%s] by [%s]: %w", v, divisor.RatString(), err)
+	}
+	return Money{amount: amount, currency: v.currency}, nil
+}
+
+// Allocate splits the money into parts in proportion to the ratios e.g. Allocate(70, 30). The parts always add up to
+// the original amount: the minor units left over from rounding down go to the parts with the largest remainders
+// (earlier parts first, if tied).
+func (v Money) Allocate(ratios ...int64) ([]Money, error) {
+	if len(ratios) == 0 {
+		return nil, fmt.Errorf("no ratios to allocate money to")
+	}
+	total := new(big.Int)
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, fmt.Errorf("ratio [%d] cannot be negative", r)
+		}
+		total.Add(total, big.NewInt(r))
+	}
+	if total.Sign() == 0 {
+		return nil, fmt.Errorf("ratios should add up to more than zero")
+	}
+
+	amount := new(big.Int).Abs(big.NewInt(v.amount))
+	parts := make([]Money, len(ratios))
+	remainders := make([]*big.Int, len(ratios))
+	left := new(big.Int).Set(amount)
+	for i, r := range ratios {
+		share, rem := new(big.Int).QuoRem(new(big.Int).Mul(amount, big.NewInt(r)), total, new(big.Int))
+		remainders[i] = rem
+		left.Sub(left, share)
+		parts[i] = Money{amount: share.Int64(), currency: v.currency}
+	}
+
+	// Hand out what is left, one minor unit per part
+	order := make([]int, len(ratios))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return remainders[b].Cmp(remainders[a]) })
+	for i := 0; left.Sign() > 0; i++ {
+		parts[order[i%len(order)]].amount++
+		left.Sub(left, big.NewInt(1))
+	}
+
+	if v.amount < 0 {
+		for i := range parts {
+			parts[i].amount = -parts[i].amount
+		}
+	}
+	return parts, nil
+}
+
+// Split splits the money into n parts that are as equal as possible. See Allocate.
+func (v Money) Split(n int) ([]Money, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("cannot split money into [%d] parts", n)
+	}
+	ratios := make([]int64, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return v.Allocate(ratios...)
+}
+
+// Neg returns the money with the sign of the amount flipped.
+func (v Money) Neg() (Money, error) {
+	if v.amount == math.MinInt64 {
+		return Money{}, fmt.Errorf("negating [%s] overflows the range of money", v)
+	}
+	return Money{amount: -v.amount, currency: v.currency}, nil
+}
+
+func (v Money) Abs() (Money, error) {
+	if v.amount < 0 {
+		return v.Neg()
+	}
+	return v, nil
+}
+
+func (v Money) IsZero() bool     { return v.amount == 0 }
+func (v Money) IsNegative() bool { return v.amount < 0 }
+func (v Money) IsPositive() bool { return v.amount > 0 }
+
+// Compare returns -1, 0 or 1 if the money is less than, equal to or greater than the other. Money in different
+// currencies cannot be compared.
+func (v Money) Compare(other Money) (int, error) {
+	if err := v.checkCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case v.amount < other.amount:
+		return -1, nil
+	case v.amount > other.amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Equal is true if both the amount and the currency are the same.
+func (v Money) Equal(other Money) bool {
+	return v == other
+}
@@ -0,0 +1,209 @@
+package scalars
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMoneyFromString(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{"12.50", MustNewMoney(1250, Currency_USD), false},
+		{"0.05 USD", MustNewMoney(5, Currency_USD), false},
+		{"-12.5 usd", MustNewMoney(-1250, Currency_USD), false},
+		{"EUR 7", MustNewMoney(700, Currency_EUR), false},
+		{"1250 JPY", MustNewMoney(1250, Currency_JPY), false},
+		{"12.5000 USD", MustNewMoney(1250, Currency_USD), false},
+		{".5 USD", MustNewMoney(50, Currency_USD), false},
+		{"12.505 USD", Money{}, true},
+		{"12.5 JPY", Money{}, true},
+		{"12.50 XYZ", Money{}, true},
+		{"1.2.3", Money{}, true},
+		{"abc", Money{}, true},
+		{"99999999999999999999", Money{}, true},
+		{"", Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NewMoneyFromString(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMoney_Encoding(t *testing.T) {
+	m := MustNewMoney(-5, Currency_USD)
+	assert.Equal(t, "-0.05 USD", m.String())
+	assert.Equal(t, "1250 JPY", MustNewMoney(1250, Currency_JPY).String())
+	assert.Equal(t, "0.050 KWD", MustNewMoney(50, "KWD").String())
+
+	// JSON
+	b, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, `"-0.05 USD"`, string(b))
+	b, err = json.Marshal(struct{ M Money }{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"M":null}`, string(b))
+	var got Money
+	assert.NoError(t, json.Unmarshal([]byte(`"-0.05 USD"`), &got))
+	assert.Equal(t, m, got)
+	assert.NoError(t, json.Unmarshal([]byte(`12.5`), &got))
+	assert.Equal(t, MustNewMoney(1250, Currency_USD), got)
+
+	// SQL
+	v, err := m.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "-0.05", v)
+	v, err = Money{}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+	got = Money{}
+	assert.NoError(t, got.Scan([]byte("12.5000")))
+	assert.Equal(t, MustNewMoney(1250, Currency_USD), got)
+	// The currency of the value being scanned into is kept
+	got = MustNewMoney(0, Currency_JPY)
+	assert.NoError(t, got.Scan("1250"))
+	assert.Equal(t, MustNewMoney(1250, Currency_JPY), got)
+	assert.NoError(t, got.Scan(nil))
+	assert.True(t, got.IsEmpty())
+
+	// Round trip through the database, which only stores the amount
+	for _, m := range []Money{MustNewMoney(-5, Currency_USD), MustNewMoney(100000, Currency_JPY), MustNewMoney(1234, "BHD")} {
+		v, err := m.Value()
+		if m.GetCurrency() != GetDefaultCurrency() {
+			// It would be read back in the default currency
+			assert.Error(t, err, m.String())
+			continue
+		}
+		assert.NoError(t, err)
+		got = Money{}
+		assert.NoError(t, got.Scan(v))
+		assert.Equal(t, m, got)
+	}
+
+	// GraphQL
+	assert.NoError(t, got.UnmarshalGraphQL("GBP 1.99"))
+	assert.Equal(t, MustNewMoney(199, Currency_GBP), got)
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a := MustNewMoney(1050, Currency_USD)
+	b := MustNewMoney(2000, Currency_USD)
+
+	diff, err := a.Sub(b)
+	assert.NoError(t, err)
+	assert.Equal(t, MustNewMoney(-950, Currency_USD), diff)
+
+	sum, err := diff.Add(b)
+	assert.NoError(t, err)
+	assert.Equal(t, a, sum)
+
+	_, err = a.Add(MustNewMoney(1, Currency_EUR))
+	assert.Error(t, err)
+	_, err = MustNewMoney(1<<62, Currency_USD).Add(MustNewMoney(1<<62, Currency_USD))
+	assert.Error(t, err)
+
+	// The negative of the smallest amount does not fit
+	neg, err := diff.Neg()
+	assert.NoError(t, err)
+	assert.Equal(t, MustNewMoney(950, Currency_USD), neg)
+	abs, err := diff.Abs()
+	assert.NoError(t, err)
+	assert.Equal(t, neg, abs)
+	_, err = MustNewMoney(math.MinInt64, Currency_USD).Neg()
+	assert.Error(t, err)
+	_, err = MustNewMoney(math.MinInt64, Currency_USD).Abs()
+	assert.Error(t, err)
+	_, err = a.Sub(MustNewMoney(math.MinInt64, Currency_USD))
+	assert.Error(t, err)
+
+	cmp, err := a.Compare(b)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, cmp)
+	_, err = a.Compare(MustNewMoney(1050, Currency_EUR))
+	assert.Error(t, err)
+	assert.True(t, a.Equal(MustNewMoney(1050, Currency_USD)))
+	assert.False(t, a.Equal(MustNewMoney(1050, Currency_EUR)))
+}
+
+func TestMoney_Rounding(t *testing.T) {
+	tests := []struct {
+		amount int64
+		mode   RoundingMode
+		want   int64
+	}{
+		// 25 / 10 = 2.5
+		{25, RoundingMode_HalfEven, 2},
+		{25, RoundingMode_HalfUp, 3},
+		{25, RoundingMode_HalfDown, 2},
+		{35, RoundingMode_HalfEven, 4},
+		{-25, RoundingMode_HalfEven, -2},
+		{-25, RoundingMode_HalfUp, -3},
+		// 21 / 10 = 2.1
+		{21, RoundingMode_Up, 3},
+		{21, RoundingMode_Down, 2},
+		{-21, RoundingMode_Up, -3},
+		{-21, RoundingMode_Down, -2},
+		{-21, RoundingMode_Ceiling, -2},
+		{-21, RoundingMode_Floor, -3},
+		{21, RoundingMode_Ceiling, 3},
+		{21, RoundingMode_Floor, 2},
+	}
+	for _, tt := range tests {
+		got, err := MustNewMoney(tt.amount, Currency_USD).Div(big.NewRat(10, 1), tt.mode)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got.GetAmountMinor(), "%d with mode %d", tt.amount, tt.mode)
+	}
+
+	// 8.25% tax on $19.99 is $1.649175
+	tax, err := MustNewMoney(1999, Currency_USD).Mul(big.NewRat(825, 10000), RoundingMode_HalfUp)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.65 USD", tax.String())
+
+	_, err = MustNewMoney(1, Currency_USD).Div(new(big.Rat), RoundingMode_HalfEven)
+	assert.Error(t, err)
+}
+
+func TestMoney_Allocate(t *testing.T) {
+	toMinor := func(parts []Money) []int64 {
+		var r []int64
+		for _, p := range parts {
+			r = append(r, p.GetAmountMinor())
+		}
+		return r
+	}
+
+	parts, err := MustNewMoney(100, Currency_USD).Split(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{34, 33, 33}, toMinor(parts))
+
+	parts, err = MustNewMoney(-100, Currency_USD).Split(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{-34, -33, -33}, toMinor(parts))
+
+	// The leftover goes to the largest remainder: 5 * 3/7 = 2.14, 5 * 4/7 = 2.86
+	parts, err = MustNewMoney(5, Currency_USD).Allocate(3, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, toMinor(parts))
+
+	parts, err = MustNewMoney(5, Currency_USD).Allocate(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 5}, toMinor(parts))
+
+	_, err = MustNewMoney(5, Currency_USD).Allocate(0, 0)
+	assert.Error(t, err)
+	_, err = MustNewMoney(5, Currency_USD).Allocate(1, -1)
+	assert.Error(t, err)
+}
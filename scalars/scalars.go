@@ -10,6 +10,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -43,10 +44,11 @@ var _ = []IScalar{
 	&Date{},
 	&Email{},
 	&Secret{},
+	&Money{},
 }
 
 /* * * * * * *
- * ID (UUID V4)
+ * ID (UUID)
 * * * * * * */
 
 type ID struct {
@@ -58,11 +60,55 @@ type ID struct {
 	matchString string
 }
 
-// NewID creates a new random ID.
+// IDVersion is the version of the UUIDs generated by NewID.
+type IDVersion int
+
+const (
+	// IDVersion_V4 IDs are random.
+	IDVersion_V4 IDVersion = 4
+	// IDVersion_V7 IDs start with a timestamp, so IDs generated later sort after earlier ones. This keeps inserts into
+	// B-tree indexes local, instead of spread over the whole index.
+	IDVersion_V7 IDVersion = 7
+)
+
+var idVersion = IDVersion_V4
+var idVersionLock sync.RWMutex
+
+// SetIDVersion sets the version of the UUIDs generated by NewID. The default is IDVersion_V4.
+func SetIDVersion(v IDVersion) error {
+	if v != IDVersion_V4 && v != IDVersion_V7 {
+		return fmt.Errorf("unsupported ID version [%d]", v)
+	}
+	idVersionLock.Lock()
+	defer idVersionLock.Unlock()
+	idVersion = v
+	return nil
+}
+
+func GetIDVersion() IDVersion {
+	idVersionLock.RLock()
+	defer idVersionLock.RUnlock()
+	return idVersion
+}
+
+// NewID creates a new ID, using the version set by SetIDVersion.
 func NewID() ID {
+	if GetIDVersion() == IDVersion_V7 {
+		return NewIDV7()
+	}
+	return NewIDV4()
+}
+
+// NewIDV4 creates a new random ID.
+func NewIDV4() ID {
 	return ID{UUID: uuid.New()}
 }
 
+// NewIDV7 creates a new time ordered ID.
+func NewIDV7() ID {
+	return ID{UUID: uuid.Must(uuid.NewV7())}
+}
+
 func (id ID) String() string {
 	if id.UUID != uuid.Nil {
 		return id.UUID.String()
@@ -541,6 +587,8 @@ func IsEmail(email string) bool {
  * Secret
 * * * * * * */
 
+// Secret is a string that is stored as it is. Use Encrypted for values that should be encrypted at rest, and kept out
+// of logs.
 type Secret struct {
 	GenericStringScalar
 }
@@ -681,221 +729,6 @@ func (g GenericData) LoadTo(v interface{}) error {
 	return jsonhelper.UnmarshalStrict([]byte(g.value), v)
 }
 
-/* * * * * * *
- * Money
-* * * * * * */
-
-type Money struct {
-	whole   uint
-	decimal uint
-	init    bool
-}
-
-func NewMoney(whole uint, decimal uint) (Money, error) {
-	v := Money{whole: whole, decimal: decimal, init: true}
-	if err := v.Validate(); err != nil {
-		return Money{}, err
-	}
-	return v, nil
-}
-
-func (v Money) String() string {
-	return fmt.Sprintf("%d.%d", v.whole, v.decimal)
-}
-
-func (v Money) IsEmpty() bool {
-	return !v.init
-}
-
-func (v Money) Validate() error {
-	if v.IsEmpty() {
-		return fmt.Errorf("is empty")
-	}
-
-	// cannot be negative
-	if v.whole < 0 {
-		return fmt.Errorf("whole value [%d] cannot be negative", v.whole)
-	}
-	if v.decimal < 0 {
-		return fmt.Errorf("decimal value [%d] cannot be negative", v.decimal)
-	}
-	// decimal must be between 0 and 99
-	if v.decimal > 99 {
-		return fmt.Errorf("decimal value [%d] is out of range [0-99]", v.decimal)
-	}
-	return nil
-}
-
-func (v *Money) ParseString(str string) error {
-	_v, err := NewMoneyFromString(str)
-	if err != nil {
-		return err
-	}
-	// Validate that the date is valid
-	if err := _v.Validate(); err != nil {
-		return err
-	}
-	*v = _v
-	return nil
-}
-
-// JSON
-
-func (v Money) MarshalJSON() ([]byte, error) {
-	// If the value is empty, return nil
-	if v.IsEmpty() {
-		return nil, nil
-	}
-	// Otherwise, return as a two decimal float
-	s := fmt.Sprintf("%d.%02d", v.whole, v.decimal)
-	return []byte(s), nil
-}
-
-func (v *Money) UnmarshalJSON(data []byte) error {
-	s, err := strconv.Unquote(string(data))
-	if err != nil {
-		// the value did not have qoutes. Try using the raw value.
-		s = string(data)
-	}
-	if s == "" {
-		// money is nil
-		return nil
-	}
-	// Parse
-	_v, err := NewMoneyFromString(s)
-	if err != nil {
-		return err
-	}
-	*v = _v
-	return nil
-}
-
-// SQL
-
-func (v *Money) Scan(value interface{}) error {
-	if value == nil {
-		return nil
-	}
-
-	var _v Money
-	var err error
-
-	switch v := value.(type) {
-	case string:
-		err = _v.ParseString(v)
-		if err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("could not decode SQL db value into scalar.Money field: %v", v)
-	}
-	// Validate that the date is valid
-	if err := _v.Validate(); err != nil {
-		return err
-	}
-	*v = _v
-	return nil
-}
-
-func (v Money) Value() (driver.Value, error) {
-	return fmt.Sprintf("%d.%d", v.whole, v.decimal), nil
-}
-
-// GraphQL
-
-func (v Money) ImplementsGraphQLType(name string) bool {
-	return name == "Money"
-}
-
-func (v *Money) UnmarshalGraphQL(input interface{}) error {
-	var err error
-	switch input := input.(type) {
-	case string:
-		money, err := NewMoneyFromString(input)
-		if err != nil {
-			return err
-		}
-		*v = money
-	default:
-		err = fmt.Errorf("wrong type for Money: %T", input)
-	}
-	return err
-}
-
-// Custom Methods
-
-func NewMoneyFromString(str string) (Money, error) {
-	// Expect string to be in the format "XY.MN" where XY is the whole value and MN is the decimal value
-	// split the string into whole and decimal parts
-	if str == "" {
-		return Money{}, fmt.Errorf("empty string")
-	}
-	parts := strings.Split(str, ".")
-	// expect 1 or 2 parts
-	if len(parts) > 2 || len(parts) == 0 {
-		return Money{}, fmt.Errorf("invalid string for money [%s]", str)
-	}
-
-	// Function to parse string to uint
-	var parseUint = func(s string) (uint, error) {
-		i, err := strconv.Atoi(s)
-		if err != nil {
-			return 0, fmt.Errorf("could not parse to uint: %w", err)
-		}
-		if i < 0 {
-			return 0, fmt.Errorf("value [%d] is negative", i)
-		}
-		return uint(i), nil
-	}
-
-	// Parse the whole value
-	whole, err := parseUint(parts[0])
-	if err != nil {
-		return Money{}, fmt.Errorf("Parsing whole value [%s]: %w", parts[0], err)
-	}
-
-	var decimal uint
-	if len(parts) > 1 {
-		decimal, err = parseUint(parts[1])
-		if err != nil {
-			return Money{}, fmt.Errorf("Parsing decimal value [%s]: %w", parts[1], err)
-		}
-	}
-
-	return NewMoney(whole, decimal)
-}
-
-func (v *Money) adjust() {
-	if v.decimal > 99 {
-		v.whole += v.decimal / 100
-		v.decimal = v.decimal % 100
-	}
-}
-
-func (v Money) GetWhole() uint {
-	return v.whole
-}
-
-func (v Money) GetDecimal() uint {
-	return v.decimal
-}
-
-func (v Money) ToFloat32() float32 {
-	return float32(v.whole) + float32(v.decimal)/100
-}
-
-func (v Money) ToFloat64() float64 {
-	return float64(v.whole) + float64(v.decimal)/100
-}
-
-func (v Money) Add(other Money) (Money, error) {
-	new := Money{whole: v.whole + other.whole, decimal: v.decimal + other.decimal}
-	new.adjust()
-	return new, nil
-}
-
-// Not adding sub method because it can be tricky to handle negative values
-
 /* * * * * * *
 * Link
 * * * * * * */
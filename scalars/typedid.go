@@ -0,0 +1,239 @@
+package scalars
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+/* * * * * * *
+ * TypedID
+* * * * * * */
+
+// IDPrefix names the prefix of a TypedID. It is usually implemented by an empty struct e.g.
+//
+//	type UserIDPrefix struct{}
+//	func (UserIDPrefix) IDPrefix() string { return "usr" }
+//	type UserID = scalars.TypedID[UserIDPrefix]
+type IDPrefix interface {
+	IDPrefix() string
+}
+
+// TypedID is an ID that is written with the prefix of its entity e.g. `usr_01HV9Y3K8ZQ6W0B2M4N7P5R3TX`, so IDs of
+// different entities can be told apart (in logs, URLs etc.) and cannot be mixed up when parsed. The part after the
+// prefix is the UUID in Crockford's base32 (like a ULID), so TypedIDs of UUIDv7s sort by time.
+//
+// It is stored in the database as the native UUID, using the Scan and Value methods of the embedded ID. Use the
+// embedded ID with IDCondition filters.
+type TypedID[P IDPrefix] struct {
+	ID
+}
+
+// NewTypedID creates a new ID, using the version set by SetIDVersion.
+func NewTypedID[P IDPrefix]() TypedID[P] {
+	return TypedID[P]{ID: NewID()}
+}
+
+func NewTypedIDFromID[P IDPrefix](id ID) TypedID[P] {
+	return TypedID[P]{ID: id}
+}
+
+// NewTypedIDFromString parses the ID, which should have the prefix of P.
+func NewTypedIDFromString[P IDPrefix](str string) (TypedID[P], error) {
+	var id TypedID[P]
+	if err := id.ParseString(str); err != nil {
+		return TypedID[P]{}, err
+	}
+	return id, nil
+}
+
+// NewTypedIDMatchString is like NewIDMatchString, but for a part of a TypedID e.g. `usr_01HV9`. If the part after the
+// prefix is the start of an ID, it is converted to the start of the UUID string, so it can be matched with the IDs in
+// the database. Strings without the prefix are kept as they are.
+func NewTypedIDMatchString[P IDPrefix](str string) TypedID[P] {
+	var id TypedID[P]
+	s, hasPrefix := strings.CutPrefix(str, id.Prefix()+"_")
+	if hex, ok := base32PrefixToUUIDPrefix(s); hasPrefix && ok {
+		s = hex
+	}
+	return TypedID[P]{ID: NewIDMatchString(s)}
+}
+
+// Prefix returns the prefix of the IDs of the entity, without the `_` separator.
+func (id TypedID[P]) Prefix() string {
+	var p P
+	return p.IDPrefix()
+}
+
+func (id TypedID[P]) String() string {
+	if id.UUID == uuid.Nil {
+		return id.ID.String()
+	}
+	return id.Prefix() + "_" + encodeBase32UUID(id.UUID)
+}
+
+func (id TypedID[P]) Validate() error {
+	if err := validateIDPrefix(id.Prefix()); err != nil {
+		return err
+	}
+	return id.ID.Validate()
+}
+
+func (id *TypedID[P]) ParseString(str string) error {
+	prefix := id.Prefix()
+	if err := validateIDPrefix(prefix); err != nil {
+		return err
+	}
+	s, ok := strings.CutPrefix(str, prefix+"_")
+	if !ok {
+		return fmt.Errorf("ID [%s] should have the prefix [%s_]", str, prefix)
+	}
+	uid, err := decodeBase32UUID(s)
+	if err != nil {
+		return fmt.Errorf("ID [%s] is invalid: %w", str, err)
+	}
+	*id = TypedID[P]{ID: ID{UUID: uid}}
+	return nil
+}
+
+// JSON
+
+func (id TypedID[P]) MarshalJSON() ([]byte, error) {
+	if id.IsEmpty() {
+		return []byte("null"), nil
+	}
+	return strconv.AppendQuote(nil, id.String()), nil
+}
+
+func (id *TypedID[P]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*id = TypedID[P]{}
+		return nil
+	}
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return err
+	}
+	if s == "" {
+		*id = TypedID[P]{}
+		return nil
+	}
+	return id.ParseString(s)
+}
+
+// Text (e.g. map keys), which would otherwise be the plain UUID of the embedded ID
+
+func (id TypedID[P]) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *TypedID[P]) UnmarshalText(data []byte) error {
+	return id.ParseString(string(data))
+}
+
+// GraphQL
+
+func (id *TypedID[P]) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		return id.ParseString(input)
+	default:
+		return fmt.Errorf("wrong type for ID: %T", input)
+	}
+}
+
+// validateIDPrefix checks that the prefix is lower case letters and underscores, starting and ending with a letter.
+func validateIDPrefix(prefix string) error {
+	if prefix == "" || len(prefix) > 63 {
+		return fmt.Errorf("ID prefix [%s] should have 1 to 63 characters", prefix)
+	}
+	for i, r := range prefix {
+		isEdge := i == 0 || i == len(prefix)-1
+		if !(r >= 'a' && r <= 'z') && (isEdge || r != '_') {
+			return fmt.Errorf("ID prefix [%s] should be lower case letters and underscores, starting and ending with a letter", prefix)
+		}
+	}
+	return nil
+}
+
+/* * * * * * *
+ * Base32 (Crockford)
+* * * * * * */
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// base32UUIDLength is the number of characters for the 128 bits of a UUID. The first character only has 3 bits.
+const base32UUIDLength = 26
+
+func encodeBase32UUID(uid uuid.UUID) string {
+	n := new(big.Int).SetBytes(uid[:])
+	b := make([]byte, base32UUIDLength)
+	mask := big.NewInt(31)
+	for i := base32UUIDLength - 1; i >= 0; i-- {
+		b[i] = crockfordAlphabet[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(b)
+}
+
+// decodeBase32Digits decodes the characters into a number. Lower case characters are accepted.
+func decodeBase32Digits(s string) (*big.Int, error) {
+	n := new(big.Int)
+	for _, r := range strings.ToUpper(s) {
+		i := strings.IndexRune(crockfordAlphabet, r)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid character [%c]", r)
+		}
+		n.Lsh(n, 5)
+		n.Or(n, big.NewInt(int64(i)))
+	}
+	return n, nil
+}
+
+func decodeBase32UUID(s string) (uuid.UUID, error) {
+	if len(s) != base32UUIDLength {
+		return uuid.Nil, fmt.Errorf("should have [%d] characters but has [%d]", base32UUIDLength, len(s))
+	}
+	if s[0] > '7' {
+		return uuid.Nil, fmt.Errorf("is out of range")
+	}
+	n, err := decodeBase32Digits(s)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	var uid uuid.UUID
+	n.FillBytes(uid[:])
+	return uid, nil
+}
+
+// base32PrefixToUUIDPrefix converts the start of a base32 encoded UUID into the start of the UUID string, for the bits
+// that it fully determines e.g. `01J6S` is `0191b`.
+func base32PrefixToUUIDPrefix(s string) (string, bool) {
+	if s == "" || len(s) >= base32UUIDLength || s[0] > '7' {
+		return "", false
+	}
+	n, err := decodeBase32Digits(s)
+	if err != nil {
+		return "", false
+	}
+	bits := 5*len(s) - 2 // the first character only has 3 bits
+	hexDigits := bits / 4
+	if hexDigits == 0 {
+		return "", false
+	}
+	n.Rsh(n, uint(bits-4*hexDigits))
+	hex := fmt.Sprintf("%0*x", hexDigits, n)
+
+	// Add the dashes of the UUID string format
+	var sb strings.Builder
+	for i, c := range hex {
+		if i == 8 || i == 12 || i == 16 || i == 20 {
+			sb.WriteByte('-')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String(), true
+}
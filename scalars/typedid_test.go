@@ -0,0 +1,114 @@
+package scalars
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type testUserPrefix struct{}
+
+func (testUserPrefix) IDPrefix() string { return "usr" }
+
+type testOrgPrefix struct{}
+
+func (testOrgPrefix) IDPrefix() string { return "org" }
+
+type testBadPrefix struct{}
+
+func (testBadPrefix) IDPrefix() string { return "Bad_" }
+
+func TestNewID_Version(t *testing.T) {
+	defer SetIDVersion(GetIDVersion())
+
+	assert.Equal(t, uuid.Version(4), NewID().Version())
+	assert.NoError(t, SetIDVersion(IDVersion_V7))
+	a, b := NewID(), NewID()
+	assert.Equal(t, uuid.Version(7), a.Version())
+	assert.Less(t, a.String(), b.String())
+	assert.Error(t, SetIDVersion(5))
+}
+
+func TestTypedID(t *testing.T) {
+	uid := uuid.MustParse("0191b3a4-5c6d-7e8f-9a0b-1c2d3e4f5a6b")
+	id := NewTypedIDFromID[testUserPrefix](ID{UUID: uid})
+	assert.Equal(t, "usr_01J6ST8Q3DFT7SM2RW5MZ4YPKB", id.String())
+	assert.NoError(t, id.Validate())
+
+	// Round trip, ignoring the case
+	got, err := NewTypedIDFromString[testUserPrefix](id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, id, got)
+	got, err = NewTypedIDFromString[testUserPrefix](strings.ToLower(id.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	// The prefix is checked
+	_, err = NewTypedIDFromString[testOrgPrefix](id.String())
+	assert.Error(t, err)
+	_, err = NewTypedIDFromString[testUserPrefix](uid.String())
+	assert.Error(t, err)
+	_, err = NewTypedIDFromString[testUserPrefix]("usr_81J6ST8Q3DFT7SM2RW5MZ4YPKB")
+	assert.Error(t, err)
+	_, err = NewTypedIDFromString[testUserPrefix]("usr_01J6SU")
+	assert.Error(t, err)
+	_, err = NewTypedIDFromString[testBadPrefix]("Bad__01J6ST8Q3DFT7SM2RW5MZ4YPKB")
+	assert.Error(t, err)
+
+	// Time ordered IDs stay ordered
+	defer SetIDVersion(GetIDVersion())
+	assert.NoError(t, SetIDVersion(IDVersion_V7))
+	a, b := NewTypedID[testUserPrefix](), NewTypedID[testUserPrefix]()
+	assert.Less(t, a.String(), b.String())
+}
+
+func TestTypedID_Encoding(t *testing.T) {
+	id := NewTypedIDFromID[testUserPrefix](NewIDV4())
+
+	// JSON
+	b, err := json.Marshal(map[string]TypedID[testUserPrefix]{"id": id})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"`+id.String()+`"}`, string(b))
+	var got map[string]TypedID[testUserPrefix]
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, id, got["id"])
+	var org TypedID[testOrgPrefix]
+	assert.Error(t, json.Unmarshal([]byte(`"`+id.String()+`"`), &org))
+
+	// Map keys use the typed form too
+	b, err = json.Marshal(map[TypedID[testUserPrefix]]int{id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"`+id.String()+`":1}`, string(b))
+
+	// SQL uses the native UUID
+	v, err := id.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, id.UUID.String(), v)
+	var scanned TypedID[testUserPrefix]
+	assert.NoError(t, scanned.Scan(id.UUID.String()))
+	assert.Equal(t, id, scanned)
+
+	// GraphQL
+	var gql TypedID[testUserPrefix]
+	assert.True(t, gql.ImplementsGraphQLType("ID"))
+	assert.NoError(t, gql.UnmarshalGraphQL(id.String()))
+	assert.Equal(t, id, gql)
+}
+
+func TestNewTypedIDMatchString(t *testing.T) {
+	id := NewTypedIDFromID[testUserPrefix](ID{UUID: uuid.MustParse("0191b3a4-5c6d-7e8f-9a0b-1c2d3e4f5a6b")})
+
+	// The start of a typed ID matches the start of the UUID
+	for _, n := range []int{5, 10, 14, 20, 25} {
+		m := NewTypedIDMatchString[testUserPrefix](id.String()[:4+n])
+		assert.True(t, strings.HasPrefix(id.UUID.String(), m.String()), "%d: %s", n, m.String())
+	}
+	assert.Equal(t, "0191b", NewTypedIDMatchString[testUserPrefix]("usr_01J6S").String())
+
+	// Other strings are kept as they are
+	assert.Equal(t, "3e4f", NewTypedIDMatchString[testUserPrefix]("3e4f").String())
+	assert.Equal(t, "zz", NewTypedIDMatchString[testUserPrefix]("usr_zz").String())
+}
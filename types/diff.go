@@ -0,0 +1,294 @@
+package types
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/teejays/gokutil/naam"
+)
+
+/* * * * * *
+ * Diff
+ * * * * * */
+
+// FieldPath is the path to a value within an object, using the JSON names e.g. `["address", "lines", "0"]`.
+type FieldPath []string
+
+// String returns the path joined by dots e.g. `address.lines.0`.
+func (p FieldPath) String() string {
+	return strings.Join(p, ".")
+}
+
+// JSONPointer returns the path as an RFC 6901 JSON Pointer e.g. `/address/lines/0`.
+func (p FieldPath) JSONPointer() string {
+	var sb strings.Builder
+	for _, s := range p {
+		sb.WriteString("/")
+		sb.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(s))
+	}
+	return sb.String()
+}
+
+// FieldDiff is a single difference between two values. Old is nil if the value was added, and New is nil if it was
+// removed. The values are decoded JSON i.e. map[string]interface{}, []interface{}, string, json.Number, bool or nil.
+type FieldDiff struct {
+	Path FieldPath
+	Old  interface{}
+	New  interface{}
+}
+
+// IRedactedValue is implemented by values that are redacted in JSON, such as scalars.Encrypted. Their JSON encodings
+// do not change with their values, so Diff compares them using EqualRedacted.
+type IRedactedValue interface {
+	EqualRedacted(other interface{}) bool
+}
+
+// Diff returns the differences between the JSON encodings of the two values, at the deepest path at which they differ.
+// Arrays of different lengths are reported as a whole. The diffs are sorted by path.
+//
+// Changes to IRedactedValue values (within structs, pointers and arrays) are also reported, with their redacted JSON as
+// the Old and New values.
+func Diff[T any](old, new T) ([]FieldDiff, error) {
+	oldDoc, err := toJSONDoc(old)
+	if err != nil {
+		return nil, fmt.Errorf("encoding old value: %w", err)
+	}
+	newDoc, err := toJSONDoc(new)
+	if err != nil {
+		return nil, fmt.Errorf("encoding new value: %w", err)
+	}
+	diffs := diffJSONDocs(nil, oldDoc, newDoc)
+
+	var added bool
+	for _, p := range diffRedactedValues(nil, reflect.ValueOf(old), reflect.ValueOf(new)) {
+		if slices.ContainsFunc(diffs, func(d FieldDiff) bool { return slices.Equal(d.Path, p) }) {
+			continue
+		}
+		oldValue, _ := jsonDocGet(oldDoc, p)
+		newValue, _ := jsonDocGet(newDoc, p)
+		diffs = append(diffs, FieldDiff{Path: p, Old: oldValue, New: newValue})
+		added = true
+	}
+	if added {
+		slices.SortStableFunc(diffs, func(a, b FieldDiff) int { return compareFieldPaths(a.Path, b.Path) })
+	}
+	return diffs, nil
+}
+
+// GetChangedFields returns the fields that have a diff, in the order of fields. Fields are matched to the top level of
+// the diff paths by name.
+func GetChangedFields[F Field](fields []F, diffs []FieldDiff) []F {
+	var changed []F
+	for _, f := range fields {
+		if slices.ContainsFunc(diffs, func(d FieldDiff) bool { return len(d.Path) > 0 && f.Name().EqualString(d.Path[0]) }) {
+			changed = append(changed, f)
+		}
+	}
+	return changed
+}
+
+// GetFieldByJSONName returns the field with the name e.g. `createdAt` for the field `created_at`.
+func GetFieldByJSONName[F Field](fields []F, name string) (F, bool) {
+	key := naam.New(name)
+	for _, f := range fields {
+		if f.Name().Equal(key) {
+			return f, true
+		}
+	}
+	var zero F
+	return zero, false
+}
+
+// diffRedactedValues returns the paths of the IRedactedValue values that differ between a and b, which are of the
+// same type. The paths use the JSON names of the struct fields.
+func diffRedactedValues(path FieldPath, a, b reflect.Value) []FieldPath {
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() {
+		return nil
+	}
+	if a.CanInterface() {
+		if r, ok := a.Interface().(IRedactedValue); ok {
+			if r.EqualRedacted(b.Interface()) {
+				return nil
+			}
+			return []FieldPath{path}
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			// The JSON encodings differ unless both are nil
+			return nil
+		}
+		return diffRedactedValues(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		var paths []FieldPath
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			if sf.Anonymous && name == "" {
+				// Fields of embedded structs are at the same level
+				paths = append(paths, diffRedactedValues(path, a.Field(i), b.Field(i))...)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			paths = append(paths, diffRedactedValues(append(slices.Clone(path), name), a.Field(i), b.Field(i))...)
+		}
+		return paths
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return nil
+		}
+		var paths []FieldPath
+		for i := 0; i < a.Len(); i++ {
+			paths = append(paths, diffRedactedValues(append(slices.Clone(path), strconv.Itoa(i)), a.Index(i), b.Index(i))...)
+		}
+		return paths
+	}
+	return nil
+}
+
+// compareFieldPaths orders paths like diffJSONDocs does, comparing array indexes as numbers.
+func compareFieldPaths(a, b FieldPath) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		ai, aerr := strconv.Atoi(a[i])
+		bi, berr := strconv.Atoi(b[i])
+		if aerr == nil && berr == nil {
+			return cmp.Compare(ai, bi)
+		}
+		return strings.Compare(a[i], b[i])
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func diffJSONDocs(path FieldPath, a, b interface{}) []FieldDiff {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		var keys []string
+		for k := range a {
+			keys = append(keys, k)
+		}
+		for k := range b {
+			if _, exists := a[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		var diffs []FieldDiff
+		for _, k := range keys {
+			av, aok := a[k]
+			bv, bok := b[k]
+			p := append(slices.Clone(path), k)
+			switch {
+			case !aok:
+				diffs = append(diffs, FieldDiff{Path: p, New: bv})
+			case !bok:
+				diffs = append(diffs, FieldDiff{Path: p, Old: av})
+			default:
+				diffs = append(diffs, diffJSONDocs(p, av, bv)...)
+			}
+		}
+		return diffs
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+		var diffs []FieldDiff
+		for i := range a {
+			diffs = append(diffs, diffJSONDocs(append(slices.Clone(path), strconv.Itoa(i)), a[i], b[i])...)
+		}
+		return diffs
+	}
+	if jsonDocsEqual(a, b) {
+		return nil
+	}
+	return []FieldDiff{{Path: path, Old: a, New: b}}
+}
+
+// jsonDocsEqual compares two decoded JSON values. Numbers are compared by value, so `1` equals `1.0`.
+func jsonDocsEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !jsonDocsEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonDocsEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if a == b {
+			return true
+		}
+		// Compared exactly, since large integers e.g. IDs can differ and still have the same float64
+		ar, aok := new(big.Rat).SetString(a.String())
+		br, bok := new(big.Rat).SetString(b.String())
+		return aok && bok && ar.Cmp(br) == 0
+	}
+	return a == b
+}
+
+func toJSONDoc(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONDoc(b)
+}
+
+func decodeJSONDoc(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return doc, nil
+}
@@ -0,0 +1,95 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/scalars"
+)
+
+type testAddress struct {
+	City  string   `json:"city"`
+	Lines []string `json:"lines"`
+}
+
+type testPerson struct {
+	Name    string       `json:"name"`
+	Age     int          `json:"age"`
+	Tags    []string     `json:"tags"`
+	Address *testAddress `json:"address"`
+}
+
+func TestDiff(t *testing.T) {
+	old := testPerson{Name: "a", Age: 1, Tags: []string{"x", "y"}, Address: &testAddress{City: "c", Lines: []string{"1"}}}
+	new := testPerson{Name: "a", Age: 2, Tags: []string{"x", "z"}, Address: &testAddress{City: "d", Lines: []string{"1", "2"}}}
+
+	diffs, err := Diff(old, new)
+	assert.NoError(t, err)
+	var paths []string
+	for _, d := range diffs {
+		paths = append(paths, d.Path.String())
+	}
+	assert.Equal(t, []string{"address.city", "address.lines", "age", "tags.1"}, paths)
+	assert.Equal(t, "/address/city", diffs[0].Path.JSONPointer())
+
+	diffs, err = Diff(old, old)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// Removed values have no New
+	diffs, err = Diff(map[string]int{"a": 1}, map[string]int{})
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldDiff{{Path: FieldPath{"a"}, Old: json.Number("1")}}, diffs)
+
+	// Numbers are compared by value, and exactly
+	assert.True(t, jsonDocsEqual(json.Number("1"), json.Number("1.0")))
+	assert.True(t, jsonDocsEqual(json.Number("100"), json.Number("1e2")))
+	diffs, err = Diff(map[string]int64{"a": 1 << 60}, map[string]int64{"a": 1<<60 + 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldDiff{{Path: FieldPath{"a"}, Old: json.Number("1152921504606846976"), New: json.Number("1152921504606846977")}}, diffs)
+}
+
+type testAccount struct {
+	Name    string                    `json:"name"`
+	Secret  scalars.Encrypted[string] `json:"secret"`
+	Backups []scalars.Encrypted[int]  `json:"backups"`
+}
+
+func TestDiff_Encrypted(t *testing.T) {
+	old := testAccount{Name: "a", Secret: scalars.NewEncrypted("s3cret"), Backups: []scalars.Encrypted[int]{scalars.NewEncrypted(1), scalars.NewEncrypted(2)}}
+
+	diffs, err := Diff(old, old)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// Encrypted values look the same in JSON, but their changes are reported
+	new := old
+	new.Name = "b"
+	new.Secret = scalars.NewEncrypted("n3w")
+	new.Backups = []scalars.Encrypted[int]{scalars.NewEncrypted(1), scalars.NewEncrypted(3)}
+	diffs, err = Diff(old, new)
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldDiff{
+		{Path: FieldPath{"backups", "1"}, Old: scalars.Redacted, New: scalars.Redacted},
+		{Path: FieldPath{"name"}, Old: "a", New: "b"},
+		{Path: FieldPath{"secret"}, Old: scalars.Redacted, New: scalars.Redacted},
+	}, diffs)
+
+	// Setting an empty value is reported once
+	diffs, err = Diff(testAccount{}, testAccount{Secret: scalars.NewEncrypted("s3cret")})
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldDiff{{Path: FieldPath{"secret"}, Old: nil, New: scalars.Redacted}}, diffs)
+}
+
+func TestGetChangedFields(t *testing.T) {
+	diffs, err := Diff(map[string]int{"1": 1, "2": 2}, map[string]int{"1": 1, "2": 3, "3": 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []TestField{TestField_2, TestField_3}, GetChangedFields([]TestField{TestField_1, TestField_2, TestField_3}, diffs))
+
+	f, ok := GetFieldByJSONName([]TestField{TestField_1, TestField_2}, "2")
+	assert.True(t, ok)
+	assert.Equal(t, TestField_2, f)
+	_, ok = GetFieldByJSONName([]TestField{TestField_1, TestField_2}, "3")
+	assert.False(t, ok)
+}
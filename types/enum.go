@@ -0,0 +1,322 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+	"github.com/teejays/gokutil/naam"
+)
+
+/* * * * * *
+ * EnumOf
+ * * * * * */
+
+// EnumValue pairs a value of an enum with its name, for RegisterEnum.
+type EnumValue[T ~int] struct {
+	Value T
+	Name  string
+}
+
+type enumInfo struct {
+	graphQLName string
+	values      []int // in the order of registration
+	names       map[int]string
+}
+
+var enums = map[reflect.Type]*enumInfo{}
+var enumsLock sync.RWMutex
+
+// RegisterEnum registers the values of the enum T and their names. graphQLName is the name of the enum in the GraphQL
+// schema. Each enum can only be registered once, and should be registered (usually in an init func) before EnumOf[T]
+// is used. The zero value of T does not need to be registered: if it isn't, it is treated as unset (i.e. NULL).
+func RegisterEnum[T ~int](graphQLName string, values ...EnumValue[T]) error {
+	t := reflect.TypeFor[T]()
+	if len(values) == 0 {
+		return fmt.Errorf("enum [%s] has no values", t)
+	}
+	info := &enumInfo{graphQLName: graphQLName, names: map[int]string{}}
+	seenNames := map[string]bool{}
+	for _, v := range values {
+		if v.Name == "" {
+			return fmt.Errorf("enum [%s] value [%d] has no name", t, v.Value)
+		}
+		if _, exists := info.names[int(v.Value)]; exists {
+			return fmt.Errorf("enum [%s] value [%d] is registered more than once", t, v.Value)
+		}
+		if seenNames[v.Name] {
+			return fmt.Errorf("enum [%s] name [%s] is registered more than once", t, v.Name)
+		}
+		seenNames[v.Name] = true
+		info.values = append(info.values, int(v.Value))
+		info.names[int(v.Value)] = v.Name
+	}
+
+	enumsLock.Lock()
+	defer enumsLock.Unlock()
+	if _, exists := enums[t]; exists {
+		return fmt.Errorf("enum [%s] is already registered", t)
+	}
+	enums[t] = info
+	return nil
+}
+
+// MustRegisterEnum is like RegisterEnum, but panics if there is an error. It is meant for init funcs.
+func MustRegisterEnum[T ~int](graphQLName string, values ...EnumValue[T]) {
+	if err := RegisterEnum(graphQLName, values...); err != nil {
+		panic(err)
+	}
+}
+
+func getEnumInfo[T ~int]() (*enumInfo, error) {
+	enumsLock.RLock()
+	defer enumsLock.RUnlock()
+	info, ok := enums[reflect.TypeFor[T]()]
+	if !ok {
+		return nil, fmt.Errorf("enum [%s] is not registered", reflect.TypeFor[T]())
+	}
+	return info, nil
+}
+
+// EnumOf implements Enum for any int based enum T that is registered using RegisterEnum, so enums do not have to
+// implement the methods themselves. It is stored in the database, and encoded in JSON, YAML and GraphQL, by its name.
+type EnumOf[T ~int] struct {
+	v T
+}
+
+var _ Enum = (*EnumOf[int])(nil)
+
+func NewEnum[T ~int](v T) EnumOf[T] {
+	return EnumOf[T]{v: v}
+}
+
+// ParseEnum returns the enum with the name. Names are matched exactly first, and then ignoring the case and
+// separators (e.g. `in_progress` matches `IN_PROGRESS` and `InProgress`).
+func ParseEnum[T ~int](name string) (EnumOf[T], error) {
+	info, err := getEnumInfo[T]()
+	if err != nil {
+		return EnumOf[T]{}, err
+	}
+	for _, v := range info.values {
+		if info.names[v] == name {
+			return EnumOf[T]{v: T(v)}, nil
+		}
+	}
+	for _, v := range info.values {
+		if naam.New(info.names[v]).EqualString(name) {
+			return EnumOf[T]{v: T(v)}, nil
+		}
+	}
+	return EnumOf[T]{}, fmt.Errorf("invalid value [%s] for enum [%s]", name, reflect.TypeFor[T]())
+}
+
+// EnumValues returns the valid values of the enum, in the order they were registered.
+func EnumValues[T ~int]() []EnumOf[T] {
+	info, err := getEnumInfo[T]()
+	if err != nil {
+		return nil
+	}
+	var values []EnumOf[T]
+	for _, v := range info.values {
+		values = append(values, EnumOf[T]{v: T(v)})
+	}
+	return values
+}
+
+// EnumNames returns the names of the valid values of the enum, in the order they were registered.
+func EnumNames[T ~int]() []string {
+	info, err := getEnumInfo[T]()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, v := range info.values {
+		names = append(names, info.names[v])
+	}
+	return names
+}
+
+// Get returns the underlying value.
+func (e EnumOf[T]) Get() T {
+	return e.v
+}
+
+// IsValid is true if the value is registered.
+func (e EnumOf[T]) IsValid() bool {
+	_, ok := e.name()
+	return ok
+}
+
+// IsEmpty is true for the zero value, if it is not registered.
+func (e EnumOf[T]) IsEmpty() bool {
+	return e.v == 0 && !e.IsValid()
+}
+
+// Validate returns an error if the value is not registered.
+func (e EnumOf[T]) Validate() error {
+	if !e.IsValid() {
+		return fmt.Errorf("invalid value [%d] for enum [%s], expected one of %v", e.v, reflect.TypeFor[T](), EnumNames[T]())
+	}
+	return nil
+}
+
+func (e EnumOf[T]) name() (string, bool) {
+	info, err := getEnumInfo[T]()
+	if err != nil {
+		return "", false
+	}
+	name, ok := info.names[int(e.v)]
+	return name, ok
+}
+
+func (e EnumOf[T]) String() string {
+	if name, ok := e.name(); ok {
+		return name
+	}
+	return fmt.Sprintf("%s(%d)", reflect.TypeFor[T]().Name(), e.v)
+}
+
+func (e EnumOf[T]) Name() naam.Name {
+	if name, ok := e.name(); ok {
+		return naam.New(name)
+	}
+	return naam.Name{}
+}
+
+// Database
+
+func (e EnumOf[T]) Value() (driver.Value, error) {
+	if e.IsEmpty() {
+		return nil, nil
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e.String(), nil
+}
+
+func (e *EnumOf[T]) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*e = EnumOf[T]{}
+		return nil
+	case string:
+		return e.FromString(src)
+	case []byte:
+		return e.FromString(string(src))
+	case int64:
+		v := EnumOf[T]{v: T(src)}
+		if err := v.Validate(); err != nil {
+			return err
+		}
+		*e = v
+		return nil
+	}
+	return fmt.Errorf("cannot scan [%T] into enum [%s]", src, reflect.TypeFor[T]())
+}
+
+// FromString sets the value to the enum with the name (see ParseEnum). An empty string sets the zero value.
+func (e *EnumOf[T]) FromString(str string) error {
+	if str == "" {
+		*e = EnumOf[T]{}
+		return nil
+	}
+	v, err := ParseEnum[T](str)
+	if err != nil {
+		return err
+	}
+	*e = v
+	return nil
+}
+
+// JSON
+
+func (e EnumOf[T]) MarshalJSON() ([]byte, error) {
+	if e.IsEmpty() {
+		return []byte("null"), nil
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(e.String())
+}
+
+func (e *EnumOf[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*e = EnumOf[T]{}
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("enum [%s] should be a string: %w", reflect.TypeFor[T](), err)
+	}
+	return e.FromString(str)
+}
+
+// JSONSchema lists the names of the enum, so they show up in the schemas generated using invopop/jsonschema.
+func (e EnumOf[T]) JSONSchema() *jsonschema.Schema {
+	schema := &jsonschema.Schema{Type: "string"}
+	for _, name := range EnumNames[T]() {
+		schema.Enum = append(schema.Enum, name)
+	}
+	return schema
+}
+
+// Text
+
+func (e EnumOf[T]) MarshalText() ([]byte, error) {
+	if e.IsEmpty() {
+		return []byte{}, nil
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(e.String()), nil
+}
+
+func (e *EnumOf[T]) UnmarshalText(data []byte) error {
+	return e.FromString(string(data))
+}
+
+// YAML
+
+func (e EnumOf[T]) MarshalYAML() (interface{}, error) {
+	if e.IsEmpty() {
+		return nil, nil
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e.String(), nil
+}
+
+func (e *EnumOf[T]) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var str string
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	return e.FromString(str)
+}
+
+// GraphQL
+
+// ImplementsGraphQLType maps the enum to the GraphQL enum with the name it was registered with.
+func (e EnumOf[T]) ImplementsGraphQLType(name string) bool {
+	info, err := getEnumInfo[T]()
+	if err != nil {
+		return false
+	}
+	return name == info.graphQLName
+}
+
+func (e *EnumOf[T]) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		return e.FromString(input)
+	default:
+		return fmt.Errorf("wrong type for enum [%s]: %T", reflect.TypeFor[T](), input)
+	}
+}
@@ -0,0 +1,141 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type testStatus int
+
+const (
+	testStatus_Invalid testStatus = iota
+	testStatus_Pending
+	testStatus_InProgress
+	testStatus_Done
+)
+
+func init() {
+	MustRegisterEnum("TestStatus",
+		EnumValue[testStatus]{Value: testStatus_Pending, Name: "PENDING"},
+		EnumValue[testStatus]{Value: testStatus_InProgress, Name: "IN_PROGRESS"},
+		EnumValue[testStatus]{Value: testStatus_Done, Name: "DONE"},
+	)
+}
+
+func TestRegisterEnum(t *testing.T) {
+	type other int
+	assert.Error(t, RegisterEnum[other]("Other"))
+	assert.Error(t, RegisterEnum("Other", EnumValue[other]{Value: 1, Name: "A"}, EnumValue[other]{Value: 1, Name: "B"}))
+	assert.Error(t, RegisterEnum("Other", EnumValue[other]{Value: 1, Name: "A"}, EnumValue[other]{Value: 2, Name: "A"}))
+	assert.Error(t, RegisterEnum("TestStatus", EnumValue[testStatus]{Value: 1, Name: "A"}))
+
+	// Unregistered enums are never valid
+	assert.False(t, NewEnum(other(1)).IsValid())
+	assert.Nil(t, EnumNames[other]())
+}
+
+func TestEnumOf(t *testing.T) {
+	assert.Equal(t, []string{"PENDING", "IN_PROGRESS", "DONE"}, EnumNames[testStatus]())
+	assert.Equal(t, []EnumOf[testStatus]{NewEnum(testStatus_Pending), NewEnum(testStatus_InProgress), NewEnum(testStatus_Done)}, EnumValues[testStatus]())
+
+	e := NewEnum(testStatus_InProgress)
+	assert.Equal(t, testStatus_InProgress, e.Get())
+	assert.Equal(t, "IN_PROGRESS", e.String())
+	assert.Equal(t, "in_progress", e.Name().ToSnake())
+	assert.True(t, e.ImplementsGraphQLType("TestStatus"))
+	assert.NoError(t, e.Validate())
+
+	invalid := NewEnum(testStatus(9))
+	assert.Equal(t, "testStatus(9)", invalid.String())
+	assert.Error(t, invalid.Validate())
+	_, err := invalid.Value()
+	assert.Error(t, err)
+	_, err = json.Marshal(invalid)
+	assert.Error(t, err)
+
+	// The unregistered zero value is unset
+	var zero EnumOf[testStatus]
+	assert.True(t, zero.IsEmpty())
+	v, err := zero.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestEnumOf_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    testStatus
+		wantErr bool
+	}{
+		{"exact", "IN_PROGRESS", testStatus_InProgress, false},
+		{"snake case", "in_progress", testStatus_InProgress, false},
+		{"pascal case", "InProgress", testStatus_InProgress, false},
+		{"unknown", "CANCELLED", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEnum[testStatus](tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Get())
+		})
+	}
+}
+
+func TestEnumOf_Encoding(t *testing.T) {
+	type doc struct {
+		Status EnumOf[testStatus]  `json:"status" yaml:"status"`
+		Other  *EnumOf[testStatus] `json:"other" yaml:"other"`
+	}
+
+	// JSON
+	b, err := json.Marshal(doc{Status: NewEnum(testStatus_Done)})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"status":"DONE","other":null}`, string(b))
+	var d doc
+	assert.NoError(t, json.Unmarshal([]byte(`{"status":"PENDING","other":null}`), &d))
+	assert.Equal(t, testStatus_Pending, d.Status.Get())
+	assert.Error(t, json.Unmarshal([]byte(`{"status":"NOPE"}`), &d))
+	assert.Error(t, json.Unmarshal([]byte(`{"status":1}`), &d))
+
+	// YAML
+	b, err = yaml.Marshal(doc{Status: NewEnum(testStatus_InProgress)})
+	assert.NoError(t, err)
+	assert.Equal(t, "status: IN_PROGRESS\nother: null\n", string(b))
+	d = doc{}
+	assert.NoError(t, yaml.Unmarshal([]byte("status: done\n"), &d))
+	assert.Equal(t, testStatus_Done, d.Status.Get())
+
+	// Text, e.g. as map keys
+	b, err = json.Marshal(map[EnumOf[testStatus]]int{NewEnum(testStatus_Pending): 1})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"PENDING":1}`, string(b))
+
+	// Database
+	v, err := NewEnum(testStatus_Done).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "DONE", v)
+	var e EnumOf[testStatus]
+	assert.NoError(t, e.Scan([]byte("PENDING")))
+	assert.Equal(t, testStatus_Pending, e.Get())
+	assert.NoError(t, e.Scan(int64(3)))
+	assert.Equal(t, testStatus_Done, e.Get())
+	assert.Error(t, e.Scan(int64(7)))
+	assert.NoError(t, e.Scan(nil))
+	assert.True(t, e.IsEmpty())
+
+	// GraphQL
+	assert.NoError(t, e.UnmarshalGraphQL("IN_PROGRESS"))
+	assert.Equal(t, testStatus_InProgress, e.Get())
+	assert.Error(t, e.UnmarshalGraphQL(2))
+
+	// JSON Schema
+	assert.Equal(t, []interface{}{"PENDING", "IN_PROGRESS", "DONE"}, e.JSONSchema().Enum)
+}
@@ -0,0 +1,272 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"sync"
+
+	"github.com/teejays/gokutil/log"
+	"github.com/teejays/gokutil/naam"
+	"github.com/teejays/gokutil/scalars"
+)
+
+/* * * * * *
+ * Events
+ * * * * * */
+
+type EventType string
+
+const (
+	EventType_Invalid EventType = ""
+	EventType_Created EventType = "created"
+	EventType_Updated EventType = "updated"
+	EventType_Deleted EventType = "deleted"
+)
+
+// Event is published for an object of a type once a change to it has been committed. New is set for Created and
+// Updated events, and Old is set for Updated and Deleted events.
+type Event[T BasicType] struct {
+	Type     EventType
+	TypeName naam.Name
+	ID       scalars.ID
+	Old      T
+	New      T
+	At       scalars.Timestamp
+}
+
+func NewCreatedEvent[T BasicType](typeName naam.Name, elem T) Event[T] {
+	return Event[T]{Type: EventType_Created, TypeName: typeName, ID: elem.GetID(), New: elem, At: scalars.NewTimestampNow()}
+}
+
+func NewUpdatedEvent[T BasicType](typeName naam.Name, old, new T) Event[T] {
+	return Event[T]{Type: EventType_Updated, TypeName: typeName, ID: new.GetID(), Old: old, New: new, At: scalars.NewTimestampNow()}
+}
+
+func NewDeletedEvent[T BasicType](typeName naam.Name, old T) Event[T] {
+	return Event[T]{Type: EventType_Deleted, TypeName: typeName, ID: old.GetID(), Old: old, At: scalars.NewTimestampNow()}
+}
+
+// EventHandler handles the events of a type. Unlike hooks, handlers cannot change the data or fail the request that
+// led to the event: errors and panics are logged.
+type EventHandler[T BasicType] func(context.Context, Event[T]) error
+
+type SubscribeOptions struct {
+	// Name is used in the logs. Defaults to the type name.
+	Name string
+	// EventTypes limits the events that are handled. All events are handled if empty.
+	EventTypes []EventType
+	// Async handlers run on a pool of workers, so the publisher does not wait for them. Sync handlers run before
+	// Publish returns.
+	Async bool
+	// Workers is the number of workers for an async handler. Defaults to 1, which handles the events in order.
+	Workers int
+	// QueueSize is the number of events that can wait for an async handler. Events are dropped (and logged) when the
+	// queue is full. Defaults to DEFAULT_EVENT_QUEUE_SIZE.
+	QueueSize int
+}
+
+const DEFAULT_EVENT_QUEUE_SIZE = 1000
+
+type queuedEvent struct {
+	ctx   context.Context
+	event interface{}
+}
+
+type subscription struct {
+	id         int
+	name       string
+	eventTypes []EventType
+	handle     func(context.Context, interface{}) error
+
+	// Async only
+	queueLock sync.RWMutex // guards sending on the queue against closing it
+	queue     chan queuedEvent
+	stopped   bool
+	wg        sync.WaitGroup
+}
+
+// EventBus delivers the events of each type to its subscribers. It is safe for concurrent use.
+type EventBus struct {
+	lock   sync.RWMutex
+	subs   map[naam.Name][]*subscription
+	nextID int
+	closed bool
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[naam.Name][]*subscription{}}
+}
+
+var eventBus = NewEventBus()
+var eventBusLock sync.RWMutex
+
+// SetEventBus sets the bus that dalutil publishes the lifecycle events to.
+func SetEventBus(b *EventBus) {
+	eventBusLock.Lock()
+	defer eventBusLock.Unlock()
+	eventBus = b
+}
+
+func GetEventBus() *EventBus {
+	eventBusLock.RLock()
+	defer eventBusLock.RUnlock()
+	return eventBus
+}
+
+// Subscribe adds a handler for the events of the type with the name. It returns a func that removes the handler, and
+// waits for its queued events to be handled. The func can be called after the bus is closed. An async handler should
+// not call its own unsubscribe func, since it would wait for itself: it can run it in a new goroutine instead.
+func Subscribe[T BasicType](bus *EventBus, typeName naam.Name, handler EventHandler[T], opts SubscribeOptions) (func(), error) {
+	if handler == nil {
+		return nil, fmt.Errorf("event handler is nil")
+	}
+	if opts.Name == "" {
+		opts.Name = typeName.String()
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize < 1 {
+		opts.QueueSize = DEFAULT_EVENT_QUEUE_SIZE
+	}
+
+	sub := &subscription{
+		name:       opts.Name,
+		eventTypes: opts.EventTypes,
+		handle: func(ctx context.Context, e interface{}) error {
+			event, ok := e.(Event[T])
+			if !ok {
+				return fmt.Errorf("expected event of type [%T] but got [%T]", Event[T]{}, e)
+			}
+			return handler(ctx, event)
+		},
+	}
+
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	if bus.closed {
+		return nil, fmt.Errorf("event bus is closed")
+	}
+	bus.nextID++
+	sub.id = bus.nextID
+	if opts.Async {
+		sub.queue = make(chan queuedEvent, opts.QueueSize)
+		for i := 0; i < opts.Workers; i++ {
+			sub.wg.Add(1)
+			go func() {
+				defer sub.wg.Done()
+				for qe := range sub.queue {
+					sub.run(qe.ctx, qe.event)
+				}
+			}()
+		}
+	}
+	bus.subs[typeName] = append(bus.subs[typeName], sub)
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			bus.lock.Lock()
+			bus.subs[typeName] = slices.DeleteFunc(slices.Clone(bus.subs[typeName]), func(s *subscription) bool { return s.id == sub.id })
+			bus.lock.Unlock()
+			sub.stop()
+		})
+	}
+	return unsubscribe, nil
+}
+
+// Publish delivers the event to the subscribers of its type. Sync handlers run before it returns, and async handlers
+// are queued. It never fails: handler errors and panics are logged.
+//
+// The bus is not locked while the handlers run, so a sync handler can publish events, subscribe, or unsubscribe.
+func Publish[T BasicType](ctx context.Context, bus *EventBus, event Event[T]) {
+	bus.lock.RLock()
+	if bus.closed {
+		bus.lock.RUnlock()
+		log.Warn(ctx, "Event published on a closed event bus", "type", event.TypeName, "event", event.Type)
+		return
+	}
+	// Unsubscribe replaces the list rather than changing it, so it can be used after unlocking
+	subs := bus.subs[event.TypeName]
+	bus.lock.RUnlock()
+
+	for _, sub := range subs {
+		if len(sub.eventTypes) > 0 && !slices.Contains(sub.eventTypes, event.Type) {
+			continue
+		}
+		if sub.queue == nil {
+			sub.run(ctx, event)
+			continue
+		}
+		// Async handlers should not be cancelled when the request that published the event is done
+		if !sub.enqueue(queuedEvent{ctx: context.WithoutCancel(ctx), event: event}) {
+			log.Error(ctx, "Event queue is full, dropping event", "subscriber", sub.name, "type", event.TypeName, "event", event.Type, "id", event.ID)
+		}
+	}
+}
+
+// Close stops the bus, and waits for the queued events to be handled.
+func (bus *EventBus) Close() {
+	bus.lock.Lock()
+	if bus.closed {
+		bus.lock.Unlock()
+		return
+	}
+	bus.closed = true
+	subs := bus.subs
+	bus.subs = map[naam.Name][]*subscription{}
+	bus.lock.Unlock()
+
+	for _, list := range subs {
+		for _, sub := range list {
+			sub.stop()
+		}
+	}
+}
+
+// run calls the handler, isolating the caller from its errors and panics.
+func (sub *subscription) run(ctx context.Context, event interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(ctx, "Panic in event handler", "subscriber", sub.name, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	if err := sub.handle(ctx, event); err != nil {
+		log.Error(ctx, "Event handler failed", "subscriber", sub.name, "error", err)
+	}
+}
+
+// enqueue queues the event for an async handler, and returns false if the queue is full. Events for a stopped
+// subscription are dropped, since it may have been removed after Publish got it.
+func (sub *subscription) enqueue(qe queuedEvent) bool {
+	sub.queueLock.RLock()
+	defer sub.queueLock.RUnlock()
+	if sub.stopped {
+		return true
+	}
+	select {
+	case sub.queue <- qe:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop closes the queue of an async subscription, and waits for the queued events to be handled. It can be called
+// more than once e.g. by Close and then by the unsubscribe func.
+func (sub *subscription) stop() {
+	if sub.queue == nil {
+		return
+	}
+	sub.queueLock.Lock()
+	if sub.stopped {
+		sub.queueLock.Unlock()
+		return
+	}
+	sub.stopped = true
+	close(sub.queue)
+	sub.queueLock.Unlock()
+	sub.wg.Wait()
+}
@@ -0,0 +1,155 @@
+package types
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/naam"
+)
+
+func TestEventBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewEventBus()
+	defer bus.Close()
+	typeName := naam.New("test_type")
+
+	var lock sync.Mutex
+	var got []string
+	record := func(s string) {
+		lock.Lock()
+		defer lock.Unlock()
+		got = append(got, s)
+	}
+
+	// A panicking handler does not affect the publisher or the other handlers
+	_, err := Subscribe(bus, typeName, func(ctx context.Context, e Event[testType]) error {
+		panic("boom")
+	}, SubscribeOptions{Name: "panics"})
+	assert.NoError(t, err)
+
+	_, err = Subscribe(bus, typeName, func(ctx context.Context, e Event[testType]) error {
+		record("sync:" + string(e.Type) + ":" + e.Old.Value + "->" + e.New.Value)
+		return nil
+	}, SubscribeOptions{})
+	assert.NoError(t, err)
+
+	unsubscribe, err := Subscribe(bus, typeName, func(ctx context.Context, e Event[testType]) error {
+		record("async:" + string(e.Type))
+		return nil
+	}, SubscribeOptions{Async: true, Workers: 2, EventTypes: []EventType{EventType_Deleted}})
+	assert.NoError(t, err)
+
+	// Other types are not delivered
+	_, err = Subscribe(bus, naam.New("other_type"), func(ctx context.Context, e Event[testType]) error {
+		record("other")
+		return nil
+	}, SubscribeOptions{})
+	assert.NoError(t, err)
+
+	Publish(ctx, bus, NewCreatedEvent(typeName, testType{Value: "a"}))
+	Publish(ctx, bus, NewUpdatedEvent(typeName, testType{Value: "a"}, testType{Value: "b"}))
+	Publish(ctx, bus, NewDeletedEvent(typeName, testType{Value: "b"}))
+
+	// Unsubscribing waits for the queued events
+	unsubscribe()
+	Publish(ctx, bus, NewDeletedEvent(typeName, testType{Value: "c"}))
+
+	assert.Equal(t, []string{"sync:created:->a", "sync:updated:a->b", "sync:deleted:b->", "async:deleted", "sync:deleted:c->"}, got)
+}
+
+func TestEventBus_AsyncQueueFull(t *testing.T) {
+	ctx := context.Background()
+	bus := NewEventBus()
+	typeName := naam.New("test_type")
+
+	block := make(chan struct{})
+	var lock sync.Mutex
+	handled := 0
+	_, err := Subscribe(bus, typeName, func(ctx context.Context, e Event[testType]) error {
+		<-block
+		lock.Lock()
+		defer lock.Unlock()
+		handled++
+		return nil
+	}, SubscribeOptions{Async: true, QueueSize: 2})
+	assert.NoError(t, err)
+
+	// One event is being handled and two are queued, so the rest are dropped without blocking the publisher
+	for i := 0; i < 10; i++ {
+		Publish(ctx, bus, NewCreatedEvent(typeName, testType{}))
+	}
+	close(block)
+	bus.Close()
+	assert.LessOrEqual(t, handled, 3)
+	assert.GreaterOrEqual(t, handled, 2)
+
+	// A closed bus does not accept subscribers
+	_, err = Subscribe(bus, typeName, func(ctx context.Context, e Event[testType]) error { return nil }, SubscribeOptions{})
+	assert.Error(t, err)
+}
+
+func TestEventBus_NestedPublish(t *testing.T) {
+	ctx := context.Background()
+	bus := NewEventBus()
+	defer bus.Close()
+	typeName := naam.New("test_type")
+
+	// A sync handler can publish, subscribe and unsubscribe without deadlocking
+	var got []string
+	var unsubscribe func()
+	unsubscribe, err := Subscribe(bus, typeName, func(ctx context.Context, e Event[testType]) error {
+		got = append(got, "outer:"+string(e.Type))
+		if e.Type != EventType_Created {
+			return nil
+		}
+		_, err := Subscribe(bus, typeName, func(ctx context.Context, e Event[testType]) error {
+			got = append(got, "inner:"+string(e.Type))
+			return nil
+		}, SubscribeOptions{})
+		if err != nil {
+			return err
+		}
+		unsubscribe()
+		Publish(ctx, bus, NewUpdatedEvent(typeName, e.New, e.New))
+		return nil
+	}, SubscribeOptions{})
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Publish(ctx, bus, NewCreatedEvent(typeName, testType{Value: "a"}))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish deadlocked")
+	}
+	assert.Equal(t, []string{"outer:created", "inner:updated"}, got)
+}
+
+func TestEventBus_UnsubscribeAfterClose(t *testing.T) {
+	ctx := context.Background()
+	bus := NewEventBus()
+	typeName := naam.New("test_type")
+
+	var lock sync.Mutex
+	handled := 0
+	unsubscribe, err := Subscribe(bus, typeName, func(ctx context.Context, e Event[testType]) error {
+		lock.Lock()
+		defer lock.Unlock()
+		handled++
+		return nil
+	}, SubscribeOptions{Async: true})
+	assert.NoError(t, err)
+	Publish(ctx, bus, NewCreatedEvent(typeName, testType{}))
+
+	// Close waits for the queued events, and unsubscribing afterwards does nothing
+	bus.Close()
+	assert.Equal(t, 1, handled)
+	assert.NotPanics(t, unsubscribe)
+	assert.NotPanics(t, unsubscribe)
+}
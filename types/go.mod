@@ -3,13 +3,17 @@ module github.com/teejays/gokutil/types
 go 1.23.4
 
 require (
+	github.com/doug-martin/goqu/v9 v9.19.0
+	github.com/invopop/jsonschema v0.13.0
 	github.com/stretchr/testify v1.10.0
 	github.com/teejays/gokutil/dalutil v0.0.0-20240807004339-2e36dbb93e96
+	github.com/teejays/gokutil/errutil v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/gopi v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/httputil v0.0.0-20250416202003-6e164b8ddcf8
 	github.com/teejays/gokutil/log v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/naam v0.0.0-20250426215142-5dc7bd3f1fd0
 	github.com/teejays/gokutil/scalars v0.0.0-20250426215142-5dc7bd3f1fd0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -17,7 +21,6 @@ require (
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
-	github.com/doug-martin/goqu/v9 v9.19.0 // indirect
 	github.com/felixge/httpsnoop v1.0.4 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.9 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
@@ -28,7 +31,6 @@ require (
 	github.com/gorilla/mux v1.8.1 // indirect
 	github.com/graph-gophers/graphql-go v1.6.0 // indirect
 	github.com/iancoleman/strcase v0.3.0 // indirect
-	github.com/invopop/jsonschema v0.13.0 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/lib/pq v1.10.9 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
@@ -37,7 +39,6 @@ require (
 	github.com/teejays/gokutil/clog v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/ctxutil v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/env v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
-	github.com/teejays/gokutil/errutil v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/panics v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/sclog v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
 	github.com/teejays/gokutil/strcase v0.0.0-20250426215142-5dc7bd3f1fd0 // indirect
@@ -47,5 +48,4 @@ require (
 	golang.org/x/net v0.39.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 	golang.org/x/text v0.24.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,12 +1,14 @@
 package types
 
 import (
+	"cmp"
 	"context"
 	"database/sql/driver"
 	"fmt"
 	"reflect"
 	"slices"
 
+	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/teejays/gokutil/naam"
 	"github.com/teejays/gokutil/scalars"
 )
@@ -88,11 +90,99 @@ const (
 	HookPoint_ReadPost   HookType = "read_post"
 )
 
+// HookPoints lists all the valid hook points.
+var HookPoints = []HookType{
+	HookPoint_Init,
+	HookPoint_CreatePre,
+	HookPoint_CreatePost,
+	HookPoint_UpdatePre,
+	HookPoint_UpdatePost,
+	HookPoint_SavePre,
+	HookPoint_SavePost,
+	HookPoint_DeletePre,
+	HookPoint_DeletePost,
+	HookPoint_ReadPre,
+	HookPoint_ReadPost,
+}
+
+func (h HookType) IsValid() bool { return slices.Contains(HookPoints, h) }
+
+// IsTypeHook returns true for the hook points that run on the type i.e. their hooks are TypeHookFuncs. Init hooks are
+// InitHookFuncs and ReadPre hooks are ReadPreHookFuncs.
+func (h HookType) IsTypeHook() bool { return h.IsValid() && !h.IsInit() && !h.IsReadPre() }
+
 type TypeHookFunc[T BasicType] func(context.Context, T) (T, error)
 
+// InitHookFunc runs once, when the meta is registered using RegisterMeta. It can be used to set defaults, or to set up
+// any side tables that the type needs.
+type InitHookFunc func(context.Context) error
+
+// ReadPreHookFunc runs before the type is read from the database. It can modify the request e.g. add a condition that
+// limits the rows to the ones owned by the current user, or deny the read by returning an error.
+type ReadPreHookFunc func(context.Context, ReadRequest) (ReadRequest, error)
+
+// ReadRequest is the read query that the ReadPre hooks run on.
+type ReadRequest struct {
+	TableName string
+	IDColumn  string
+	IDs       []scalars.ID
+	// Where conditions are added to the query, in addition to the IDs.
+	Where []exp.Expression
+}
+
+// Hook is a named TypeHookFunc. Hooks at the same hook point run in the order of their Priority (lowest first), and
+// hooks with the same Priority run in the order they were added.
+type Hook[T BasicType] struct {
+	Name     string
+	Priority int
+	Fn       TypeHookFunc[T]
+}
+
+// InitHook is a named InitHookFunc, ordered like Hook.
+type InitHook struct {
+	Name     string
+	Priority int
+	Fn       InitHookFunc
+}
+
+// ReadPreHook is a named ReadPreHookFunc, ordered like Hook.
+type ReadPreHook struct {
+	Name     string
+	Priority int
+	Fn       ReadPreHookFunc
+}
+
+func (h Hook[T]) getName() string      { return h.Name }
+func (h Hook[T]) getPriority() int     { return h.Priority }
+func (h Hook[T]) hasFn() bool          { return h.Fn != nil }
+func (h InitHook) getName() string     { return h.Name }
+func (h InitHook) getPriority() int    { return h.Priority }
+func (h InitHook) hasFn() bool         { return h.Fn != nil }
+func (h ReadPreHook) getName() string  { return h.Name }
+func (h ReadPreHook) getPriority() int { return h.Priority }
+func (h ReadPreHook) hasFn() bool      { return h.Fn != nil }
+
+type namedHook interface {
+	getName() string
+	getPriority() int
+	hasFn() bool
+}
+
 type IWithHooks[T BasicType] interface {
+	// Hook chains
+	AddHook(HookType, Hook[T]) error
+	ReplaceHook(HookType, Hook[T]) error
+	RemoveHook(HookType, string) error
+	GetHooks(HookType) []Hook[T]
+	AddInitHook(InitHook) error
+	RemoveInitHook(string) error
+	GetInitHooks() []InitHook
+	AddReadPreHook(ReadPreHook) error
+	RemoveReadPreHook(string) error
+	GetReadPreHooks() []ReadPreHook
+
 	// Setters
-	SetHookInit(TypeHookFunc[T]) error
+	SetHookInit(InitHookFunc) error
 	SetHookCreatePre(TypeHookFunc[T]) error
 	SetHookCreatePost(TypeHookFunc[T]) error
 	SetHookUpdatePre(TypeHookFunc[T]) error
@@ -101,11 +191,11 @@ type IWithHooks[T BasicType] interface {
 	SetHookSavePost(TypeHookFunc[T]) error
 	SetHookDeletePre(TypeHookFunc[T]) error
 	SetHookDeletePost(TypeHookFunc[T]) error
-	SetHookReadPre(TypeHookFunc[T]) error
+	SetHookReadPre(ReadPreHookFunc) error
 	SetHookReadPost(TypeHookFunc[T]) error
 
 	// Getters
-	GetHookInit() TypeHookFunc[T]
+	GetHookInit() InitHookFunc
 	GetHookCreatePre() TypeHookFunc[T]
 	GetHookCreatePost() TypeHookFunc[T]
 	GetHookUpdatePre() TypeHookFunc[T]
@@ -114,170 +204,299 @@ type IWithHooks[T BasicType] interface {
 	GetHookSavePost() TypeHookFunc[T]
 	GetHookDeletePre() TypeHookFunc[T]
 	GetHookDeletePost() TypeHookFunc[T]
-	GetHookReadPre() TypeHookFunc[T]
+	GetHookReadPre() ReadPreHookFunc
 	GetHookReadPost() TypeHookFunc[T]
 }
 
+// WithHooks holds the hook chain for each hook point. The SetHook* methods add a hook named after the hook point, so
+// they can only be called once per hook point. The GetHook* methods return the whole chain as a single func.
 type WithHooks[T BasicType] struct {
-	Init       TypeHookFunc[T]
-	CreatePre  TypeHookFunc[T]
-	CreatePost TypeHookFunc[T]
-	UpdatePre  TypeHookFunc[T]
-	UpdatePost TypeHookFunc[T]
-	SavePre    TypeHookFunc[T]
-	SavePost   TypeHookFunc[T]
-	DeletePre  TypeHookFunc[T]
-	DeletePost TypeHookFunc[T]
-	ReadPre    TypeHookFunc[T]
-	ReadPost   TypeHookFunc[T]
-}
-
-func setHookHelper[T BasicType](fn TypeHookFunc[T], dst *TypeHookFunc[T]) error {
-	// TODO: Figure this out and document better
-	if dst == nil {
-		return fmt.Errorf("destination pointer is nil")
-	}
-	if *dst != nil {
-		return fmt.Errorf("hook already set")
-	}
-	*dst = fn
-	return nil
+	hooks        map[HookType][]Hook[T]
+	initHooks    []InitHook
+	readPreHooks []ReadPreHook
 }
 
 func NewWithHooks[T BasicType]() IWithHooks[T] {
 	return &WithHooks[T]{}
 }
 
-func (t *WithHooks[T]) SetHookInit(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.Init)
+// AddHook adds the hook to the chain for the hook point. The name of the hook should be unique for the hook point.
+// Init and ReadPre hooks are added using AddInitHook and AddReadPreHook.
+func (t *WithHooks[T]) AddHook(point HookType, hook Hook[T]) error {
+	if err := validateHookPoint(point); err != nil {
+		return err
+	}
+	hooks, err := addNamedHook(point, t.hooks[point], hook)
 	if err != nil {
 		return err
 	}
+	if t.hooks == nil {
+		t.hooks = map[HookType][]Hook[T]{}
+	}
+	t.hooks[point] = hooks
 	return nil
 }
 
-func (t *WithHooks[T]) SetHookCreatePre(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.CreatePre)
+// ReplaceHook replaces the hook with the same name in the chain for the hook point. If the new hook is not valid, the
+// chain is not changed.
+func (t *WithHooks[T]) ReplaceHook(point HookType, hook Hook[T]) error {
+	if err := validateHookPoint(point); err != nil {
+		return err
+	}
+	hooks, err := removeNamedHook(point, t.hooks[point], hook.Name)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-func (t *WithHooks[T]) SetHookCreatePost(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.CreatePost)
+	hooks, err = addNamedHook(point, hooks, hook)
 	if err != nil {
 		return err
 	}
+	t.hooks[point] = hooks
 	return nil
 }
 
-func (t *WithHooks[T]) SetHookUpdatePre(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.UpdatePre)
+// RemoveHook removes the hook with the name from the chain for the hook point.
+func (t *WithHooks[T]) RemoveHook(point HookType, name string) error {
+	hooks, err := removeNamedHook(point, t.hooks[point], name)
 	if err != nil {
 		return err
 	}
+	t.hooks[point] = hooks
 	return nil
 }
 
-func (t *WithHooks[T]) SetHookUpdatePost(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.UpdatePost)
+// GetHooks returns the hooks for the hook point, in the order they should run.
+func (t WithHooks[T]) GetHooks(point HookType) []Hook[T] {
+	return slices.Clone(t.hooks[point])
+}
+
+func (t *WithHooks[T]) AddInitHook(hook InitHook) error {
+	hooks, err := addNamedHook(HookPoint_Init, t.initHooks, hook)
 	if err != nil {
 		return err
 	}
+	t.initHooks = hooks
 	return nil
 }
 
-func (t *WithHooks[T]) SetHookSavePre(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.SavePre)
+func (t *WithHooks[T]) RemoveInitHook(name string) error {
+	hooks, err := removeNamedHook(HookPoint_Init, t.initHooks, name)
 	if err != nil {
 		return err
 	}
+	t.initHooks = hooks
 	return nil
 }
 
-func (t *WithHooks[T]) SetHookSavePost(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.SavePost)
+func (t WithHooks[T]) GetInitHooks() []InitHook {
+	return slices.Clone(t.initHooks)
+}
+
+func (t *WithHooks[T]) AddReadPreHook(hook ReadPreHook) error {
+	hooks, err := addNamedHook(HookPoint_ReadPre, t.readPreHooks, hook)
 	if err != nil {
 		return err
 	}
+	t.readPreHooks = hooks
 	return nil
 }
 
-func (t *WithHooks[T]) SetHookDeletePre(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.DeletePre)
+func (t *WithHooks[T]) RemoveReadPreHook(name string) error {
+	hooks, err := removeNamedHook(HookPoint_ReadPre, t.readPreHooks, name)
 	if err != nil {
 		return err
 	}
+	t.readPreHooks = hooks
 	return nil
 }
 
-func (t *WithHooks[T]) SetHookDeletePost(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.DeletePost)
-	if err != nil {
-		return err
+func (t WithHooks[T]) GetReadPreHooks() []ReadPreHook {
+	return slices.Clone(t.readPreHooks)
+}
+
+func validateHookPoint(point HookType) error {
+	if !point.IsValid() {
+		return fmt.Errorf("invalid hook point [%s]", point)
+	}
+	if !point.IsTypeHook() {
+		return fmt.Errorf("hooks for [%s] do not run on the type and have their own methods", point)
 	}
 	return nil
 }
 
-func (t *WithHooks[T]) SetHookReadPre(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.ReadPre)
-	if err != nil {
-		return err
+// addNamedHook returns the hooks with the new hook added, in the order they should run.
+func addNamedHook[H namedHook](point HookType, hooks []H, hook H) ([]H, error) {
+	if hook.getName() == "" {
+		return nil, fmt.Errorf("hook for [%s] has no name", point)
 	}
-	return nil
+	if !hook.hasFn() {
+		return nil, fmt.Errorf("hook [%s] for [%s] has no function", hook.getName(), point)
+	}
+	if slices.ContainsFunc(hooks, func(h H) bool { return h.getName() == hook.getName() }) {
+		return nil, fmt.Errorf("hook [%s] already set for [%s]", hook.getName(), point)
+	}
+	hooks = append(slices.Clone(hooks), hook)
+	slices.SortStableFunc(hooks, func(a, b H) int { return cmp.Compare(a.getPriority(), b.getPriority()) })
+	return hooks, nil
 }
 
-func (t *WithHooks[T]) SetHookReadPost(fn TypeHookFunc[T]) error {
-	err := setHookHelper(fn, &t.ReadPost)
-	if err != nil {
-		return err
+func removeNamedHook[H namedHook](point HookType, hooks []H, name string) ([]H, error) {
+	i := slices.IndexFunc(hooks, func(h H) bool { return h.getName() == name })
+	if i < 0 {
+		return nil, fmt.Errorf("hook [%s] not found for [%s]", name, point)
+	}
+	return slices.Delete(slices.Clone(hooks), i, i+1), nil
+}
+
+// RunHooks runs the hooks in order, passing the output of each hook to the next one. It stops at the first error,
+// which is wrapped with the name of the hook.
+func RunHooks[T BasicType](ctx context.Context, hooks []Hook[T], elem T) (T, error) {
+	for _, h := range hooks {
+		var err error
+		elem, err = h.Fn(ctx, elem)
+		if err != nil {
+			return elem, fmt.Errorf("hook [%s]: %w", h.Name, err)
+		}
+	}
+	return elem, nil
+}
+
+// RunInitHooks runs the Init hooks in order, and stops at the first error.
+func RunInitHooks(ctx context.Context, hooks []InitHook) error {
+	for _, h := range hooks {
+		if err := h.Fn(ctx); err != nil {
+			return fmt.Errorf("hook [%s]: %w", h.Name, err)
+		}
 	}
 	return nil
 }
 
-func (t WithHooks[T]) GetHookInit() TypeHookFunc[T] {
-	return t.Init
+// RunReadPreHooks runs the ReadPre hooks in order, passing the request returned by each hook to the next one. It stops
+// at the first error, so a hook can deny the read by returning an error.
+func RunReadPreHooks(ctx context.Context, hooks []ReadPreHook, req ReadRequest) (ReadRequest, error) {
+	for _, h := range hooks {
+		var err error
+		req, err = h.Fn(ctx, req)
+		if err != nil {
+			return req, fmt.Errorf("hook [%s]: %w", h.Name, err)
+		}
+	}
+	return req, nil
+}
+
+func (t *WithHooks[T]) setHook(point HookType, fn TypeHookFunc[T]) error {
+	return t.AddHook(point, Hook[T]{Name: string(point), Fn: fn})
+}
+
+// getHookChain returns a TypeHookFunc that runs all the hooks for the hook point, or nil if there are none.
+func (t WithHooks[T]) getHookChain(point HookType) TypeHookFunc[T] {
+	hooks := t.GetHooks(point)
+	if len(hooks) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, elem T) (T, error) {
+		return RunHooks(ctx, hooks, elem)
+	}
+}
+
+func (t *WithHooks[T]) SetHookInit(fn InitHookFunc) error {
+	return t.AddInitHook(InitHook{Name: string(HookPoint_Init), Fn: fn})
+}
+
+func (t *WithHooks[T]) SetHookCreatePre(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_CreatePre, fn)
+}
+
+func (t *WithHooks[T]) SetHookCreatePost(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_CreatePost, fn)
+}
+
+func (t *WithHooks[T]) SetHookUpdatePre(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_UpdatePre, fn)
+}
+
+func (t *WithHooks[T]) SetHookUpdatePost(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_UpdatePost, fn)
+}
+
+func (t *WithHooks[T]) SetHookSavePre(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_SavePre, fn)
+}
+
+func (t *WithHooks[T]) SetHookSavePost(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_SavePost, fn)
+}
+
+func (t *WithHooks[T]) SetHookDeletePre(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_DeletePre, fn)
+}
+
+func (t *WithHooks[T]) SetHookDeletePost(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_DeletePost, fn)
+}
+
+func (t *WithHooks[T]) SetHookReadPre(fn ReadPreHookFunc) error {
+	return t.AddReadPreHook(ReadPreHook{Name: string(HookPoint_ReadPre), Fn: fn})
+}
+
+func (t *WithHooks[T]) SetHookReadPost(fn TypeHookFunc[T]) error {
+	return t.setHook(HookPoint_ReadPost, fn)
+}
+
+func (t WithHooks[T]) GetHookInit() InitHookFunc {
+	hooks := t.GetInitHooks()
+	if len(hooks) == 0 {
+		return nil
+	}
+	return func(ctx context.Context) error {
+		return RunInitHooks(ctx, hooks)
+	}
 }
 
 func (t WithHooks[T]) GetHookCreatePre() TypeHookFunc[T] {
-	return t.CreatePre
+	return t.getHookChain(HookPoint_CreatePre)
 }
 
 func (t WithHooks[T]) GetHookCreatePost() TypeHookFunc[T] {
-	return t.CreatePost
+	return t.getHookChain(HookPoint_CreatePost)
 }
 
 func (t WithHooks[T]) GetHookUpdatePre() TypeHookFunc[T] {
-	return t.UpdatePre
+	return t.getHookChain(HookPoint_UpdatePre)
 }
 
 func (t WithHooks[T]) GetHookUpdatePost() TypeHookFunc[T] {
-	return t.UpdatePost
+	return t.getHookChain(HookPoint_UpdatePost)
 }
 
 func (t WithHooks[T]) GetHookSavePre() TypeHookFunc[T] {
-	return t.SavePre
+	return t.getHookChain(HookPoint_SavePre)
 }
 
 func (t WithHooks[T]) GetHookSavePost() TypeHookFunc[T] {
-	return t.SavePost
+	return t.getHookChain(HookPoint_SavePost)
 }
 
 func (t WithHooks[T]) GetHookDeletePre() TypeHookFunc[T] {
-	return t.DeletePre
+	return t.getHookChain(HookPoint_DeletePre)
 }
 
 func (t WithHooks[T]) GetHookDeletePost() TypeHookFunc[T] {
-	return t.DeletePost
+	return t.getHookChain(HookPoint_DeletePost)
 }
 
-func (t WithHooks[T]) GetHookReadPre() TypeHookFunc[T] {
-	return t.ReadPost
+func (t WithHooks[T]) GetHookReadPre() ReadPreHookFunc {
+	hooks := t.GetReadPreHooks()
+	if len(hooks) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, req ReadRequest) (ReadRequest, error) {
+		return RunReadPreHooks(ctx, hooks, req)
+	}
 }
 
 func (t WithHooks[T]) GetHookReadPost() TypeHookFunc[T] {
-	return t.ReadPost
+	return t.getHookChain(HookPoint_ReadPost)
 }
 
 /* * * * * *
@@ -1,11 +1,16 @@
 package types
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"testing"
 
+	"github.com/doug-martin/goqu/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/teejays/gokutil/naam"
+	"github.com/teejays/gokutil/scalars"
 )
 
 type TestField int
@@ -98,3 +103,109 @@ func TestPruneFields(t *testing.T) {
 		})
 	}
 }
+
+type testType struct {
+	Value string
+}
+
+func (t testType) GetID() scalars.ID                { return scalars.ID{} }
+func (t testType) GetUpdatedAt() scalars.Timestamp  { return scalars.Timestamp{} }
+func (t testType) GetDeletedAt() *scalars.Timestamp { return nil }
+
+func appendHook(s string) TypeHookFunc[testType] {
+	return func(ctx context.Context, t testType) (testType, error) {
+		t.Value += s
+		return t, nil
+	}
+}
+
+func TestWithHooks(t *testing.T) {
+	var h WithHooks[testType]
+	assert.Nil(t, h.GetHookCreatePre())
+
+	assert.NoError(t, h.AddHook(HookPoint_CreatePre, Hook[testType]{Name: "business", Priority: 10, Fn: appendHook("c")}))
+	assert.NoError(t, h.AddHook(HookPoint_CreatePre, Hook[testType]{Name: "audit", Fn: appendHook("a")}))
+	assert.NoError(t, h.AddHook(HookPoint_CreatePre, Hook[testType]{Name: "validation", Fn: appendHook("b")}))
+	assert.Error(t, h.AddHook(HookPoint_CreatePre, Hook[testType]{Name: "audit", Fn: appendHook("x")}))
+	assert.Error(t, h.AddHook(HookType("foo"), Hook[testType]{Name: "audit", Fn: appendHook("x")}))
+	assert.Error(t, h.AddHook(HookPoint_CreatePre, Hook[testType]{Name: "empty"}))
+
+	got, err := h.GetHookCreatePre()(context.Background(), testType{})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", got.Value)
+
+	// Replace and remove by name
+	assert.NoError(t, h.ReplaceHook(HookPoint_CreatePre, Hook[testType]{Name: "audit", Priority: 20, Fn: appendHook("A")}))
+	assert.NoError(t, h.RemoveHook(HookPoint_CreatePre, "validation"))
+	assert.Error(t, h.RemoveHook(HookPoint_CreatePre, "validation"))
+	assert.Error(t, h.ReplaceHook(HookPoint_CreatePre, Hook[testType]{Name: "validation", Fn: appendHook("b")}))
+	// A failing replace keeps the existing hook
+	assert.Error(t, h.ReplaceHook(HookPoint_CreatePre, Hook[testType]{Name: "audit"}))
+	assert.Len(t, h.GetHooks(HookPoint_CreatePre), 2)
+
+	got, err = RunHooks(context.Background(), h.GetHooks(HookPoint_CreatePre), testType{})
+	assert.NoError(t, err)
+	assert.Equal(t, "cA", got.Value)
+
+	// Priorities far apart are still ordered
+	assert.NoError(t, h.AddHook(HookPoint_CreatePost, Hook[testType]{Name: "last", Priority: math.MaxInt, Fn: appendHook("z")}))
+	assert.NoError(t, h.AddHook(HookPoint_CreatePost, Hook[testType]{Name: "first", Priority: math.MinInt, Fn: appendHook("a")}))
+	got, err = h.GetHookCreatePost()(context.Background(), testType{})
+	assert.NoError(t, err)
+	assert.Equal(t, "az", got.Value)
+
+	// SetHook* can only be called once per hook point
+	assert.NoError(t, h.SetHookReadPost(appendHook("r")))
+	assert.Error(t, h.SetHookReadPost(appendHook("r")))
+	assert.Nil(t, h.GetHookUpdatePost())
+
+	// Init and ReadPre hooks have their own methods
+	assert.Error(t, h.AddHook(HookPoint_Init, Hook[testType]{Name: "init", Fn: appendHook("i")}))
+	assert.Error(t, h.AddHook(HookPoint_ReadPre, Hook[testType]{Name: "read", Fn: appendHook("r")}))
+
+	// The chain stops at the first error, which is wrapped with the hook name
+	assert.NoError(t, h.AddHook(HookPoint_SavePre, Hook[testType]{Name: "deny", Fn: func(ctx context.Context, t testType) (testType, error) {
+		return t, errBadValue
+	}}))
+	assert.NoError(t, h.AddHook(HookPoint_SavePre, Hook[testType]{Name: "after", Fn: appendHook("z")}))
+	got, err = h.GetHookSavePre()(context.Background(), testType{})
+	assert.ErrorIs(t, err, errBadValue)
+	assert.Contains(t, err.Error(), "hook [deny]")
+	assert.Equal(t, "", got.Value)
+}
+
+var errBadValue = errors.New("bad value")
+
+func TestWithHooks_ReadPre(t *testing.T) {
+	var h WithHooks[testType]
+	assert.Nil(t, h.GetHookReadPre())
+
+	assert.NoError(t, h.AddReadPreHook(ReadPreHook{Name: "owner", Fn: func(ctx context.Context, req ReadRequest) (ReadRequest, error) {
+		req.Where = append(req.Where, goqu.C("owner_id").Eq("me"))
+		return req, nil
+	}}))
+	assert.NoError(t, h.AddReadPreHook(ReadPreHook{Name: "table", Priority: -1, Fn: func(ctx context.Context, req ReadRequest) (ReadRequest, error) {
+		if len(req.Where) > 0 {
+			return req, fmt.Errorf("expected to run first")
+		}
+		req.TableName = "v_" + req.TableName
+		return req, nil
+	}}))
+	assert.Error(t, h.AddReadPreHook(ReadPreHook{Name: "owner", Fn: h.GetReadPreHooks()[0].Fn}))
+
+	req, err := h.GetHookReadPre()(context.Background(), ReadRequest{TableName: "t"})
+	assert.NoError(t, err)
+	assert.Equal(t, "v_t", req.TableName)
+	assert.Len(t, req.Where, 1)
+
+	// A hook can deny the read
+	assert.NoError(t, h.SetHookReadPre(func(ctx context.Context, req ReadRequest) (ReadRequest, error) {
+		return req, errBadValue
+	}))
+	_, err = RunReadPreHooks(context.Background(), h.GetReadPreHooks(), ReadRequest{TableName: "t"})
+	assert.ErrorIs(t, err, errBadValue)
+	assert.Contains(t, err.Error(), "hook [read_pre]")
+
+	assert.NoError(t, h.RemoveReadPreHook("read_pre"))
+	assert.Len(t, h.GetReadPreHooks(), 2)
+}
@@ -0,0 +1,459 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/teejays/gokutil/errutil"
+)
+
+/* * * * * *
+ * Patch
+ * * * * * */
+
+// PatchType is the kind of a patch document, named after its media type.
+type PatchType string
+
+const (
+	PatchType_Invalid PatchType = ""
+	// PatchType_MergePatch is an RFC 7386 JSON Merge Patch.
+	PatchType_MergePatch PatchType = "application/merge-patch+json"
+	// PatchType_JSONPatch is an RFC 6902 JSON Patch.
+	PatchType_JSONPatch PatchType = "application/json-patch+json"
+)
+
+// JSONPatchOperation is a single operation of an RFC 6902 JSON Patch.
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyPatch applies the patch to the JSON encoding of the value and decodes the fields that the patch modifies onto a
+// copy of the value. It also returns those top level (JSON) fields. Patches that cannot be applied are returned as
+// GokuErrors with a 400 HTTP status.
+func ApplyPatch[T any](current T, patchType PatchType, patch []byte) (T, []string, error) {
+	switch patchType {
+	case PatchType_MergePatch:
+		return ApplyMergePatch(current, patch)
+	case PatchType_JSONPatch:
+		return ApplyJSONPatch(current, patch)
+	}
+	var zero T
+	return zero, nil, newPatchError("unsupported patch type [%s]", patchType)
+}
+
+// ApplyMergePatch applies an RFC 7386 JSON Merge Patch. The patch should be an object, and each of its keys is a
+// modified field.
+func ApplyMergePatch[T any](current T, patch []byte) (T, []string, error) {
+	var zero T
+	patchDoc, err := decodeJSONDoc(patch)
+	if err != nil {
+		return zero, nil, newPatchError("invalid merge patch: %s", err)
+	}
+	patchObj, ok := patchDoc.(map[string]interface{})
+	if !ok {
+		return zero, nil, newPatchError("merge patch should be an object")
+	}
+	doc, err := toJSONDoc(current)
+	if err != nil {
+		return zero, nil, fmt.Errorf("encoding value: %w", err)
+	}
+
+	var fields []string
+	for k := range patchObj {
+		fields = append(fields, k)
+	}
+	slices.Sort(fields)
+
+	result, err := fromJSONDoc(current, mergePatch(doc, patchObj), fields)
+	if err != nil {
+		return zero, nil, err
+	}
+	return result, fields, nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergePatch(targetObj[k], v)
+	}
+	return targetObj
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch. The operations are applied in order, and the patch fails as a whole
+// if any of them fail. The top level field of the path (and of the from of a move) of each operation, other than test,
+// is a modified field. Operations on the whole document are not allowed.
+func ApplyJSONPatch[T any](current T, patch []byte) (T, []string, error) {
+	var zero T
+	var ops []JSONPatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return zero, nil, newPatchError("invalid JSON patch: %s", err)
+	}
+	doc, err := toJSONDoc(current)
+	if err != nil {
+		return zero, nil, fmt.Errorf("encoding value: %w", err)
+	}
+
+	var fields []string
+	addField := func(path []string) {
+		if !slices.Contains(fields, path[0]) {
+			fields = append(fields, path[0])
+		}
+	}
+	for i, op := range ops {
+		path, err := parseJSONPointer(op.Path)
+		if err != nil {
+			return zero, nil, newPatchError("operation [%d]: %s", i, err)
+		}
+		if len(path) == 0 {
+			return zero, nil, newPatchError("operation [%d]: path should not be the whole document", i)
+		}
+		doc, err = applyJSONPatchOperation(doc, op, path)
+		if err != nil {
+			return zero, nil, newPatchError("operation [%d] (%s %s): %s", i, op.Op, op.Path, err)
+		}
+		if op.Op == "test" {
+			continue
+		}
+		addField(path)
+		if op.Op == "move" {
+			from, _ := parseJSONPointer(op.From)
+			addField(from)
+		}
+	}
+	slices.Sort(fields)
+
+	result, err := fromJSONDoc(current, doc, fields)
+	if err != nil {
+		return zero, nil, err
+	}
+	return result, fields, nil
+}
+
+func applyJSONPatchOperation(doc interface{}, op JSONPatchOperation, path []string) (interface{}, error) {
+	getValue := func() (interface{}, error) {
+		if op.Value == nil {
+			return nil, fmt.Errorf("value is required")
+		}
+		return decodeJSONDoc(op.Value)
+	}
+	getFrom := func() ([]string, error) {
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		if len(from) == 0 {
+			return nil, fmt.Errorf("from should not be the whole document")
+		}
+		return from, nil
+	}
+
+	switch op.Op {
+	case "add":
+		value, err := getValue()
+		if err != nil {
+			return nil, err
+		}
+		return jsonDocAdd(doc, path, value)
+	case "remove":
+		doc, _, err := jsonDocRemove(doc, path)
+		return doc, err
+	case "replace":
+		value, err := getValue()
+		if err != nil {
+			return nil, err
+		}
+		doc, _, err := jsonDocRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonDocAdd(doc, path, value)
+	case "move":
+		from, err := getFrom()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+			return nil, fmt.Errorf("cannot move a value into itself")
+		}
+		doc, value, err := jsonDocRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return jsonDocAdd(doc, path, value)
+	case "copy":
+		from, err := getFrom()
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonDocGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		// Copy the value, so that later operations on one of the copies do not change the other
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		value, err = decodeJSONDoc(b)
+		if err != nil {
+			return nil, err
+		}
+		return jsonDocAdd(doc, path, value)
+	case "test":
+		value, err := getValue()
+		if err != nil {
+			return nil, err
+		}
+		got, err := jsonDocGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonDocsEqual(got, value) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unrecognized operation [%s]", op.Op)
+}
+
+// parseJSONPointer parses an RFC 6901 JSON Pointer into its reference tokens. The empty pointer is the whole document.
+func parseJSONPointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer [%s]: should start with a `/`", ptr)
+	}
+	var tokens []string
+	for _, t := range strings.Split(ptr[1:], "/") {
+		tokens = append(tokens, strings.NewReplacer("~1", "/", "~0", "~").Replace(t))
+	}
+	return tokens, nil
+}
+
+// parseJSONArrayIndex parses the index of an array element. `-` is the index after the last element.
+func parseJSONArrayIndex(token string, n int) (int, error) {
+	if token == "-" {
+		return n, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index [%s]", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid array index [%s]", token)
+	}
+	return i, nil
+}
+
+func jsonDocGet(doc interface{}, path []string) (interface{}, error) {
+	for _, t := range path {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[t]
+			if !ok {
+				return nil, fmt.Errorf("path [%s] does not exist", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := parseJSONArrayIndex(t, len(d))
+			if err != nil {
+				return nil, err
+			}
+			if i >= len(d) {
+				return nil, fmt.Errorf("array index [%d] is out of range", i)
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("path [%s] does not exist", t)
+		}
+	}
+	return doc, nil
+}
+
+// jsonDocAdd adds the value at the path, and returns the updated document. The parent of the path should exist.
+func jsonDocAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := jsonDocGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = value
+		return doc, nil
+	case []interface{}:
+		i, err := parseJSONArrayIndex(last, len(p))
+		if err != nil {
+			return nil, err
+		}
+		if i > len(p) {
+			return nil, fmt.Errorf("array index [%d] is out of range", i)
+		}
+		return jsonDocSet(doc, path[:len(path)-1], slices.Insert(slices.Clone(p), i, value))
+	}
+	return nil, fmt.Errorf("cannot add [%s] to a value that is not an object or an array", last)
+}
+
+// jsonDocSet sets the existing value at the path, and returns the updated document.
+func jsonDocSet(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := jsonDocGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = value
+	case []interface{}:
+		i, err := parseJSONArrayIndex(last, len(p))
+		if err != nil {
+			return nil, err
+		}
+		if i >= len(p) {
+			return nil, fmt.Errorf("array index [%d] is out of range", i)
+		}
+		p[i] = value
+	}
+	return doc, nil
+}
+
+// jsonDocRemove removes the value at the path, and returns the updated document and the removed value.
+func jsonDocRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	parent, err := jsonDocGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		v, ok := p[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path [%s] does not exist", last)
+		}
+		delete(p, last)
+		return doc, v, nil
+	case []interface{}:
+		i, err := parseJSONArrayIndex(last, len(p))
+		if err != nil {
+			return nil, nil, err
+		}
+		if i >= len(p) {
+			return nil, nil, fmt.Errorf("array index [%d] is out of range", i)
+		}
+		v := p[i]
+		doc, err = jsonDocSet(doc, path[:len(path)-1], slices.Delete(slices.Clone(p), i, i+1))
+		return doc, v, err
+	}
+	return nil, nil, fmt.Errorf("path [%s] does not exist", last)
+}
+
+// fromJSONDoc decodes the modified top level fields of the patched document onto a copy of the current value. Other
+// fields are kept as they are, including the ones that do not survive a JSON round trip e.g. unexported fields, fields
+// with a `json:"-"` tag, or values that encode to a redacted placeholder. A modified field is reset before it is
+// decoded, so that the current value is not changed, and removing it from the document sets it to its zero value.
+//
+// Values that are not structs are decoded from the whole document.
+func fromJSONDoc[T any](current T, doc interface{}, fields []string) (T, error) {
+	result := current
+	rv := reflect.ValueOf(&result).Elem()
+	if obj, ok := doc.(map[string]interface{}); ok && rv.Kind() == reflect.Struct {
+		partial := map[string]interface{}{}
+		for _, k := range fields {
+			if fv, ok := getJSONFieldValue(rv, k); ok && fv.CanSet() {
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+			if v, ok := obj[k]; ok {
+				partial[k] = v
+			}
+		}
+		doc = partial
+	} else {
+		var zero T
+		result = zero
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return result, fmt.Errorf("encoding patched value: %w", err)
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return result, newPatchError("patched value is not a valid [%T]: %s", result, err)
+	}
+	return result, nil
+}
+
+// getJSONFieldValue returns the field of the struct value that the JSON key is decoded into. Like encoding/json, it
+// prefers an exact match of the name to a case-insensitive one, and looks into embedded structs.
+func getJSONFieldValue(v reflect.Value, key string) (reflect.Value, bool) {
+	var folded reflect.Value
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if f, ok := getJSONFieldValue(fv, key); ok {
+					return f, true
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if name == key {
+			return fv, true
+		}
+		if !folded.IsValid() && strings.EqualFold(name, key) {
+			folded = fv
+		}
+	}
+	return folded, folded.IsValid()
+}
+
+func newPatchError(msg string, args ...interface{}) error {
+	return errutil.NewGerror(msg, args...).SetHTTPStatus(http.StatusBadRequest)
+}
@@ -0,0 +1,185 @@
+package types
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/errutil"
+	"github.com/teejays/gokutil/scalars"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	current := testPerson{Name: "a", Age: 1, Tags: []string{"x"}, Address: &testAddress{City: "c", Lines: []string{"1"}}}
+
+	tests := []struct {
+		name       string
+		patch      string
+		want       testPerson
+		wantFields []string
+		wantErr    bool
+	}{
+		{
+			name:       "nested object is merged",
+			patch:      `{"age": 2, "address": {"city": "d"}}`,
+			want:       testPerson{Name: "a", Age: 2, Tags: []string{"x"}, Address: &testAddress{City: "d", Lines: []string{"1"}}},
+			wantFields: []string{"address", "age"},
+		},
+		{
+			name:       "arrays are replaced and null removes",
+			patch:      `{"tags": ["y", "z"], "address": null}`,
+			want:       testPerson{Name: "a", Age: 1, Tags: []string{"y", "z"}},
+			wantFields: []string{"address", "tags"},
+		},
+		{name: "not an object", patch: `["a"]`, wantErr: true},
+		{name: "invalid JSON", patch: `{`, wantErr: true},
+		{name: "wrong type", patch: `{"age": "old"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fields, err := ApplyPatch(current, PatchType_MergePatch, []byte(tt.patch))
+			if tt.wantErr {
+				assertBadRequest(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantFields, fields)
+		})
+	}
+	// The current value is not modified
+	assert.Equal(t, "c", current.Address.City)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	current := testPerson{Name: "a", Age: 1, Tags: []string{"x", "y"}, Address: &testAddress{City: "c", Lines: []string{"1"}}}
+
+	tests := []struct {
+		name       string
+		patch      string
+		want       testPerson
+		wantFields []string
+		wantErr    bool
+	}{
+		{
+			name:       "replace and add",
+			patch:      `[{"op": "replace", "path": "/age", "value": 3}, {"op": "add", "path": "/tags/1", "value": "w"}, {"op": "add", "path": "/address/lines/-", "value": "2"}]`,
+			want:       testPerson{Name: "a", Age: 3, Tags: []string{"x", "w", "y"}, Address: &testAddress{City: "c", Lines: []string{"1", "2"}}},
+			wantFields: []string{"address", "age", "tags"},
+		},
+		{
+			name:       "remove from an array",
+			patch:      `[{"op": "remove", "path": "/tags/0"}]`,
+			want:       testPerson{Name: "a", Age: 1, Tags: []string{"y"}, Address: &testAddress{City: "c", Lines: []string{"1"}}},
+			wantFields: []string{"tags"},
+		},
+		{
+			name:       "test does not modify",
+			patch:      `[{"op": "test", "path": "/age", "value": 1.0}, {"op": "replace", "path": "/name", "value": "b"}]`,
+			want:       testPerson{Name: "b", Age: 1, Tags: []string{"x", "y"}, Address: &testAddress{City: "c", Lines: []string{"1"}}},
+			wantFields: []string{"name"},
+		},
+		{
+			name:       "move and copy",
+			patch:      `[{"op": "copy", "from": "/address/city", "path": "/name"}, {"op": "move", "from": "/tags/1", "path": "/address/lines/0"}]`,
+			want:       testPerson{Name: "c", Age: 1, Tags: []string{"x"}, Address: &testAddress{City: "c", Lines: []string{"y", "1"}}},
+			wantFields: []string{"address", "name", "tags"},
+		},
+		{name: "failed test", patch: `[{"op": "test", "path": "/age", "value": 2}]`, wantErr: true},
+		{name: "missing path", patch: `[{"op": "replace", "path": "/address/zip", "value": "1"}]`, wantErr: true},
+		{name: "index out of range", patch: `[{"op": "add", "path": "/tags/5", "value": "1"}]`, wantErr: true},
+		{name: "leading zero index", patch: `[{"op": "remove", "path": "/tags/01"}]`, wantErr: true},
+		{name: "whole document", patch: `[{"op": "replace", "path": "", "value": {}}]`, wantErr: true},
+		{name: "move into itself", patch: `[{"op": "move", "from": "/address", "path": "/address/city"}]`, wantErr: true},
+		{name: "missing value", patch: `[{"op": "add", "path": "/name"}]`, wantErr: true},
+		{name: "unknown op", patch: `[{"op": "merge", "path": "/name", "value": "b"}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fields, err := ApplyPatch(current, PatchType_JSONPatch, []byte(tt.patch))
+			if tt.wantErr {
+				assertBadRequest(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
+
+type testPatchMeta struct {
+	Version int `json:"version"`
+}
+
+type testPatchTarget struct {
+	testPatchMeta
+	Name     string         `json:"name"`
+	Labels   map[string]int `json:"labels"`
+	Internal string         `json:"-"`
+	cache    string
+}
+
+func TestApplyPatch_KeepsUnmodifiedFields(t *testing.T) {
+	current := testPatchTarget{testPatchMeta: testPatchMeta{Version: 1}, Name: "a", Labels: map[string]int{"x": 1}, Internal: "i", cache: "c"}
+
+	// Fields that are not encoded are kept, and the modified ones are replaced rather than merged into
+	got, fields, err := ApplyMergePatch(current, []byte(`{"name": "b", "labels": {"y": 2}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"labels", "name"}, fields)
+	assert.Equal(t, testPatchTarget{testPatchMeta: testPatchMeta{Version: 1}, Name: "b", Labels: map[string]int{"x": 1, "y": 2}, Internal: "i", cache: "c"}, got)
+
+	got, _, err = ApplyJSONPatch(current, []byte(`[{"op": "replace", "path": "/labels", "value": {"z": 3}}, {"op": "replace", "path": "/version", "value": 2}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, testPatchTarget{testPatchMeta: testPatchMeta{Version: 2}, Name: "a", Labels: map[string]int{"z": 3}, Internal: "i", cache: "c"}, got)
+
+	// Removed fields are set to their zero value
+	got, _, err = ApplyJSONPatch(current, []byte(`[{"op": "remove", "path": "/labels"}]`))
+	assert.NoError(t, err)
+	assert.Nil(t, got.Labels)
+	assert.Equal(t, "i", got.Internal)
+
+	// The current value is not modified
+	assert.Equal(t, map[string]int{"x": 1}, current.Labels)
+}
+
+func TestApplyPatch_Encrypted(t *testing.T) {
+	current := testAccount{Name: "a", Secret: scalars.NewEncrypted("s3cret")}
+
+	// Encrypted values are redacted in JSON, so they are kept unless the patch modifies them
+	got, fields, err := ApplyMergePatch(current, []byte(`{"name": "b"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name"}, fields)
+	assert.Equal(t, "b", got.Name)
+	assert.Equal(t, "s3cret", got.Secret.Get())
+
+	got, _, err = ApplyJSONPatch(current, []byte(`[{"op": "replace", "path": "/secret", "value": "n3w"}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, "n3w", got.Secret.Get())
+
+	diffs, err := Diff(current, got)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"secret"}, []string(diffs[0].Path))
+
+	// The redacted placeholder cannot be written back
+	_, _, err = ApplyMergePatch(current, []byte(`{"secret": "`+scalars.Redacted+`"}`))
+	assertBadRequest(t, err)
+}
+
+func TestParseJSONPointer(t *testing.T) {
+	tokens, err := parseJSONPointer("/a~1b/c~0d/~01")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "c~d", "~1"}, tokens)
+	assert.Equal(t, "/a~1b/c~0d/~01", FieldPath(tokens).JSONPointer())
+
+	_, err = parseJSONPointer("a")
+	assert.Error(t, err)
+}
+
+func assertBadRequest(t *testing.T, err error) {
+	t.Helper()
+	assert.Error(t, err)
+	gerr, ok := errutil.AsGokuError(err)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, gerr.GetHTTPStatus())
+}
@@ -0,0 +1,274 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+	"github.com/teejays/gokutil/errutil"
+	"github.com/teejays/gokutil/gopi"
+	"github.com/teejays/gokutil/naam"
+)
+
+/* * * * * *
+ * Registry
+ * * * * * */
+
+// IDALMeta is implemented by the DAL metas (see dalutil.TypeCommonDALMeta), so the registry can tell how a type is stored.
+type IDALMeta interface {
+	GetDALInfo() DALInfo
+}
+
+// DALInfo lists the fields of a type by how the DAL treats them.
+type DALInfo struct {
+	DatabaseColumnFields []naam.Name
+	SubTableFields       []naam.Name
+	ImmutableFields      []naam.Name
+	InternalFields       []naam.Name // fields that are set internally by the DAL
+}
+
+// TypeInfo is what the registry exposes about a type.
+type TypeInfo struct {
+	Name       naam.Name          `json:"name"`
+	Fields     []FieldInfo        `json:"fields"`
+	Hooks      []HookType         `json:"hooks"` // hook points that have at least one hook
+	HasDALMeta bool               `json:"hasDalMeta"`
+	JSONSchema *jsonschema.Schema `json:"jsonSchema"`
+}
+
+type FieldInfo struct {
+	Name             naam.Name `json:"name"`
+	IsDatabaseColumn bool      `json:"isDatabaseColumn"`
+	IsSubTable       bool      `json:"isSubTable"`
+	IsImmutable      bool      `json:"isImmutable"`
+	IsInternal       bool      `json:"isInternal"`
+}
+
+type registryEntry struct {
+	info    TypeInfo
+	meta    interface{}
+	dalMeta IDALMeta
+	hooks   func() []HookType
+}
+
+// Registry holds the metas of the types by name, so tools can find out what types exist. It is safe for concurrent use.
+type Registry struct {
+	lock    sync.RWMutex
+	entries map[naam.Name]*registryEntry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{entries: map[naam.Name]*registryEntry{}}
+}
+
+var registry = NewRegistry()
+var registryLock sync.RWMutex
+
+// SetRegistry sets the registry that RegisterMeta uses.
+func SetRegistry(r *Registry) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry = r
+}
+
+func GetRegistry() *Registry {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return registry
+}
+
+// RegisterMeta registers the meta of a type with the default registry. See Register.
+func RegisterMeta[T BasicType, F Field](ctx context.Context, meta ITypeCommonMeta[T, F]) error {
+	return Register(ctx, GetRegistry(), meta, nil)
+}
+
+// IsMetaRegistered returns true if the type with the name is in the default registry.
+func IsMetaRegistered(name naam.Name) bool {
+	return GetRegistry().Has(name)
+}
+
+// Register adds the meta of a type, and optionally its DAL meta, to the registry and runs its Init hooks. Each type can
+// only be registered once. If an Init hook fails, the type is not registered.
+func Register[T BasicType, F Field](ctx context.Context, r *Registry, meta ITypeCommonMeta[T, F], dalMeta IDALMeta) error {
+	common := meta.GetTypeCommonMeta()
+	name := common.Name
+	if name.IsEmpty() {
+		return fmt.Errorf("type meta has no name")
+	}
+
+	var dalInfo DALInfo
+	if dalMeta != nil {
+		dalInfo = dalMeta.GetDALInfo()
+	}
+	entry := &registryEntry{
+		info: TypeInfo{
+			Name:       name,
+			Fields:     getFieldInfos(common.Fields, dalInfo),
+			HasDALMeta: dalMeta != nil,
+		},
+		meta:    meta,
+		dalMeta: dalMeta,
+		hooks: func() []HookType {
+			var points []HookType
+			for _, p := range HookPoints {
+				if (p.IsInit() && len(meta.GetInitHooks()) > 0) ||
+					(p.IsReadPre() && len(meta.GetReadPreHooks()) > 0) ||
+					(p.IsTypeHook() && len(meta.GetHooks(p)) > 0) {
+					points = append(points, p)
+				}
+			}
+			return points
+		},
+	}
+	var zero T
+	entry.info.JSONSchema = getTypeJSONSchema(zero, entry.info.Fields)
+
+	r.lock.Lock()
+	if _, exists := r.entries[name]; exists {
+		r.lock.Unlock()
+		return fmt.Errorf("type [%s] is already registered", name)
+	}
+	r.entries[name] = entry
+	r.lock.Unlock()
+
+	// Init hooks run without the lock, so they can register other types
+	if err := RunInitHooks(ctx, meta.GetInitHooks()); err != nil {
+		r.lock.Lock()
+		delete(r.entries, name)
+		r.lock.Unlock()
+		return fmt.Errorf("Running Init hooks for type [%s]: %w", name, err)
+	}
+	return nil
+}
+
+func (r *Registry) Has(name naam.Name) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	_, ok := r.entries[name]
+	return ok
+}
+
+// Get returns the info for the type with the name.
+func (r *Registry) Get(name naam.Name) (TypeInfo, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	entry, ok := r.entries[name]
+	if !ok {
+		return TypeInfo{}, false
+	}
+	return entry.getInfo(), true
+}
+
+// List returns the info for all the types, sorted by name.
+func (r *Registry) List() []TypeInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var infos []TypeInfo
+	for _, entry := range r.entries {
+		infos = append(infos, entry.getInfo())
+	}
+	slices.SortFunc(infos, func(a, b TypeInfo) int { return strings.Compare(a.Name.ToSnake(), b.Name.ToSnake()) })
+	return infos
+}
+
+// GetMeta returns the meta for the type with the name. It is a ITypeCommonMeta[T, F] of the type.
+func (r *Registry) GetMeta(name naam.Name) (interface{}, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	entry, ok := r.entries[name]
+	if !ok {
+		return nil, false
+	}
+	return entry.meta, true
+}
+
+// GetDALMeta returns the DAL meta for the type with the name, if it was registered with one.
+func (r *Registry) GetDALMeta(name naam.Name) (IDALMeta, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	entry, ok := r.entries[name]
+	if !ok || entry.dalMeta == nil {
+		return nil, false
+	}
+	return entry.dalMeta, true
+}
+
+func (e *registryEntry) getInfo() TypeInfo {
+	info := e.info
+	info.Fields = slices.Clone(info.Fields)
+	info.Hooks = e.hooks()
+	return info
+}
+
+func getFieldInfos[F Field](fields []F, dalInfo DALInfo) []FieldInfo {
+	has := func(names []naam.Name, name naam.Name) bool {
+		return slices.ContainsFunc(names, func(n naam.Name) bool { return n.Equal(name) })
+	}
+	var infos []FieldInfo
+	for _, f := range fields {
+		name := f.Name()
+		infos = append(infos, FieldInfo{
+			Name:             name,
+			IsDatabaseColumn: has(dalInfo.DatabaseColumnFields, name),
+			IsSubTable:       has(dalInfo.SubTableFields, name),
+			IsImmutable:      has(dalInfo.ImmutableFields, name),
+			IsInternal:       has(dalInfo.InternalFields, name),
+		})
+	}
+	return infos
+}
+
+// getTypeJSONSchema returns the JSON Schema of the type, with the immutable and internal fields marked as read only.
+func getTypeJSONSchema(v interface{}, fields []FieldInfo) *jsonschema.Schema {
+	reflector := jsonschema.Reflector{ExpandedStruct: true, DoNotReference: true}
+	schema := reflector.Reflect(v)
+	if schema.Properties == nil {
+		return schema
+	}
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		key := naam.New(pair.Key)
+		i := slices.IndexFunc(fields, func(f FieldInfo) bool { return f.Name.Equal(key) })
+		if i >= 0 && (fields[i].IsImmutable || fields[i].IsInternal) {
+			pair.Value.ReadOnly = true
+		}
+	}
+	return schema
+}
+
+/* * * * * *
+ * Routes
+ * * * * * */
+
+// GetRoutes returns the routes that serve the registry: `GET meta` lists all the types, and `GET meta/{name}` returns
+// a single type.
+func (r *Registry) GetRoutes() []gopi.Route {
+	return []gopi.Route{
+		{
+			Method: http.MethodGet,
+			Path:   "meta",
+			HandlerFunc: gopi.GetDirectRequestHandler(func(ctx context.Context, req *http.Request) ([]TypeInfo, error) {
+				return r.List(), nil
+			}),
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "meta/{name}",
+			HandlerFunc: gopi.GetDirectRequestHandler(func(ctx context.Context, req *http.Request) (TypeInfo, error) {
+				nameStr, err := gopi.GetMuxParamStr(req, "name")
+				if err != nil {
+					return TypeInfo{}, errutil.WrapGerror(err).SetHTTPStatus(http.StatusBadRequest)
+				}
+				info, ok := r.Get(naam.New(nameStr))
+				if !ok {
+					return TypeInfo{}, errutil.NewGerror("Type [%s] is not registered", nameStr).
+						SetHTTPStatus(http.StatusNotFound)
+				}
+				return info, nil
+			}),
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teejays/gokutil/gopi"
+	"github.com/teejays/gokutil/naam"
+)
+
+type testDALMeta struct {
+	info DALInfo
+}
+
+func (m testDALMeta) GetDALInfo() DALInfo { return m.info }
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry()
+
+	meta := &TypeCommonMeta[testType, TestField]{Name: naam.New("register_test"), Fields: []TestField{TestField_1, TestField_2}}
+	dalMeta := testDALMeta{DALInfo{
+		DatabaseColumnFields: []naam.Name{TestField_1.Name(), TestField_2.Name()},
+		ImmutableFields:      []naam.Name{TestField_1.Name()},
+	}}
+
+	// A failing Init hook does not register the type
+	calls := 0
+	assert.NoError(t, meta.AddInitHook(InitHook{Name: "setup", Fn: func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errBadValue
+		}
+		return nil
+	}}))
+	err := Register[testType, TestField](ctx, r, meta, dalMeta)
+	assert.ErrorIs(t, err, errBadValue)
+	assert.False(t, r.Has(meta.Name))
+
+	// Init hooks run once, when the type is registered
+	assert.NoError(t, Register[testType, TestField](ctx, r, meta, dalMeta))
+	assert.Error(t, Register[testType, TestField](ctx, r, meta, dalMeta))
+	assert.Equal(t, 2, calls)
+
+	assert.NoError(t, meta.SetHookSavePre(appendHook("s")))
+	info, ok := r.Get(naam.New("Register Test"))
+	assert.True(t, ok)
+	assert.Equal(t, []FieldInfo{
+		{Name: TestField_1.Name(), IsDatabaseColumn: true, IsImmutable: true},
+		{Name: TestField_2.Name(), IsDatabaseColumn: true},
+	}, info.Fields)
+	assert.Equal(t, []HookType{HookPoint_Init, HookPoint_SavePre}, info.Hooks)
+	assert.True(t, info.HasDALMeta)
+	prop, ok := info.JSONSchema.Properties.Get("Value")
+	assert.True(t, ok)
+	assert.Equal(t, "string", prop.Type)
+
+	_, ok = r.GetDALMeta(meta.Name)
+	assert.True(t, ok)
+	_, ok = r.Get(naam.New("missing"))
+	assert.False(t, ok)
+
+	// Routes
+	h, err := gopi.GetHandler(ctx, r.GetRoutes(), gopi.MiddlewareFuncs{}, "")
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v0/meta", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp gopi.StandardResponseGeneric[[]TypeInfo]
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, "register_test", resp.Data[0].Name.ToSnake())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v0/meta/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}